@@ -28,6 +28,7 @@ require (
 	github.com/yuin/goldmark-meta v1.1.0
 	github.com/zk-org/pretty v0.2.4
 	gopkg.in/djherbis/times.v1 v1.3.0
+	gopkg.in/yaml.v2 v2.4.0
 )
 
 require (
@@ -50,5 +51,4 @@ require (
 	golang.org/x/term v0.27.0 // indirect
 	golang.org/x/text v0.21.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/zk-org/zk/internal/util"
 )
@@ -116,6 +117,10 @@ func (fs *FileStorage) Read(path string) ([]byte, error) {
 	return os.ReadFile(path)
 }
 
+func (fs *FileStorage) SetModified(path string, modified time.Time) error {
+	return os.Chtimes(path, modified, modified)
+}
+
 func (fs *FileStorage) Write(path string, content []byte) error {
 	dir := filepath.Dir(path)
 	if dir != "." && dir != ".." {
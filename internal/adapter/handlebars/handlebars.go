@@ -21,8 +21,10 @@ func Init(supportsUTF8 bool, logger util.Logger) {
 	helpers.RegisterJSON(logger)
 	helpers.RegisterList(supportsUTF8)
 	helpers.RegisterPrepend(logger)
+	helpers.RegisterSection()
 	helpers.RegisterShell(logger)
 	helpers.RegisterSubstring()
+	helpers.RegisterYAML(logger)
 }
 
 // Template renders a parsed handlebars template.
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -145,6 +146,41 @@ func TestSubstringHelper(t *testing.T) {
 	testString(t, "{{substring 'A full quote' -5 6}}", nil, "quote")
 }
 
+func TestSectionHelper(t *testing.T) {
+	body := `Intro
+
+## Summary
+
+The main idea.
+
+### Detail
+
+More about it.
+
+## References ##
+
+` + "```" + `
+# Not a heading
+` + "```" + `
+- A book
+#not-a-heading`
+
+	test := func(heading string, expected string) {
+		testString(t, "{{section body heading}}", map[string]interface{}{"body": body, "heading": heading}, expected)
+	}
+
+	// Subsections are included, until the next heading of the same level.
+	test("## Summary", "The main idea.\n\n### Detail\n\nMore about it.")
+	test("### Detail", "More about it.")
+	// Without # markers, the heading can have any level.
+	test("Detail", "More about it.")
+	// Headings in code blocks are ignored.
+	test("References", "```\n# Not a heading\n```\n- A book\n#not-a-heading")
+	test("# Summary", "")
+	test("Unknown", "")
+	test("Not a heading", "")
+}
+
 func TestJoinHelper(t *testing.T) {
 	test := func(items []string, expected string) {
 		context := map[string]interface{}{"items": items}
@@ -179,6 +215,34 @@ func TestJSONHelper(t *testing.T) {
 	}, `{"Foo":"baz","stringList":["foo","bar"]}`)
 }
 
+func TestYAMLHelper(t *testing.T) {
+	test := func(value interface{}, expected string) {
+		context := map[string]interface{}{"value": value}
+		testString(t, "{{yaml value}}", context, expected)
+	}
+
+	test("foo", "foo")
+	test(map[string]interface{}{}, "{}")
+	// Metadata unmarshalled from JSON, with nested maps and arrays.
+	test(map[string]interface{}{
+		"title":    "A note",
+		"priority": float64(2),
+		"tags":     []interface{}{"fiction", "history"},
+		"author": map[string]interface{}{
+			"name":  "Dom",
+			"links": []interface{}{map[string]interface{}{"url": "https://domain.com"}},
+		},
+	}, `author:
+  links:
+  - url: https://domain.com
+  name: Dom
+priority: 2
+tags:
+- fiction
+- history
+title: A note`)
+}
+
 func TestPrependHelper(t *testing.T) {
 	// inline
 	testString(t, "{{prepend '> ' 'A quote'}}", nil, "> A quote")
@@ -211,6 +275,38 @@ func TestLinkHelper(t *testing.T) {
 	assert.Equal(t, actual, "path/to note.md - An interesting subject")
 }
 
+func TestLinkTitleHelper(t *testing.T) {
+	testString(t, `{{link-title "interesting"}}`, nil, "An interesting subject")
+	testString(t, `{{link-title "untitled"}}`, nil, "path/to/untitled.md")
+	testString(t, `{{link-title "https://example.com"}}`, nil, "https://example.com")
+	testString(t, `{{link-title "failing"}}`, nil, "failing")
+}
+
+func TestBacklinkCountHelper(t *testing.T) {
+	queries := 0
+	countBacklinks := func() (map[string]int, error) {
+		queries++
+		return map[string]int{"a.md": 12, "dir/b.md": 1}, nil
+	}
+	notebookPath := func(path string) (string, error) {
+		return strings.TrimPrefix(path, "../"), nil
+	}
+
+	sut := testLoader(LoaderOpts{})
+	sut.RegisterHelper("backlink-count", helpers.NewBacklinkCountHelper(countBacklinks, notebookPath, &util.NullLogger))
+	templ, err := sut.LoadTemplate(`{{#each paths}}{{this}}: {{backlink-count this}}, {{/each}}`)
+	assert.Nil(t, err)
+
+	actual, err := templ.Render(map[string]interface{}{
+		"paths": []string{"a.md", "../dir/b.md", "orphan.md"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, actual, "a.md: 12, ../dir/b.md: 1, orphan.md: 0, ")
+
+	// The counts are loaded only once for all the notes.
+	assert.Equal(t, queries, 1)
+}
+
 func TestSlugHelper(t *testing.T) {
 	// inline
 	testString(t,
@@ -400,5 +496,19 @@ func testLoader(opts LoaderOpts) *Loader {
 	}
 	loader.RegisterHelper("format-link", helpers.NewLinkHelper(formatter, &util.NullLogger))
 
+	findByHref := func(href string) (*core.MinimalNote, error) {
+		switch href {
+		case "interesting":
+			return &core.MinimalNote{Path: "path/to/interesting.md", Title: "An interesting subject"}, nil
+		case "untitled":
+			return &core.MinimalNote{Path: "path/to/untitled.md"}, nil
+		case "failing":
+			return nil, fmt.Errorf("failed to find note")
+		default:
+			return nil, nil
+		}
+	}
+	loader.RegisterHelper("link-title", helpers.NewLinkTitleHelper(findByHref, &util.NullLogger))
+
 	return loader
 }
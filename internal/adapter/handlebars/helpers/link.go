@@ -29,3 +29,57 @@ func NewLinkHelper(formatter core.LinkFormatter, logger util.Logger) interface{}
 		return link
 	}
 }
+
+// NewLinkTitleHelper creates a new template helper to print the title of the
+// note targeted by a link href, found with the given function.
+//
+// The path of the note is used when it has no title, and the href itself
+// when the link can't be resolved, e.g. for external or broken links.
+//
+// {{link-title "note"}} -> An interesting subject
+// {{link-title "https://example.com"}} -> https://example.com
+func NewLinkTitleHelper(findByHref func(href string) (*core.MinimalNote, error), logger util.Logger) interface{} {
+	return func(href string) string {
+		note, err := findByHref(href)
+		if err != nil {
+			logger.Err(err)
+		}
+		switch {
+		case note == nil:
+			return href
+		case note.Title == "":
+			return note.Path
+		default:
+			return note.Title
+		}
+	}
+}
+
+// NewBacklinkCountHelper creates a new template helper to print the number of
+// notes linking to the note at the given path.
+//
+// The counts of all the notes are loaded with countBacklinks the first time
+// the helper is used, to avoid running a query for each rendered note. The
+// given path is converted with notebookPath to match their keys.
+//
+// {{backlink-count path}} -> 12
+func NewBacklinkCountHelper(countBacklinks func() (map[string]int, error), notebookPath func(path string) (string, error), logger util.Logger) interface{} {
+	var counts map[string]int
+	return func(path string) int {
+		if counts == nil {
+			var err error
+			counts, err = countBacklinks()
+			if err != nil {
+				logger.Err(err)
+				return 0
+			}
+		}
+
+		path, err := notebookPath(path)
+		if err != nil {
+			logger.Err(err)
+			return 0
+		}
+		return counts[path]
+	}
+}
@@ -0,0 +1,78 @@
+package helpers
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/aymerick/raymond"
+)
+
+// RegisterSection registers a {{section}} template helper which extracts the
+// Markdown content under the given heading, until the next heading of the
+// same or a higher level.
+//
+// {{section body "## Summary"}} -> content of the "Summary" level 2 section
+// {{section body "Summary"}} -> content of the first "Summary" section, at any level
+//
+// An empty string is returned when the heading is not found.
+func RegisterSection() {
+	raymond.RegisterHelper("section", func(markdown string, heading string) string {
+		return extractSection(markdown, heading)
+	})
+}
+
+// extractSection returns the content of the section with the given heading
+// in markdown. The heading is matched with its level only when it starts with
+// # markers.
+func extractSection(markdown string, heading string) string {
+	wantedLevel, wantedTitle := parseHeading(heading)
+	if wantedLevel == 0 {
+		wantedTitle = strings.TrimSpace(heading)
+	}
+
+	lines := strings.Split(markdown, "\n")
+	inFence := false
+	start := -1
+	level := 0
+
+	for i, line := range lines {
+		if fenceRegex.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		l, title := parseHeading(line)
+		if l == 0 {
+			continue
+		}
+		if start >= 0 {
+			if l <= level {
+				return strings.TrimSpace(strings.Join(lines[start:i], "\n"))
+			}
+		} else if title == wantedTitle && (wantedLevel == 0 || l == wantedLevel) {
+			start = i + 1
+			level = l
+		}
+	}
+
+	if start < 0 {
+		return ""
+	}
+	return strings.TrimSpace(strings.Join(lines[start:], "\n"))
+}
+
+var headingRegex = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+var fenceRegex = regexp.MustCompile("^ {0,3}(```|~~~)")
+
+// parseHeading returns the level and title of a Markdown ATX heading, or a
+// zero level if the line is not a heading.
+func parseHeading(line string) (int, string) {
+	matches := headingRegex.FindStringSubmatch(line)
+	if matches == nil {
+		return 0, ""
+	}
+	return len(matches[1]), matches[2]
+}
@@ -0,0 +1,26 @@
+package helpers
+
+import (
+	"strings"
+
+	"github.com/aymerick/raymond"
+	"github.com/zk-org/zk/internal/util"
+	"github.com/zk-org/zk/internal/util/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// RegisterYAML registers a {{yaml}} template helper which serializes its
+// parameter to YAML, e.g. to print the metadata of a note as it is usually
+// written in its frontmatter.
+//
+// {{yaml metadata}} -> "tags:\n- fiction\ntitle: A note"
+func RegisterYAML(logger util.Logger) {
+	raymond.RegisterHelper("yaml", func(arg interface{}) string {
+		yamlBytes, err := yaml.Marshal(arg)
+		if err != nil {
+			logger.Err(errors.Wrapf(err, "%v: not a serializable argument for {{yaml}}", arg))
+			return ""
+		}
+		return strings.TrimSuffix(string(yamlBytes), "\n")
+	})
+}
@@ -41,6 +41,7 @@ func executeCommandList(logger util.Logger, notebook *core.Notebook, args []inte
 	if err != nil {
 		return nil, err
 	}
+	findOpts.LocateMatch = selection.MatchOffset
 
 	notes, err := notebook.FindNotes(findOpts)
 	if err != nil {
@@ -56,6 +57,7 @@ func executeCommandList(logger util.Logger, notebook *core.Notebook, args []inte
 }
 
 type listSelection struct {
+	ID           bool
 	Filename     bool
 	FilenameStem bool
 	Path         bool
@@ -64,6 +66,8 @@ type listSelection struct {
 	Lead         bool
 	Body         bool
 	Snippets     bool
+	Score        bool
+	MatchOffset  bool
 	RawContent   bool
 	WordCount    bool
 	Tags         bool
@@ -75,6 +79,7 @@ type listSelection struct {
 
 func newListSelection(fields []string) listSelection {
 	return listSelection{
+		ID:           strutil.Contains(fields, "id"),
 		Filename:     strutil.Contains(fields, "filename"),
 		FilenameStem: strutil.Contains(fields, "filenameStem"),
 		Path:         strutil.Contains(fields, "path"),
@@ -83,6 +88,8 @@ func newListSelection(fields []string) listSelection {
 		Lead:         strutil.Contains(fields, "lead"),
 		Body:         strutil.Contains(fields, "body"),
 		Snippets:     strutil.Contains(fields, "snippets"),
+		MatchOffset:  strutil.Contains(fields, "matchOffset"),
+		Score:        strutil.Contains(fields, "score"),
 		RawContent:   strutil.Contains(fields, "rawContent"),
 		WordCount:    strutil.Contains(fields, "wordCount"),
 		Tags:         strutil.Contains(fields, "tags"),
@@ -95,6 +102,9 @@ func newListSelection(fields []string) listSelection {
 
 func newListNote(note core.ContextualNote, selection listSelection, basePath string) listNote {
 	var res listNote
+	if selection.ID {
+		res.ID = note.ID
+	}
 	if selection.Filename {
 		res.Filename = note.Filename()
 	}
@@ -119,6 +129,13 @@ func newListNote(note core.ContextualNote, selection listSelection, basePath str
 	if selection.Snippets {
 		res.Snippets = note.Snippets
 	}
+	if selection.Score {
+		res.Score = note.Score
+	}
+	if selection.MatchOffset && note.MatchOffset >= 0 {
+		offset := note.MatchOffset
+		res.MatchOffset = &offset
+	}
 	if selection.RawContent {
 		res.RawContent = note.RawContent
 	}
@@ -144,6 +161,7 @@ func newListNote(note core.ContextualNote, selection listSelection, basePath str
 }
 
 type listNote struct {
+	ID           core.NoteID            `json:"id,omitempty"`
 	Filename     string                 `json:"filename,omitempty"`
 	FilenameStem string                 `json:"filenameStem,omitempty"`
 	Path         string                 `json:"path,omitempty"`
@@ -152,6 +170,8 @@ type listNote struct {
 	Lead         string                 `json:"lead,omitempty"`
 	Body         string                 `json:"body,omitempty"`
 	Snippets     []string               `json:"snippets,omitempty"`
+	Score        float64                `json:"score,omitempty"`
+	MatchOffset  *int                   `json:"matchOffset,omitempty"`
 	RawContent   string                 `json:"rawContent,omitempty"`
 	WordCount    int                    `json:"wordCount,omitempty"`
 	Tags         []string               `json:"tags,omitempty"`
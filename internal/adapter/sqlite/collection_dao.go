@@ -21,6 +21,11 @@ type CollectionDAO struct {
 	findAssociationStmt    *LazyStmt
 	createAssociationStmt  *LazyStmt
 	removeAssociationsStmt *LazyStmt
+	renameCollectionStmt   *LazyStmt
+	removeCollectionStmt   *LazyStmt
+	countNotesStmt         *LazyStmt
+	removeDuplicatesStmt   *LazyStmt
+	moveAssociationsStmt   *LazyStmt
 }
 
 // NewCollectionDAO creates a new instance of a DAO working on the given
@@ -59,6 +64,40 @@ func NewCollectionDAO(tx Transaction, logger util.Logger) *CollectionDAO {
 			DELETE FROM notes_collections
 			 WHERE note_id = ?
 		`),
+
+		// Renames a collection.
+		renameCollectionStmt: tx.PrepareLazy(`
+			UPDATE collections
+			   SET name = ?
+			 WHERE id = ?
+		`),
+
+		// Removes a collection, along with its associations.
+		removeCollectionStmt: tx.PrepareLazy(`
+			DELETE FROM collections
+			 WHERE id = ?
+		`),
+
+		// Counts the notes associated with a collection.
+		countNotesStmt: tx.PrepareLazy(`
+			SELECT COUNT(DISTINCT note_id) FROM notes_collections
+			 WHERE collection_id = ?
+		`),
+
+		// Removes the associations of a source collection with the notes
+		// already associated with a target one.
+		removeDuplicatesStmt: tx.PrepareLazy(`
+			DELETE FROM notes_collections
+			 WHERE collection_id = ?1
+			   AND note_id IN (SELECT note_id FROM notes_collections WHERE collection_id = ?2)
+		`),
+
+		// Moves the associations of a source collection to a target one.
+		moveAssociationsStmt: tx.PrepareLazy(`
+			UPDATE notes_collections
+			   SET collection_id = ?2
+			 WHERE collection_id = ?1
+		`),
 	}
 }
 
@@ -176,6 +215,61 @@ func (d *CollectionDAO) create(kind core.CollectionKind, name string) (core.Coll
 	return core.CollectionID(id), nil
 }
 
+// Rename renames the collection with the given kind and name in the index
+// only, and returns the number of notes associated with it.
+//
+// If a collection named newName already exists, both collections are merged
+// into it, without duplicating the associations of the notes having both.
+func (d *CollectionDAO) Rename(kind core.CollectionKind, oldName string, newName string) (int, error) {
+	wrap := errors.Wrapperf("failed to rename %s %s to %s", kind, oldName, newName)
+
+	id, err := d.findCollection(kind, oldName)
+	if err != nil {
+		return 0, wrap(err)
+	}
+	if !id.IsValid() {
+		return 0, wrap(errors.New("not found"))
+	}
+
+	row, err := d.countNotesStmt.QueryRow(id)
+	if err != nil {
+		return 0, wrap(err)
+	}
+	var count int
+	err = row.Scan(&count)
+	if err != nil {
+		return 0, wrap(err)
+	}
+
+	if oldName == newName {
+		return count, nil
+	}
+
+	targetID, err := d.findCollection(kind, newName)
+	if err != nil {
+		return 0, wrap(err)
+	}
+	if !targetID.IsValid() {
+		_, err = d.renameCollectionStmt.Exec(newName, id)
+		return count, wrap(err)
+	}
+
+	_, err = d.removeDuplicatesStmt.Exec(id, targetID)
+	if err != nil {
+		return 0, wrap(err)
+	}
+	_, err = d.moveAssociationsStmt.Exec(id, targetID)
+	if err != nil {
+		return 0, wrap(err)
+	}
+	_, err = d.removeCollectionStmt.Exec(id)
+	if err != nil {
+		return 0, wrap(err)
+	}
+
+	return count, nil
+}
+
 // Associate creates a new association between a note and a collection, if it
 // does not already exist.
 func (d *CollectionDAO) Associate(noteId core.NoteID, collectionId core.CollectionID) (core.NoteCollectionID, error) {
@@ -117,6 +117,51 @@ func TestCollectionDAORemoveAssociations(t *testing.T) {
 	})
 }
 
+func TestCollectionDAORename(t *testing.T) {
+	testCollectionDAO(t, func(tx Transaction, dao *CollectionDAO) {
+		count, err := dao.Rename("tag", "science", "research")
+		assert.Nil(t, err)
+		assert.Equal(t, count, 2)
+
+		sql := "SELECT id FROM collections WHERE id = 7 AND kind = 'tag' AND name = ?"
+		assertNotExistTx(t, tx, sql, "science")
+		assertExistTx(t, tx, sql, "research")
+
+		// Only the collections of the given kind are renamed.
+		_, err = dao.Rename("tag", "fiction", "novel")
+		assert.Nil(t, err)
+		assertExistTx(t, tx, "SELECT id FROM collections WHERE id = 3 AND kind = 'genre' AND name = 'fiction'")
+	})
+}
+
+// Renaming to an existing collection merges them.
+func TestCollectionDAORenameMerges(t *testing.T) {
+	testCollectionDAO(t, func(tx Transaction, dao *CollectionDAO) {
+		count, err := dao.Rename("tag", "adventure", "science")
+		assert.Nil(t, err)
+		assert.Equal(t, count, 2)
+
+		assertNotExistTx(t, tx, "SELECT id FROM collections WHERE kind = 'tag' AND name = 'adventure'")
+
+		cs, err := dao.FindAll("tag", nil)
+		assert.Nil(t, err)
+		assert.Equal(t, cs, []core.Collection{
+			{ID: 4, Kind: "tag", Name: "fantasy", NoteCount: 1},
+			{ID: 1, Kind: "tag", Name: "fiction", NoteCount: 1},
+			{ID: 5, Kind: "tag", Name: "history", NoteCount: 1},
+			// The note already tagged with both is not associated again.
+			{ID: 7, Kind: "tag", Name: "science", NoteCount: 4},
+		})
+	})
+}
+
+func TestCollectionDAORenameUnknown(t *testing.T) {
+	testCollectionDAO(t, func(tx Transaction, dao *CollectionDAO) {
+		_, err := dao.Rename("tag", "unknown", "science")
+		assert.Err(t, err, "failed to rename tag unknown to science: not found")
+	})
+}
+
 func testCollectionDAO(t *testing.T, callback func(tx Transaction, dao *CollectionDAO)) {
 	testTransaction(t, func(tx Transaction) {
 		callback(tx, NewCollectionDAO(tx, &util.NullLogger))
@@ -217,6 +217,63 @@ func (db *DB) migrate() error {
 				// https://github.com/zk-org/zk/issues/170#issuecomment-1107848441
 				NeedsReindexing: true,
 			},
+
+			{ // 8
+				SQL: []string{
+					// FTS index of the lead paragraphs. It is kept apart from
+					// notes_fts, as additional columns would change the
+					// relevance of the other matches.
+					`CREATE VIRTUAL TABLE IF NOT EXISTS notes_lead_fts USING fts5(
+						lead,
+						content = notes,
+						content_rowid = id,
+						tokenize = "porter unicode61 remove_diacritics 1 tokenchars '''&/'"
+					)`,
+					`CREATE TRIGGER IF NOT EXISTS trigger_notes_lead_ai AFTER INSERT ON notes BEGIN
+						INSERT INTO notes_lead_fts(rowid, lead) VALUES (new.id, new.lead);
+					END`,
+					`CREATE TRIGGER IF NOT EXISTS trigger_notes_lead_ad AFTER DELETE ON notes BEGIN
+						INSERT INTO notes_lead_fts(notes_lead_fts, rowid, lead) VALUES('delete', old.id, old.lead);
+					END`,
+					`CREATE TRIGGER IF NOT EXISTS trigger_notes_lead_au AFTER UPDATE ON notes BEGIN
+						INSERT INTO notes_lead_fts(notes_lead_fts, rowid, lead) VALUES('delete', old.id, old.lead);
+						INSERT INTO notes_lead_fts(rowid, lead) VALUES (new.id, new.lead);
+					END`,
+					`INSERT INTO notes_lead_fts(notes_lead_fts) VALUES('rebuild')`,
+				},
+			},
+
+			{ // 9
+				SQL: []string{
+					// FTS index of the trigrams of the notes, used by the
+					// fuzzy matching of --fuzzy. It is several times larger
+					// than notes_fts.
+					`CREATE VIRTUAL TABLE IF NOT EXISTS notes_trigram_fts USING fts5(
+						path, title, body,
+						content = notes,
+						content_rowid = id,
+						tokenize = "trigram"
+					)`,
+					`CREATE TRIGGER IF NOT EXISTS trigger_notes_trigram_ai AFTER INSERT ON notes BEGIN
+						INSERT INTO notes_trigram_fts(rowid, path, title, body) VALUES (new.id, new.path, new.title, new.body);
+					END`,
+					`CREATE TRIGGER IF NOT EXISTS trigger_notes_trigram_ad AFTER DELETE ON notes BEGIN
+						INSERT INTO notes_trigram_fts(notes_trigram_fts, rowid, path, title, body) VALUES('delete', old.id, old.path, old.title, old.body);
+					END`,
+					`CREATE TRIGGER IF NOT EXISTS trigger_notes_trigram_au AFTER UPDATE ON notes BEGIN
+						INSERT INTO notes_trigram_fts(notes_trigram_fts, rowid, path, title, body) VALUES('delete', old.id, old.path, old.title, old.body);
+						INSERT INTO notes_trigram_fts(rowid, path, title, body) VALUES (new.id, new.path, new.title, new.body);
+					END`,
+					`INSERT INTO notes_trigram_fts(notes_trigram_fts) VALUES('rebuild')`,
+				},
+			},
+
+			{ // 10
+				SQL: []string{},
+				// The aliases declared in the metadata are indexed as
+				// collections.
+				NeedsReindexing: true,
+			},
 		}
 
 		needsReindexing := false
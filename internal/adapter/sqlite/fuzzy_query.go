@@ -0,0 +1,77 @@
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/zk-org/zk/internal/util/fts5"
+)
+
+// fuzzyQuery holds the SQL needed to match a --fuzzy query with the trigram
+// index.
+type fuzzyQuery struct {
+	// Full-text query of the trigram index selecting the candidate notes,
+	// sharing at least one trigram with the query. Empty when the query has
+	// no word long enough to be split into trigrams.
+	fts string
+	// SQL expressions requiring each word of the query to be approximately
+	// found in the note.
+	exprs []string
+	args  []interface{}
+}
+
+// parseFuzzyQuery converts a --fuzzy query into the SQL expressions matching
+// it with the trigram index.
+//
+// A word matches a note when at least half of its trigrams are found in the
+// note, which tolerates a typo in words of five letters or more, e.g.
+// "indexng" matches "indexing". Words shorter than three characters can't be
+// split into trigrams, so they must be found as-is. The FTS query syntax is
+// not supported, punctuation only separates the words.
+func parseFuzzyQuery(query string) fuzzyQuery {
+	res := fuzzyQuery{}
+	ftsTerms := []string{}
+
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for _, word := range words {
+		trigrams := wordTrigrams(word)
+		if len(trigrams) == 0 {
+			res.exprs = append(res.exprs, `(n.title LIKE '%' || ? || '%' ESCAPE '\' OR n.body LIKE '%' || ? || '%' ESCAPE '\')`)
+			term := escapeLikeTerm(word, '\\')
+			res.args = append(res.args, term, term)
+			continue
+		}
+
+		found := []string{}
+		for _, trigram := range trigrams {
+			quoted := fts5.QuoteQuery(trigram)
+			ftsTerms = append(ftsTerms, quoted)
+			found = append(found, "(n.id IN (SELECT rowid FROM notes_trigram_fts WHERE notes_trigram_fts MATCH ?))")
+			res.args = append(res.args, quoted)
+		}
+		res.exprs = append(res.exprs, fmt.Sprintf("(%s) >= %d", strings.Join(found, " + "), (len(trigrams)+1)/2))
+	}
+
+	res.fts = strings.Join(ftsTerms, " OR ")
+	return res
+}
+
+// wordTrigrams returns the distinct sequences of three characters found in
+// the given word.
+func wordTrigrams(word string) []string {
+	runes := []rune(word)
+	trigrams := []string{}
+	seen := map[string]bool{}
+	for i := 0; i+3 <= len(runes); i++ {
+		trigram := string(runes[i : i+3])
+		if !seen[trigram] {
+			seen[trigram] = true
+			trigrams = append(trigrams, trigram)
+		}
+	}
+	return trigrams
+}
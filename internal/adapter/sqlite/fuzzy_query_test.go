@@ -0,0 +1,34 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/zk-org/zk/internal/util/test/assert"
+)
+
+func TestWordTrigrams(t *testing.T) {
+	test := func(word string, expected []string) {
+		assert.Equal(t, wordTrigrams(word), expected)
+	}
+
+	test("", []string{})
+	test("ab", []string{})
+	test("abc", []string{"abc"})
+	test("index", []string{"ind", "nde", "dex"})
+	test("aaaa", []string{"aaa"})
+	test("café", []string{"caf", "afé"})
+}
+
+func TestParseFuzzyQuery(t *testing.T) {
+	test := func(query string, expectedFts string, expectedArgs []interface{}) {
+		res := parseFuzzyQuery(query)
+		assert.Equal(t, res.fts, expectedFts)
+		assert.Equal(t, res.args, expectedArgs)
+	}
+
+	test("", "", nil)
+	test("Index", `"ind" OR "nde" OR "dex"`, []interface{}{`"ind"`, `"nde"`, `"dex"`})
+	test("go, fish", `"fis" OR "ish"`, []interface{}{"go", "go", `"fis"`, `"ish"`})
+	// Quotes and operators are not interpreted.
+	test(`"foo" -bar`, `"foo" OR "bar"`, []interface{}{`"foo"`, `"bar"`})
+}
@@ -3,9 +3,12 @@ package sqlite
 import (
 	"database/sql"
 	"fmt"
+	"path/filepath"
+	"strings"
 
 	"github.com/zk-org/zk/internal/core"
 	"github.com/zk-org/zk/internal/util"
+	"github.com/zk-org/zk/internal/util/paths"
 )
 
 // LinkDAO persists links in the SQLite database.
@@ -17,6 +20,7 @@ type LinkDAO struct {
 	addLinkStmt        *LazyStmt
 	removeLinksStmt    *LazyStmt
 	updateTargetIDStmt *LazyStmt
+	updateHrefStmt     *LazyStmt
 }
 
 // NewLinkDAO creates a new instance of a DAO working on the given database
@@ -43,6 +47,12 @@ func NewLinkDAO(tx Transaction, logger util.Logger) *LinkDAO {
 			   SET target_id = ?
 			 WHERE id = ?
 		`),
+
+		updateHrefStmt: tx.PrepareLazy(`
+			UPDATE links
+			   SET href = ?
+			 WHERE id = ?
+		`),
 	}
 }
 
@@ -73,6 +83,71 @@ func (d *LinkDAO) SetTargetID(id core.LinkID, targetID core.NoteID) error {
 	return err
 }
 
+// RenameHrefs rewrites the hrefs of the links pointing to the given note,
+// after it was moved from oldPath to newPath.
+//
+// Only the hrefs spelling out oldPath, with or without its extension, are
+// rewritten. They can be relative to the notebook root or to the directory of
+// the source note, and keep the same form. Any anchor is preserved. Other
+// hrefs, such as wiki links matching only the filename or title of the note,
+// are left untouched and might not resolve anymore.
+func (d *LinkDAO) RenameHrefs(targetID core.NoteID, oldPath, newPath string) error {
+	links, err := d.findWhere(fmt.Sprintf("target_id = %d AND external = 0", targetID), "")
+	if err != nil {
+		return err
+	}
+
+	for _, link := range links {
+		href, ok := renameHref(link.Href, filepath.Dir(link.SourcePath), oldPath, newPath)
+		if !ok {
+			continue
+		}
+		_, err := d.updateHrefStmt.Exec(href, linkIDToSQL(link.ID))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// renameHref returns the given href pointing to newPath instead of oldPath,
+// if it matches. The href of a link found in sourceDir can be relative to the
+// notebook root or to sourceDir.
+func renameHref(href, sourceDir, oldPath, newPath string) (string, bool) {
+	path, anchor := href, ""
+	if i := strings.Index(href, "#"); i >= 0 {
+		path, anchor = href[:i], href[i:]
+	}
+
+	if path, ok := renamePath(path, oldPath, newPath); ok {
+		return path + anchor, true
+	}
+
+	path, ok := renamePath(filepath.Join(sourceDir, path), oldPath, newPath)
+	if !ok {
+		return href, false
+	}
+	path, err := filepath.Rel(sourceDir, path)
+	if err != nil {
+		return href, false
+	}
+	return path + anchor, true
+}
+
+// renamePath returns newPath if path is oldPath, with or without its
+// extension.
+func renamePath(path, oldPath, newPath string) (string, bool) {
+	switch path {
+	case oldPath:
+		return newPath, true
+	case paths.DropExt(oldPath):
+		return paths.DropExt(newPath), true
+	default:
+		return path, false
+	}
+}
+
 // joinLinkRels will concatenate a list of rels into a SQLite ready string.
 // Each rel is delimited by \x01 for easy matching in queries.
 func joinLinkRels(rels []core.LinkRelation) string {
@@ -89,17 +164,78 @@ func joinLinkRels(rels []core.LinkRelation) string {
 
 // FindInternal returns all the links internal to the notebook.
 func (d *LinkDAO) FindInternal() ([]core.ResolvedLink, error) {
-	return d.findWhere("external = 0")
+	return d.findWhere("external = 0", "")
+}
+
+// CountBacklinks returns the number of distinct notes linking to each note,
+// indexed by the path of the target note. The links of a note to itself are
+// ignored.
+func (d *LinkDAO) CountBacklinks() (map[string]int, error) {
+	counts := map[string]int{}
+
+	rows, err := d.tx.Query(`
+		SELECT n.path, COUNT(DISTINCT l.source_id)
+		  FROM links l
+		  JOIN notes n ON n.id = l.target_id
+		 WHERE l.source_id != l.target_id AND l.external = 0
+		 GROUP BY l.target_id
+	`)
+	if err != nil {
+		return counts, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var path string
+		var count int
+		err := rows.Scan(&path, &count)
+		if err != nil {
+			return counts, err
+		}
+		counts[path] = count
+	}
+
+	return counts, rows.Err()
 }
 
-// FindBetweenNotes returns all the links existing between the given notes.
+// FindUnresolved returns all the internal links which don't point to any
+// indexed note, grouped by source note.
+func (d *LinkDAO) FindUnresolved() ([]core.ResolvedLink, error) {
+	return d.findWhere("target_id IS NULL AND external = 0", orderBySourcePosition)
+}
+
+// FindBetweenNotes returns all the internal links existing between the given
+// notes.
 func (d *LinkDAO) FindBetweenNotes(ids []core.NoteID) ([]core.ResolvedLink, error) {
 	idsString := joinNoteIDs(ids, ",")
-	return d.findWhere(fmt.Sprintf("source_id IN (%s) AND target_id IN (%s)", idsString, idsString))
+	return d.findWhere(fmt.Sprintf("source_id IN (%s) AND target_id IN (%s) AND external = 0", idsString, idsString), "")
 }
 
-// findWhere returns all the links, filtered by the given where query.
-func (d *LinkDAO) findWhere(where string) ([]core.ResolvedLink, error) {
+// FindByTarget returns all the internal links pointing to the given note,
+// grouped by source note. The links of the note to itself are ignored.
+func (d *LinkDAO) FindByTarget(id core.NoteID) ([]core.ResolvedLink, error) {
+	return d.findWhere(
+		fmt.Sprintf("target_id = %d AND source_id != target_id AND external = 0", id),
+		orderBySourcePosition,
+	)
+}
+
+// FindBySource returns all the outbound links of the given note, in the order
+// they appear in its content.
+//
+// Links which could not be resolved, e.g. broken or external ones, have an
+// invalid TargetID.
+func (d *LinkDAO) FindBySource(id core.NoteID) ([]core.ResolvedLink, error) {
+	return d.findWhere(fmt.Sprintf("source_id = %d", id), "snippet_start, id")
+}
+
+// orderBySourcePosition sorts the links by source note, then in the order they
+// appear in its content.
+const orderBySourcePosition = "source_path, snippet_start, id"
+
+// findWhere returns all the links, filtered by the given where query and
+// sorted by the given order by clause.
+func (d *LinkDAO) findWhere(where string, orderBy string) ([]core.ResolvedLink, error) {
 	links := make([]core.ResolvedLink, 0)
 
 	query := `
@@ -110,6 +246,9 @@ func (d *LinkDAO) findWhere(where string) ([]core.ResolvedLink, error) {
 	if where != "" {
 		query += "\nWHERE " + where
 	}
+	if orderBy != "" {
+		query += "\nORDER BY " + orderBy
+	}
 
 	rows, err := d.tx.Query(query)
 	if err != nil {
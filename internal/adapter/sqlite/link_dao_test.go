@@ -23,6 +23,192 @@ type linkRow struct {
 	IsExternal                       bool
 }
 
+func TestLinkDAOFindByTarget(t *testing.T) {
+	test := func(id core.NoteID, expected []string) {
+		testLinkDAO(t, func(tx Transaction, dao *LinkDAO) {
+			links, err := dao.FindByTarget(id)
+			assert.Nil(t, err)
+
+			actual := make([]string, 0)
+			for _, link := range links {
+				assert.Equal(t, link.TargetID, id)
+				actual = append(actual, link.SourcePath+": "+link.Snippet)
+			}
+			assert.Equal(t, actual, expected)
+		})
+	}
+
+	test(6, []string{"f39c8.md: [[Link from 4 to 6]]", "f39c8.md: [[Duplicated link]]"})
+	test(3, []string{"log/2021-01-04.md: [[A transition link]]"})
+	test(5, []string{})
+}
+
+func TestLinkDAOFindByTargetIgnoresSelfLinks(t *testing.T) {
+	testLinkDAO(t, func(tx Transaction, dao *LinkDAO) {
+		_, err := tx.Exec("UPDATE links SET source_id = target_id WHERE target_id = 3")
+		assert.Nil(t, err)
+
+		links, err := dao.FindByTarget(3)
+		assert.Nil(t, err)
+		assert.Equal(t, len(links), 0)
+	})
+}
+
+func TestLinkDAOFindByTargetIgnoresExternalLinks(t *testing.T) {
+	testLinkDAO(t, func(tx Transaction, dao *LinkDAO) {
+		_, err := tx.Exec("UPDATE links SET target_id = 5 WHERE id = 3")
+		assert.Nil(t, err)
+
+		links, err := dao.FindByTarget(5)
+		assert.Nil(t, err)
+		assert.Equal(t, len(links), 0)
+
+		links, err = dao.FindBetweenNotes([]core.NoteID{1, 5})
+		assert.Nil(t, err)
+		assert.Equal(t, len(links), 0)
+	})
+}
+
+func TestLinkDAOFindBySource(t *testing.T) {
+	test := func(id core.NoteID, expected []string) {
+		testLinkDAO(t, func(tx Transaction, dao *LinkDAO) {
+			_, err := tx.Exec("UPDATE links SET snippet_start = 40 WHERE id = 1")
+			assert.Nil(t, err)
+			_, err = tx.Exec("UPDATE links SET snippet_start = 10 WHERE id = 8")
+			assert.Nil(t, err)
+
+			links, err := dao.FindBySource(id)
+			assert.Nil(t, err)
+
+			actual := make([]string, 0)
+			for _, link := range links {
+				assert.Equal(t, link.SourceID, id)
+				actual = append(actual, fmt.Sprintf("%s (resolved: %v, external: %v)", link.Href, link.TargetID.IsValid(), link.IsExternal))
+			}
+			assert.Equal(t, actual, expected)
+		})
+	}
+
+	test(1, []string{
+		"log/2021-01-04.md (resolved: true, external: false)",
+		"https://domain.com (resolved: false, external: true)",
+	})
+	test(3, []string{
+		"f39c8.md (resolved: true, external: false)",
+		"missing (resolved: false, external: false)",
+	})
+	test(5, []string{})
+}
+
+func TestLinkDAOCountBacklinks(t *testing.T) {
+	testLinkDAO(t, func(tx Transaction, dao *LinkDAO) {
+		// A second note linking to ref/test/a.md.
+		_, err := tx.Exec("UPDATE links SET target_id = 6 WHERE id = 2")
+		assert.Nil(t, err)
+		// A link of index.md to itself.
+		_, err = tx.Exec("UPDATE links SET target_id = 3 WHERE id = 1")
+		assert.Nil(t, err)
+
+		counts, err := dao.CountBacklinks()
+		assert.Nil(t, err)
+		assert.Equal(t, counts, map[string]int{
+			"log/2021-01-03.md": 1,
+			"index.md":          1,
+			"f39c8.md":          1,
+			"ref/test/a.md":     2,
+		})
+	})
+}
+
+func TestLinkDAOFindUnresolved(t *testing.T) {
+	testLinkDAO(t, func(tx Transaction, dao *LinkDAO) {
+		_, err := tx.Exec("UPDATE links SET target_id = NULL WHERE id = 2")
+		assert.Nil(t, err)
+
+		links, err := dao.FindUnresolved()
+		assert.Nil(t, err)
+
+		actual := make([]string, 0)
+		for _, link := range links {
+			assert.False(t, link.TargetID.IsValid())
+			actual = append(actual, link.SourcePath+": "+link.Href)
+		}
+		// The external links are ignored.
+		assert.Equal(t, actual, []string{
+			"index.md: missing",
+			"log/2021-01-03.md: log/2021-01-04.md",
+		})
+	})
+}
+
+func TestLinkDAOFindLoadsSnippetOffsets(t *testing.T) {
+	testLinkDAO(t, func(tx Transaction, dao *LinkDAO) {
+		_, err := tx.Exec("UPDATE links SET snippet_start = 12, snippet_end = 32 WHERE id = 2")
+		assert.Nil(t, err)
+
+		assertOffsets := func(links []core.ResolvedLink) {
+			for _, link := range links {
+				if link.Href == "log/2021-01-04.md" {
+					assert.Equal(t, link.Snippet, "[[An internal link]]")
+					assert.Equal(t, link.SnippetStart, 12)
+					assert.Equal(t, link.SnippetEnd, 32)
+					return
+				}
+			}
+			t.Errorf("link not found: %v", links)
+		}
+
+		links, err := dao.FindBySource(1)
+		assert.Nil(t, err)
+		assertOffsets(links)
+
+		links, err = dao.FindBetweenNotes([]core.NoteID{1, 2})
+		assert.Nil(t, err)
+		assertOffsets(links)
+	})
+}
+
+func TestLinkDAORenameHrefs(t *testing.T) {
+	testLinkDAO(t, func(tx Transaction, dao *LinkDAO) {
+		err := dao.RenameHrefs(6, "ref/test/a.md", "archive/a.md")
+		assert.Nil(t, err)
+		err = dao.RenameHrefs(2, "log/2021-01-04.md", "log/2021-01-05.md")
+		assert.Nil(t, err)
+		// Hrefs not matching the old path are left untouched.
+		err = dao.RenameHrefs(4, "elsewhere.md", "new.md")
+		assert.Nil(t, err)
+
+		hrefs := []string{}
+		for _, link := range queryLinkRows(t, tx, "id IN (2, 5, 6, 8)") {
+			hrefs = append(hrefs, link.Href)
+		}
+		assert.Equal(t, hrefs, []string{"log/2021-01-05.md", "archive/a", "archive/a", "f39c8.md"})
+	})
+}
+
+func TestRenameHref(t *testing.T) {
+	test := func(href, sourceDir, oldPath, newPath, expected string, expectedOK bool) {
+		actual, ok := renameHref(href, sourceDir, oldPath, newPath)
+		assert.Equal(t, actual, expected)
+		assert.Equal(t, ok, expectedOK)
+	}
+
+	test("dir/note.md", ".", "dir/note.md", "other/new.md", "other/new.md", true)
+	test("dir/note", ".", "dir/note.md", "other/new.md", "other/new", true)
+	test("dir/note.md#section", ".", "dir/note.md", "other/new.md", "other/new.md#section", true)
+	test("dir/note#section", ".", "dir/note.md", "other/new.md", "other/new#section", true)
+	test("./dir/note.md", ".", "dir/note.md", "other/new.md", "other/new.md", true)
+	test("dir/note.md", ".", "dir/other.md", "other/new.md", "dir/note.md", false)
+	// Hrefs relative to the directory of the source note.
+	test("note.md", "dir", "dir/note.md", "other/new.md", "../other/new.md", true)
+	test("note#section", "dir", "dir/note.md", "dir/new.md", "new#section", true)
+	test("../dir/note.md", "src", "dir/note.md", "other/new.md", "../other/new.md", true)
+	test("dir/note.md", "src", "dir/note.md", "other/new.md", "other/new.md", true)
+	// Partial hrefs, such as wiki links matching only the filename.
+	test("note", ".", "dir/note.md", "other/new.md", "note", false)
+	test("note", "src", "dir/note.md", "other/new.md", "note", false)
+}
+
 func queryLinkRows(t *testing.T, q RowQuerier, where string) []linkRow {
 	links := make([]linkRow, 0)
 
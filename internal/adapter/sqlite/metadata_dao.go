@@ -8,6 +8,8 @@ import (
 
 // Known metadata keys.
 var reindexingRequiredKey = "zk.reindexing_required"
+var lastListedAtKey = "zk.last_listed_at"
+var lastIndexedAtKey = "zk.last_indexed_at"
 
 // MetadataDAO persists arbitrary key/value pairs in the SQLite database.
 type MetadataDAO struct {
@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/zk-org/zk/internal/core"
 	"github.com/zk-org/zk/internal/util"
@@ -23,12 +25,21 @@ type NoteDAO struct {
 
 	// Prepared SQL statements
 	indexedStmt            *LazyStmt
+	indexedSinceStmt       *LazyStmt
 	addStmt                *LazyStmt
 	updateStmt             *LazyStmt
 	removeStmt             *LazyStmt
+	renameStmt             *LazyStmt
+	touchStmt              *LazyStmt
 	findIdByPathStmt       *LazyStmt
+	findChecksumStmt       *LazyStmt
 	findIdsByPathRegexStmt *LazyStmt
+	findIdsByTitleStmt     *LazyStmt
 	findByIdStmt           *LazyStmt
+	findByChecksumStmt     *LazyStmt
+	findRawContentStmt     *LazyStmt
+	locateMatchStmt        *LazyStmt
+	locateFuzzyMatchStmt   *LazyStmt
 }
 
 // NewNoteDAO creates a new instance of a DAO working on the given database
@@ -44,6 +55,13 @@ func NewNoteDAO(tx Transaction, logger util.Logger) *NoteDAO {
 			 ORDER BY sortable_path ASC
 		`),
 
+		// Get file info about the indexed notes modified since a given date.
+		indexedSinceStmt: tx.PrepareLazy(`
+			SELECT path, modified from notes
+			 WHERE modified >= ?
+			 ORDER BY sortable_path ASC
+		`),
+
 		// Add a new note to the index.
 		addStmt: tx.PrepareLazy(`
 			INSERT INTO notes (path, sortable_path, title, lead, body, raw_content, word_count, metadata, checksum, created, modified)
@@ -63,27 +81,88 @@ func NewNoteDAO(tx Transaction, logger util.Logger) *NoteDAO {
 			 WHERE id = ?
 		`),
 
+		// Move a note to a new path.
+		renameStmt: tx.PrepareLazy(`
+			UPDATE notes
+			   SET path = ?, sortable_path = ?
+			 WHERE id = ?
+		`),
+
+		// Change the modification date of a note.
+		touchStmt: tx.PrepareLazy(`
+			UPDATE notes
+			   SET modified = ?
+			 WHERE id = ?
+		`),
+
 		// Find a note ID from its exact path.
 		findIdByPathStmt: tx.PrepareLazy(`
 			SELECT id FROM notes
 			 WHERE path = ?
 		`),
 
+		// Find the checksum of a note from its path.
+		findChecksumStmt: tx.PrepareLazy(`
+			SELECT checksum FROM notes
+			 WHERE path = ?
+		`),
+
 		// Find note IDs from a regex matching their path.
 		findIdsByPathRegexStmt: tx.PrepareLazy(`
 			SELECT id FROM notes
 			 WHERE path REGEXP ?
 				-- To find the best match possible, we sort by path length.
 				-- See https://github.com/zk-org/zk/issues/23
-			 ORDER BY LENGTH(path) ASC
+				-- Ties are broken by path, to keep ambiguous matches stable.
+			 ORDER BY LENGTH(path) ASC, sortable_path ASC
+		`),
+
+		// Find note IDs from their title, regardless of its case.
+		findIdsByTitleStmt: tx.PrepareLazy(`
+			SELECT id FROM notes
+			 WHERE title = ? COLLATE NOCASE
+			 ORDER BY sortable_path ASC
 		`),
 
 		// Find a note from its ID.
 		findByIdStmt: tx.PrepareLazy(`
-			SELECT id, path, title, lead, body, raw_content, word_count, created, modified, metadata, checksum, tags, lead AS snippet
+			SELECT id, path, title, metadata, lead, body, raw_content, word_count, created, modified, checksum, tags, lead AS snippet, 0 AS score
+			  FROM notes_with_metadata
+			 WHERE id = ?
+		`),
+
+		// Find the notes with the given content checksum.
+		findByChecksumStmt: tx.PrepareLazy(`
+			SELECT id, path, title, metadata, lead, body, raw_content, word_count, created, modified, checksum, tags, lead AS snippet, 0 AS score
 			  FROM notes_with_metadata
+			 WHERE checksum = ?
+			 ORDER BY sortable_path ASC
+		`),
+
+		// Find the raw content of a note from its ID.
+		findRawContentStmt: tx.PrepareLazy(`
+			SELECT raw_content FROM notes
 			 WHERE id = ?
 		`),
+
+		// Find the title and body of a note with the tokens matching a
+		// full-text query marked with \x02.
+		locateMatchStmt: tx.PrepareLazy(`
+			SELECT n.raw_content, n.title, n.body,
+			       highlight(notes_fts, 1, char(2), ''), highlight(notes_fts, 2, char(2), '')
+			  FROM notes_fts
+			  JOIN notes n ON n.id = notes_fts.rowid
+			 WHERE notes_fts MATCH ? AND notes_fts.rowid = ?
+		`),
+
+		// Same as locateMatchStmt, with the trigram index used by --fuzzy.
+		locateFuzzyMatchStmt: tx.PrepareLazy(`
+			SELECT n.raw_content, n.title, n.body,
+			       highlight(notes_trigram_fts, 1, char(2), ''), highlight(notes_trigram_fts, 2, char(2), '')
+			  FROM notes_trigram_fts
+			  JOIN notes n ON n.id = notes_trigram_fts.rowid
+			 WHERE notes_trigram_fts MATCH ? AND notes_trigram_fts.rowid = ?
+		`),
 	}
 }
 
@@ -93,7 +172,22 @@ func (d *NoteDAO) Indexed() (<-chan paths.Metadata, error) {
 	if err != nil {
 		return nil, err
 	}
+	return d.streamMetadata(rows), nil
+}
 
+// IndexedSince returns file info of the indexed notes modified since the
+// given date.
+func (d *NoteDAO) IndexedSince(t time.Time) (<-chan paths.Metadata, error) {
+	rows, err := d.indexedSinceStmt.Query(t)
+	if err != nil {
+		return nil, err
+	}
+	return d.streamMetadata(rows), nil
+}
+
+// streamMetadata sends the path and modified date of each row to the
+// returned channel, until the rows are exhausted.
+func (d *NoteDAO) streamMetadata(rows *sql.Rows) <-chan paths.Metadata {
 	c := make(chan paths.Metadata)
 	go func() {
 		defer close(c)
@@ -115,28 +209,31 @@ func (d *NoteDAO) Indexed() (<-chan paths.Metadata, error) {
 			}
 		}
 
-		err = rows.Err()
+		err := rows.Err()
 		if err != nil {
 			d.logger.Err(err)
 		}
 	}()
 
-	return c, nil
+	return c
 }
 
 // Add inserts a new note to the index.
+//
+// Returns core.ErrNoteIndexed if a note is already indexed at the same path,
+// so that callers can update it instead.
 func (d *NoteDAO) Add(note core.Note) (core.NoteID, error) {
-	// For sortable_path, we replace in path / by the shortest non printable
-	// character available to make it sortable. Without this, sorting by the
-	// path would be a lexicographical sort instead of being the same order
-	// returned by filepath.Walk.
-	// \x01 is used instead of \x00, because SQLite treats \x00 as and end of
-	// string.
-	sortablePath := strings.ReplaceAll(note.Path, "/", "\x01")
+	id, err := d.FindIdByPath(note.Path)
+	if err != nil {
+		return 0, err
+	}
+	if id.IsValid() {
+		return 0, core.ErrNoteIndexed{Path: note.Path}
+	}
 
 	metadata := d.metadataToJSON(note)
 	res, err := d.addStmt.Exec(
-		note.Path, sortablePath, note.Title, note.Lead, note.Body,
+		note.Path, sortablePath(note.Path), note.Title, note.Lead, note.Body,
 		note.RawContent, note.WordCount, metadata, note.Checksum, note.Created,
 		note.Modified,
 	)
@@ -170,7 +267,69 @@ func (d *NoteDAO) Update(note core.Note) (core.NoteID, error) {
 	return id, err
 }
 
+// Rename moves the note at oldPath to newPath in the index.
+//
+// The links pointing to the note are resolved by ID, so they stay valid. Use
+// LinkDAO.RenameHrefs to rewrite their hrefs as well, which handles only the
+// hrefs spelling out the path of the note.
+func (d *NoteDAO) Rename(oldPath, newPath string) error {
+	id, err := d.FindIdByPath(oldPath)
+	if err != nil {
+		return err
+	}
+	if !id.IsValid() {
+		return fmt.Errorf("%s: note not found in the index", oldPath)
+	}
+
+	existingID, err := d.FindIdByPath(newPath)
+	if err != nil {
+		return err
+	}
+	if existingID.IsValid() && existingID != id {
+		return fmt.Errorf("%s: a note already exists at this path", newPath)
+	}
+
+	_, err = d.renameStmt.Exec(newPath, sortablePath(newPath), id)
+	return err
+}
+
+// Touch sets the modification date of the note with the given path, leaving
+// its content, checksum and links untouched.
+func (d *NoteDAO) Touch(path string, modified time.Time) error {
+	id, err := d.FindIdByPath(path)
+	if err != nil {
+		return err
+	}
+	if !id.IsValid() {
+		return fmt.Errorf("%s: note not found in the index", path)
+	}
+
+	_, err = d.touchStmt.Exec(modified, id)
+	return err
+}
+
+// sortablePath returns the value of the sortable_path column for the given
+// note path.
+//
+// We replace in path / by the shortest non printable character available to
+// make it sortable. Without this, sorting by the path would be a
+// lexicographical sort instead of being the same order returned by
+// filepath.Walk.
+// \x01 is used instead of \x00, because SQLite treats \x00 as and end of
+// string.
+func sortablePath(path string) string {
+	return strings.ReplaceAll(path, "/", "\x01")
+}
+
+// metadataToJSON serializes the metadata of the note for the metadata column.
+//
+// The notes without any frontmatter keys are always stored as an empty JSON
+// object, including when their metadata map is nil, which --no-frontmatter
+// relies on.
 func (d *NoteDAO) metadataToJSON(note core.Note) string {
+	if len(note.Metadata) == 0 {
+		return "{}"
+	}
 	json, err := json.Marshal(note.Metadata)
 	if err != nil {
 		// Failure to serialize the metadata to JSON should not prevent the
@@ -203,6 +362,26 @@ func (d *NoteDAO) FindIdByPath(path string) (core.NoteID, error) {
 	return idForRow(row)
 }
 
+// Checksum returns the checksum of the indexed note at the given path, or an
+// empty string if it is not indexed.
+func (d *NoteDAO) Checksum(path string) (string, error) {
+	row, err := d.findChecksumStmt.QueryRow(path)
+	if err != nil {
+		return "", err
+	}
+
+	var checksum sql.NullString
+	err = row.Scan(&checksum)
+	switch {
+	case err == sql.ErrNoRows:
+		return "", nil
+	case err != nil:
+		return "", err
+	default:
+		return checksum.String, nil
+	}
+}
+
 func idForRow(row *sql.Row) (core.NoteID, error) {
 	var id sql.NullInt64
 	err := row.Scan(&id)
@@ -218,8 +397,26 @@ func idForRow(row *sql.Row) (core.NoteID, error) {
 }
 
 func (d *NoteDAO) findIdsByPathRegex(regex string) ([]core.NoteID, error) {
+	return d.findIdsWithStmt(d.findIdsByPathRegexStmt, regex)
+}
+
+// findIdsByTitles returns the IDs of all the notes having one of the given
+// titles, so an ambiguous title matches several notes.
+func (d *NoteDAO) findIdsByTitles(titles []string) ([]core.NoteID, error) {
+	ids := make([]core.NoteID, 0)
+	for _, title := range titles {
+		tids, err := d.findIdsWithStmt(d.findIdsByTitleStmt, strings.TrimSpace(title))
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, tids...)
+	}
+	return ids, nil
+}
+
+func (d *NoteDAO) findIdsWithStmt(stmt *LazyStmt, args ...interface{}) ([]core.NoteID, error) {
 	ids := []core.NoteID{}
-	rows, err := d.findIdsByPathRegexStmt.Query(regex)
+	rows, err := stmt.Query(args...)
 	if err != nil {
 		return ids, err
 	}
@@ -265,10 +462,10 @@ func (d *NoteDAO) FindIdByHref(href string, allowPartialHref bool) (core.NoteID,
 	return ids[0], nil
 }
 
-func (d *NoteDAO) findIdsByHrefs(hrefs []string, allowPartialHrefs bool) ([]core.NoteID, error) {
+func (d *NoteDAO) findIdsByHrefs(hrefs []string, allowPartialHrefs bool, ignoreCase bool) ([]core.NoteID, error) {
 	ids := make([]core.NoteID, 0)
 	for _, href := range hrefs {
-		cids, err := d.FindIdsByHref(href, allowPartialHrefs)
+		cids, err := d.findIdsByHref(href, allowPartialHrefs, ignoreCase)
 		if err != nil {
 			return ids, err
 		}
@@ -279,11 +476,22 @@ func (d *NoteDAO) findIdsByHrefs(hrefs []string, allowPartialHrefs bool) ([]core
 
 // FIXME: This logic is duplicated in NoteIndex.linkMatchesPath(). Maybe there's a way to share it using a custom SQLite function?
 func (d *NoteDAO) FindIdsByHref(href string, allowPartialHref bool) ([]core.NoteID, error) {
+	return d.findIdsByHref(href, allowPartialHref, false)
+}
+
+// findIdsByHref is similar to FindIdsByHref, but can also ignore the case of
+// the paths, e.g. for case-insensitive file systems.
+func (d *NoteDAO) findIdsByHref(href string, allowPartialHref bool, ignoreCase bool) ([]core.NoteID, error) {
 	// Remove any anchor at the end of the HREF, since it's most likely
 	// matching a sub-section in the note.
 	href = strings.SplitN(href, "#", 2)[0]
 
 	href = regexp.QuoteMeta(href)
+	if ignoreCase {
+		// Only the href needs the flag, the rest of the patterns below
+		// don't contain any letter.
+		href = "(?i:" + href + ")"
+	}
 
 	if allowPartialHref {
 		ids, err := d.findIdsByPathRegex("^(.*/)?[^/]*" + href + "[^/]*$")
@@ -333,6 +541,37 @@ func (d *NoteDAO) FindMinimal(opts core.NoteFindOpts) ([]core.MinimalNote, error
 	return notes, nil
 }
 
+// FindPaths returns only the paths of the notes matching the given criteria.
+//
+// This is much faster than Find, as the notes are not loaded and their
+// snippets are not computed.
+func (d *NoteDAO) FindPaths(opts core.NoteFindOpts) ([]string, error) {
+	paths := make([]string, 0)
+
+	opts, err := d.expandMentionsIntoMatch(opts)
+	if err != nil {
+		return paths, err
+	}
+
+	rows, err := d.findRows(opts, noteSelectionPath)
+	if err != nil {
+		return paths, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var path string
+		err := rows.Scan(&path)
+		if err != nil {
+			d.logger.Err(err)
+			continue
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
 // Find returns all the notes matching the given criteria.
 func (d *NoteDAO) Find(opts core.NoteFindOpts) ([]core.ContextualNote, error) {
 	notes := make([]core.ContextualNote, 0)
@@ -346,8 +585,313 @@ func (d *NoteDAO) Find(opts core.NoteFindOpts) ([]core.ContextualNote, error) {
 	if err != nil {
 		return notes, err
 	}
+	notes = d.scanNotes(rows)
+
+	if opts.LocateMatch {
+		for i := range notes {
+			notes[i].MatchOffset, err = d.locateMatch(notes[i], opts)
+			if err != nil {
+				return notes, err
+			}
+		}
+	}
+	if opts.Related != nil && len(notes) > 0 {
+		err = d.findRelatedBridges(opts, notes)
+		if err != nil {
+			return notes, err
+		}
+	}
+	if opts.IncludeBacklinks && len(notes) > 0 {
+		err = d.findBacklinks(notes)
+		if err != nil {
+			return notes, err
+		}
+	}
+	return notes, nil
+}
+
+// findBacklinks fills the notes linking to each one of the given notes, with
+// a single query for all of them.
+func (d *NoteDAO) findBacklinks(notes []core.ContextualNote) error {
+	ids := make([]core.NoteID, 0, len(notes))
+	for _, note := range notes {
+		ids = append(ids, note.ID)
+	}
+
+	// A note linking to itself is not its own backlink.
+	rows, err := d.tx.Query(fmt.Sprintf(`SELECT DISTINCT l.target_id, s.id, s.path, s.title, s.sortable_path
+  FROM links l
+  JOIN notes s ON s.id = l.source_id
+ WHERE l.target_id IN (%s) AND l.external = 0 AND l.source_id != l.target_id
+ ORDER BY s.title ASC, s.sortable_path ASC`,
+		joinNoteIDs(ids, ","),
+	))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	backlinks := map[core.NoteID][]core.Backlink{}
+	for rows.Next() {
+		var (
+			noteID, sourceID      int
+			path, title, sortable string
+		)
+		err := rows.Scan(&noteID, &sourceID, &path, &title, &sortable)
+		if err != nil {
+			return err
+		}
+		backlinks[core.NoteID(noteID)] = append(backlinks[core.NoteID(noteID)], core.Backlink{
+			ID:    core.NoteID(sourceID),
+			Path:  path,
+			Title: title,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i, note := range notes {
+		notes[i].Backlinks = backlinks[note.ID]
+	}
+	return nil
+}
+
+// findRelatedBridges fills the intermediate notes connecting each one of the
+// given notes, found with NoteFindOpts.Related, to the related notes.
+//
+// Related notes are always two links away, so the bridges are found with a
+// simple join instead of walking the transitive closure again.
+func (d *NoteDAO) findRelatedBridges(opts core.NoteFindOpts, notes []core.ContextualNote) error {
+	relatedIDs, err := d.findIdsByHrefs(opts.Related, true /* allowPartialHrefs */, opts.IgnoreHrefCase)
+	if err != nil {
+		return err
+	}
+
+	foundIDs := make([]core.NoteID, 0, len(notes))
+	for _, note := range notes {
+		foundIDs = append(foundIDs, note.ID)
+	}
+
+	query := fmt.Sprintf(`SELECT l2.target_id, b.id, b.path, 1 AS outbound, b.sortable_path
+  FROM links l1
+  JOIN links l2 ON l2.source_id = l1.target_id
+  JOIN notes b ON b.id = l1.target_id
+ WHERE l1.source_id IN (%[1]s) AND l2.target_id IN (%[2]s)
+   AND l1.external = 0 AND l2.external = 0
+   AND b.id NOT IN (l1.source_id, l2.target_id)
+UNION
+SELECT l1.source_id, b.id, b.path, 0 AS outbound, b.sortable_path
+  FROM links l1
+  JOIN links l2 ON l2.source_id = l1.target_id
+  JOIN notes b ON b.id = l1.target_id
+ WHERE l1.source_id IN (%[2]s) AND l2.target_id IN (%[1]s)
+   AND l1.external = 0 AND l2.external = 0
+   AND b.id NOT IN (l1.source_id, l2.target_id)
+ ORDER BY outbound DESC, sortable_path ASC`,
+		joinNoteIDs(relatedIDs, ","), joinNoteIDs(foundIDs, ","),
+	)
+
+	rows, err := d.tx.Query(query)
+	if err != nil {
+		return err
+	}
 	defer rows.Close()
 
+	bridges := map[core.NoteID][]core.RelatedBridge{}
+	for rows.Next() {
+		var (
+			noteID, bridgeID int
+			path, sortable   string
+			outbound         bool
+		)
+		err := rows.Scan(&noteID, &bridgeID, &path, &outbound, &sortable)
+		if err != nil {
+			return err
+		}
+		bridges[core.NoteID(noteID)] = append(bridges[core.NoteID(noteID)], core.RelatedBridge{
+			ID:       core.NoteID(bridgeID),
+			Path:     path,
+			Outbound: outbound,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i, note := range notes {
+		notes[i].RelatedVia = bridges[note.ID]
+	}
+	return nil
+}
+
+// convertFtsQuery converts a user query into a full-text search one.
+func convertFtsQuery(query string, opts core.NoteFindOpts) string {
+	if opts.MatchLiteral {
+		return fts5.QuoteQuery(query)
+	}
+	if opts.MatchAny {
+		return fts5.ConvertAnyQuery(query)
+	}
+	return fts5.ConvertQuery(query)
+}
+
+// convertRegexQuery returns the regular expression searched for the given
+// user query.
+func convertRegexQuery(query string, opts core.NoteFindOpts) string {
+	if opts.MatchLiteral {
+		return regexp.QuoteMeta(query)
+	}
+	return query
+}
+
+// locateMatch returns the character offset of the first match of the query
+// in the raw content of the given note, or -1 if it can't be found.
+//
+// The raw content is loaded again when it was excluded from the results.
+func (d *NoteDAO) locateMatch(note core.ContextualNote, opts core.NoteFindOpts) (int, error) {
+	matchStrategy := opts.MatchStrategy
+	if opts.MatchRaw && matchStrategy == core.MatchStrategyFts && len(opts.Match) > 0 {
+		matchStrategy = core.MatchStrategyExact
+	}
+	if len(opts.Match) == 0 {
+		return -1, nil
+	}
+	if matchStrategy == core.MatchStrategyFts {
+		return d.locateFtsMatch(note.ID, opts)
+	}
+
+	content := note.RawContent
+	if opts.ExcludeBody {
+		row, err := d.findRawContentStmt.QueryRow(note.ID)
+		if err != nil {
+			return -1, err
+		}
+		err = row.Scan(&content)
+		if err != nil {
+			return -1, err
+		}
+	}
+
+	offset := -1
+	locate := func(loc []int) {
+		if loc != nil && (offset == -1 || loc[0] < offset) {
+			offset = loc[0]
+		}
+	}
+
+	for _, match := range opts.Match {
+		var re *regexp.Regexp
+		if matchStrategy == core.MatchStrategyExact {
+			re = regexp.MustCompile("(?i)" + regexp.QuoteMeta(match))
+		} else {
+			var err error
+			re, err = regexp.Compile(convertRegexQuery(match, opts))
+			if err != nil {
+				continue
+			}
+		}
+		locate(re.FindStringIndex(content))
+	}
+
+	if offset == -1 {
+		return -1, nil
+	}
+	return utf8.RuneCountInString(content[:offset]), nil
+}
+
+// locateFtsMatch returns the character offset of the first token matching
+// the full-text query in the raw content of the given note, or -1 if it
+// can't be found.
+//
+// FTS5 doesn't expose the offsets of the matched tokens, but highlight()
+// marks them in the title and body, which are then looked up in the raw
+// content. A match in the path or in the metadata can't be located.
+func (d *NoteDAO) locateFtsMatch(id core.NoteID, opts core.NoteFindOpts) (int, error) {
+	stmt := d.locateMatchStmt
+	queries := []string{}
+	sep := " AND "
+	for _, match := range opts.Match {
+		if opts.MatchFuzzy {
+			if query := parseFuzzyQuery(match).fts; query != "" {
+				queries = append(queries, query)
+			}
+		} else {
+			queries = append(queries, "("+convertFtsQuery(match, opts)+")")
+		}
+	}
+	if opts.MatchFuzzy {
+		stmt = d.locateFuzzyMatchStmt
+		sep = " OR "
+	}
+	if len(queries) == 0 {
+		return -1, nil
+	}
+
+	row, err := stmt.QueryRow(strings.Join(queries, sep), id)
+	if err != nil {
+		return -1, err
+	}
+	var rawContent, title, body, markedTitle, markedBody string
+	err = row.Scan(&rawContent, &title, &body, &markedTitle, &markedBody)
+	switch {
+	case err == sql.ErrNoRows:
+		return -1, nil
+	case err != nil:
+		return -1, err
+	}
+
+	// The bytes before the first marker are the same as in the original
+	// column. The title usually comes before the body in the raw content.
+	offset := -1
+	if i := strings.IndexByte(markedTitle, '\x02'); i >= 0 {
+		if start := strings.Index(rawContent, title); start >= 0 {
+			offset = start + i
+		}
+	}
+	if i := strings.IndexByte(markedBody, '\x02'); offset == -1 && i >= 0 {
+		// The body is the end of the raw content, after the title.
+		if start := strings.LastIndex(rawContent, body); start >= 0 {
+			offset = start + i
+		}
+	}
+
+	if offset == -1 {
+		return -1, nil
+	}
+	return utf8.RuneCountInString(rawContent[:offset]), nil
+}
+
+// FindById returns the note with the given ID, or nil if it doesn't exist.
+//
+// This avoids a round-trip through the note path for callers already
+// holding IDs, e.g. from the links.
+func (d *NoteDAO) FindById(id core.NoteID) (*core.ContextualNote, error) {
+	row, err := d.findByIdStmt.QueryRow(id)
+	if err != nil {
+		return nil, err
+	}
+	return d.scanNote(row)
+}
+
+// FindByChecksum returns all the notes with the given content checksum,
+// sorted by path.
+//
+// Several notes can share the same checksum when their files are duplicates.
+func (d *NoteDAO) FindByChecksum(checksum string) ([]core.ContextualNote, error) {
+	rows, err := d.findByChecksumStmt.Query(checksum)
+	if err != nil {
+		return []core.ContextualNote{}, err
+	}
+	return d.scanNotes(rows), nil
+}
+
+// scanNotes reads all the notes selected by the given rows, before closing
+// them. Notes which can't be read are logged and skipped.
+func (d *NoteDAO) scanNotes(rows *sql.Rows) []core.ContextualNote {
+	defer rows.Close()
+
+	notes := make([]core.ContextualNote, 0)
 	for rows.Next() {
 		note, err := d.scanNote(rows)
 		if err != nil {
@@ -359,7 +903,150 @@ func (d *NoteDAO) Find(opts core.NoteFindOpts) ([]core.ContextualNote, error) {
 		}
 	}
 
-	return notes, nil
+	return notes
+}
+
+// CountByCreationDate returns the number of notes matching the given
+// criteria, grouped by their creation date.
+func (d *NoteDAO) CountByCreationDate(opts core.NoteFindOpts, bucket core.DateBucket) ([]core.DateBucketCount, error) {
+	counts := make([]core.DateBucketCount, 0)
+
+	opts, err := d.expandMentionsIntoMatch(opts)
+	if err != nil {
+		return counts, err
+	}
+
+	idsQuery, args, err := d.findQuery(opts, noteSelectionID)
+	if err != nil {
+		return counts, err
+	}
+
+	query := fmt.Sprintf(`SELECT strftime('%s', created) AS bucket, COUNT(*)
+  FROM notes
+ WHERE id IN (%s)
+ GROUP BY bucket
+ ORDER BY bucket ASC`, dateBucketFormat(bucket), idsQuery)
+	if opts.Explain {
+		err = d.explainQuery(query, args)
+		if err != nil {
+			return counts, err
+		}
+	}
+
+	rows, err := d.tx.Query(query, args...)
+	if err != nil {
+		return counts, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var count core.DateBucketCount
+		err := rows.Scan(&count.Bucket, &count.Count)
+		if err != nil {
+			return counts, err
+		}
+		counts = append(counts, count)
+	}
+
+	return counts, rows.Err()
+}
+
+// CheckIntegrity looks for inconsistencies in the index, which can be caused
+// by a crash during indexing or by editing the database manually. It returns
+// a human-readable description of each problem found, but doesn't fix them.
+func (d *NoteDAO) CheckIntegrity() ([]string, error) {
+	problems := make([]string, 0)
+
+	// Each query selects the path of the faulty note and a detail about the
+	// problem.
+	checks := []struct {
+		query  string
+		format string
+	}{
+		{
+			query: `
+				SELECT COALESCE(s.path, '#' || l.source_id), l.href
+				  FROM links l
+				  LEFT JOIN notes s ON s.id = l.source_id
+				 WHERE l.target_id IS NOT NULL
+				   AND NOT EXISTS (SELECT 1 FROM notes WHERE id = l.target_id)
+				 ORDER BY 1, l.id
+			`,
+			format: "%s: link to %s points to a missing note",
+		},
+		{
+			query: `
+				SELECT COALESCE(n.path, '#' || nc.note_id), nc.collection_id
+				  FROM notes_collections nc
+				  LEFT JOIN notes n ON n.id = nc.note_id
+				 WHERE NOT EXISTS (SELECT 1 FROM collections WHERE id = nc.collection_id)
+				 ORDER BY 1, nc.id
+			`,
+			format: "%s: associated with the missing collection #%s",
+		},
+	}
+
+	for _, check := range checks {
+		rows, err := d.tx.Query(check.query)
+		if err != nil {
+			return problems, err
+		}
+
+		for rows.Next() {
+			var path, detail string
+			err := rows.Scan(&path, &detail)
+			if err != nil {
+				rows.Close()
+				return problems, err
+			}
+			problems = append(problems, fmt.Sprintf(check.format, path, detail))
+		}
+
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return problems, err
+		}
+	}
+
+	// The internal links whose href doesn't resolve to any note.
+	links, err := NewLinkDAO(d.tx, d.logger).FindUnresolved()
+	if err != nil {
+		return problems, err
+	}
+	for _, link := range links {
+		problems = append(problems, fmt.Sprintf("%s: link to %s does not resolve to any note", link.SourcePath, link.Href))
+	}
+
+	return problems, nil
+}
+
+// dateBucketFormat returns the strftime format used to group dates in the
+// given bucket.
+func dateBucketFormat(bucket core.DateBucket) string {
+	switch bucket {
+	case core.DateBucketDay:
+		return "%Y-%m-%d"
+	case core.DateBucketWeek:
+		return "%Y-W%W"
+	case core.DateBucketMonth:
+		return "%Y-%m"
+	case core.DateBucketYear:
+		return "%Y"
+	default:
+		panic(fmt.Sprintf("%v: unknown core.DateBucket", bucket))
+	}
+}
+
+// groupConcatDistinct returns an aggregate SQL expression joining the
+// distinct values of expr, delimited by \x01.
+//
+// SQLite doesn't support a custom separator with GROUP_CONCAT(DISTINCT), so
+// each value is terminated by \x01 before being joined by the default comma
+// separator, which is then removed. This leaves a trailing \x01, ignored by
+// parseListFromNullString.
+func groupConcatDistinct(expr string) string {
+	return fmt.Sprintf("REPLACE(GROUP_CONCAT(DISTINCT %s || '\x01'), '\x01,', '\x01')", expr)
 }
 
 // parseListFromNullString splits a 0-separated string.
@@ -373,6 +1060,57 @@ func parseListFromNullString(str sql.NullString) []string {
 	return list
 }
 
+// findIdsMatchingAny returns the IDs of the notes matching either the filters
+// of opts, or any of the alternative filters in opts.Or. The alternatives may
+// have nested alternatives of their own, evaluated recursively.
+func (d *NoteDAO) findIdsMatchingAny(opts core.NoteFindOpts) ([]core.NoteID, error) {
+	ids := []core.NoteID{}
+	found := map[core.NoteID]bool{}
+
+	for i, group := range append([]core.NoteFindOpts{opts}, opts.Or...) {
+		if i == 0 {
+			group.Or = nil
+		}
+		group.Limit = 0
+		group.Sorters = nil
+		group.PinnedPaths = nil
+		group.AfterID = 0
+		group.Explain = opts.Explain
+
+		var err error
+		// The mentions of opts were already expanded by the caller.
+		if i > 0 {
+			group, err = d.expandMentionsIntoMatch(group)
+			if err != nil {
+				return ids, err
+			}
+		}
+
+		rows, err := d.findRows(group, noteSelectionID)
+		if err != nil {
+			return ids, err
+		}
+		for rows.Next() {
+			id, err := d.scanNoteID(rows)
+			if err != nil {
+				rows.Close()
+				return ids, err
+			}
+			if !found[id] {
+				found[id] = true
+				ids = append(ids, id)
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return ids, err
+		}
+	}
+
+	return ids, nil
+}
+
 // expandMentionsIntoMatch finds the titles associated with the notes in opts.Mention to
 // expand them into the opts.Match predicate.
 func (d *NoteDAO) expandMentionsIntoMatch(opts core.NoteFindOpts) (core.NoteFindOpts, error) {
@@ -382,24 +1120,65 @@ func (d *NoteDAO) expandMentionsIntoMatch(opts core.NoteFindOpts) (core.NoteFind
 	if opts.MatchStrategy != core.MatchStrategyFts {
 		return opts, fmt.Errorf("--mention can only be used with --match-strategy=fts")
 	}
+	if opts.MatchLiteral {
+		// The mentions are searched with a full-text query added to Match.
+		return opts, fmt.Errorf("--mention can't be used with --literal")
+	}
+	if opts.MatchFuzzy {
+		return opts, fmt.Errorf("--mention can't be used with --fuzzy")
+	}
 
-	// Find the IDs for the mentioned paths.
-	ids, err := d.findIdsByHrefs(opts.Mention, true /* allowPartialHrefs */)
-	if err != nil {
-		return opts, err
+	// Find the IDs for the mentioned paths. With MentionAll, each path is
+	// matched by its own group of titles, to require all of them.
+	groups := [][]core.NoteID{}
+	if opts.MentionAll {
+		for _, href := range opts.Mention {
+			ids, err := d.findIdsByHref(href, true /* allowPartialHref */, opts.IgnoreHrefCase)
+			if err != nil {
+				return opts, err
+			}
+			if len(ids) == 0 {
+				return opts, fmt.Errorf("could not find notes at: " + href)
+			}
+			groups = append(groups, ids)
+		}
+	} else {
+		ids, err := d.findIdsByHrefs(opts.Mention, true /* allowPartialHrefs */, opts.IgnoreHrefCase)
+		if err != nil {
+			return opts, err
+		}
+		if len(ids) == 0 {
+			return opts, fmt.Errorf("could not find notes at: " + strings.Join(opts.Mention, ", "))
+		}
+		groups = append(groups, ids)
 	}
-	if len(ids) == 0 {
-		return opts, fmt.Errorf("could not find notes at: " + strings.Join(opts.Mention, ", "))
+
+	for _, ids := range groups {
+		// Exclude the mentioned notes from the results.
+		opts = opts.ExcludingIDs(ids)
+
+		mentionQueries, err := d.findMentionQueries(ids, opts.AliasKeys)
+		if err != nil {
+			return opts, err
+		}
+		if len(mentionQueries) == 0 {
+			continue
+		}
+
+		// Expand the mention queries in the match predicate.
+		opts.Match = append(opts.Match, " ("+strings.Join(mentionQueries, " OR ")+") ")
 	}
 
-	// Exclude the mentioned notes from the results.
-	opts = opts.ExcludingIDs(ids)
+	return opts, nil
+}
 
-	// Find their titles.
+// findMentionQueries returns the FTS5 predicates matching the titles and
+// aliases of the notes with the given IDs.
+func (d *NoteDAO) findMentionQueries(ids []core.NoteID, aliasKeys []string) ([]string, error) {
 	titlesQuery := "SELECT title, metadata FROM notes WHERE id IN (" + joinNoteIDs(ids, ",") + ")"
 	rows, err := d.tx.Query(titlesQuery)
 	if err != nil {
-		return opts, err
+		return nil, err
 	}
 	defer rows.Close()
 
@@ -409,20 +1188,13 @@ func (d *NoteDAO) expandMentionsIntoMatch(opts core.NoteFindOpts) (core.NoteFind
 		var title, metadataJSON string
 		err := rows.Scan(&title, &metadataJSON)
 		if err != nil {
-			return opts, err
+			return nil, err
 		}
 
-		mentionQueries = append(mentionQueries, buildMentionQuery(title, metadataJSON))
-	}
-
-	if len(mentionQueries) == 0 {
-		return opts, nil
+		mentionQueries = append(mentionQueries, buildMentionQuery(title, metadataJSON, joinAliasKeys(aliasKeys)))
 	}
 
-	// Expand the mention queries in the match predicate.
-	opts.Match = append(opts.Match, " ("+strings.Join(mentionQueries, " OR ")+") ")
-
-	return opts, nil
+	return mentionQueries, nil
 }
 
 // noteSelection represents the amount of column selected with findRows.
@@ -430,12 +1202,110 @@ type noteSelection int
 
 const (
 	noteSelectionID noteSelection = iota + 1
+	noteSelectionPath
 	noteSelectionMinimal
 	noteSelectionFull
 )
 
 func (d *NoteDAO) findRows(opts core.NoteFindOpts, selection noteSelection) (*sql.Rows, error) {
+	query, args, err := d.findQuery(opts, selection)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Explain {
+		err = d.explainQuery(query, args)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return d.tx.Query(query, args...)
+}
+
+// explainQuery logs the given SQL query with its bound arguments and the
+// query plan chosen by SQLite.
+func (d *NoteDAO) explainQuery(query string, args []interface{}) error {
+	rows, err := d.tx.Query("EXPLAIN QUERY PLAN "+query, args...)
+	if err != nil {
+		return errors.Wrap(err, "failed to explain the query")
+	}
+	defer rows.Close()
+
+	// The steps of the plan form a tree, each row referencing its parent.
+	depths := map[int]int{}
+	plan := []string{}
+	for rows.Next() {
+		var id, parent, notUsed int
+		var detail string
+		err := rows.Scan(&id, &parent, &notUsed, &detail)
+		if err != nil {
+			return err
+		}
+		depth := 0
+		if parent != 0 {
+			depth = depths[parent] + 1
+		}
+		depths[id] = depth
+		plan = append(plan, strings.Repeat("  ", depth+1)+detail)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	d.logger.Printf("explain:\n%s\n\nargs:\n%s\n\nquery plan:\n%s\n",
+		strings.TrimSpace(query), formatQueryArgs(args), strings.Join(plan, "\n"),
+	)
+	return nil
+}
+
+// formatQueryArgs lists the arguments bound to the placeholders of a query,
+// in order.
+func formatQueryArgs(args []interface{}) string {
+	if len(args) == 0 {
+		return "  (none)"
+	}
+	lines := make([]string, 0, len(args))
+	for i, arg := range args {
+		var value string
+		switch arg := arg.(type) {
+		case string:
+			value = strconv.Quote(arg)
+		case time.Time:
+			value = arg.Format(time.RFC3339)
+		default:
+			value = fmt.Sprint(arg)
+		}
+		lines = append(lines, fmt.Sprintf("  %d: %s", i+1, value))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// findQuery builds the SQL query and its arguments used to find the notes
+// matching the given criteria.
+func (d *NoteDAO) findQuery(opts core.NoteFindOpts, selection noteSelection) (string, []interface{}, error) {
+	if opts.Invert {
+		// Negating the WHERE clause in place is not reliable: some filters
+		// rely on joins, and the negation of a comparison with NULL is still
+		// NULL. The matching notes are found first to exclude them instead.
+		opts.Invert = false
+		ids, err := d.findIdsMatchingAny(opts)
+		if err != nil {
+			return "", nil, err
+		}
+		opts = opts.WithoutFilters().ExcludingIDs(ids)
+	}
+
+	if len(opts.Or) > 0 {
+		// Each group of filters may need its own joins, so the matching notes
+		// are found separately before sorting them together.
+		ids, err := d.findIdsMatchingAny(opts)
+		if err != nil {
+			return "", nil, err
+		}
+		opts = opts.WithoutFilters().IncludingIDs(ids)
+	}
+
 	snippetCol := `n.lead`
+	scoreCol := `0`
 	joinClauses := []string{}
 	whereExprs := []string{}
 	additionalOrderTerms := []string{}
@@ -444,39 +1314,72 @@ func (d *NoteDAO) findRows(opts core.NoteFindOpts, selection noteSelection) (*sq
 
 	transitiveClosure := false
 	maxDistance := 0
+	linkSnippetCols := []string{}
+	// Counts the occurrences of the --match terms, to sort by mentions.
+	matchCountExpr := ""
 
-	setupLinkFilter := func(tableAlias string, hrefs []string, direction int, negate, recursive bool) error {
-		ids, err := d.findIdsByHrefs(hrefs, true /* allowPartialHrefs */)
-		if err != nil {
-			return err
-		}
-		if len(ids) == 0 {
-			return fmt.Errorf("could not find notes at: " + strings.Join(hrefs, ", "))
-		}
+	matchOpen := quoteSQLString(opts.MatchOpen.OrString(core.DefaultMatchOpen).Unwrap())
+	matchClose := quoteSQLString(opts.MatchClose.OrString(core.DefaultMatchClose).Unwrap())
+
+	setupLinkFilterIDs := func(tableAlias string, ids []core.NoteID, direction int, negate, recursive bool, distance int, contexts []string) error {
 		idsList := "(" + joinNoteIDs(ids, ",") + ")"
 
 		linksSrc := "links"
+		// External links are not part of the graph of notes, even when their
+		// href happens to match a note. The transitive closure is already
+		// restricted to internal links, and is shared by all the recursive
+		// link filters, so each one constrains its own maximum distance
+		// instead.
+		linkCond := fmt.Sprintf(" AND %s.external = 0", tableAlias)
+		cond := " AND external = 0"
 
 		if recursive {
+			if !transitiveClosure || (maxDistance != 0 && (distance == 0 || distance > maxDistance)) {
+				maxDistance = distance
+			}
+			linkCond = ""
+			cond = ""
+			if distance != 0 {
+				linkCond = fmt.Sprintf(" AND %s.distance <= %d", tableAlias, distance)
+				cond = fmt.Sprintf(" AND distance <= %d", distance)
+			}
+
 			transitiveClosure = true
 			linksSrc = "transitive_closure"
 			additionalOrderTerms = append(additionalOrderTerms, tableAlias+".distance")
 		}
 
+		// The terms are inlined, as the join conditions come before the
+		// arguments of the WHERE clause.
+		for _, context := range contexts {
+			term := quoteSQLString(escapeLikeTerm(context, '\\'))
+			linkCond += fmt.Sprintf(` AND %s.snippet LIKE '%%' || %s || '%%' ESCAPE '\'`, tableAlias, term)
+			cond += fmt.Sprintf(` AND snippet LIKE '%%' || %s || '%%' ESCAPE '\'`, term)
+		}
+
 		if !negate {
 			if direction != 0 {
-				snippetCol = fmt.Sprintf("GROUP_CONCAT(REPLACE(%s.snippet, %[1]s.title, '<zk:match>' || %[1]s.title || '</zk:match>'), '\x01')", tableAlias)
+				// The same snippet is repeated when a note contains several
+				// identical links, or when another link filter is joined.
+				snippets := groupConcatDistinct(fmt.Sprintf("REPLACE(%[1]s.snippet, %[1]s.title, %[2]s || %[1]s.title || %[3]s)", tableAlias, matchOpen, matchClose))
+				// Truncated by SQLite, to avoid scanning huge strings. This
+				// happens after removing the duplicates, which would otherwise
+				// take the room of the other snippets.
+				if opts.MaxLinkSnippetsLength > 0 {
+					snippets = fmt.Sprintf("substr(%s, 1, %d)", snippets, opts.MaxLinkSnippetsLength)
+				}
+				linkSnippetCols = append(linkSnippetCols, snippets)
 			}
 
 			joinOns := make([]string, 0)
 			if direction <= 0 {
 				joinOns = append(joinOns, fmt.Sprintf(
-					"(n.id = %[1]s.target_id AND %[1]s.source_id IN %[2]s)", tableAlias, idsList,
+					"(n.id = %[1]s.target_id AND %[1]s.source_id IN %[2]s%[3]s)", tableAlias, idsList, linkCond,
 				))
 			}
 			if direction >= 0 {
 				joinOns = append(joinOns, fmt.Sprintf(
-					"(n.id = %[1]s.source_id AND %[1]s.target_id IN %[2]s)", tableAlias, idsList,
+					"(n.id = %[1]s.source_id AND %[1]s.target_id IN %[2]s%[3]s)", tableAlias, idsList, linkCond,
 				))
 			}
 
@@ -498,14 +1401,14 @@ func (d *NoteDAO) findRows(opts core.NoteFindOpts, selection noteSelection) (*sq
 		idSelects := make([]string, 0)
 		if direction <= 0 {
 			idSelects = append(idSelects, fmt.Sprintf(
-				"    SELECT target_id FROM %s WHERE target_id IS NOT NULL AND source_id IN %s",
-				linksSrc, idsList,
+				"    SELECT target_id FROM %s WHERE target_id IS NOT NULL AND source_id IN %s%s",
+				linksSrc, idsList, cond,
 			))
 		}
 		if direction >= 0 {
 			idSelects = append(idSelects, fmt.Sprintf(
-				"    SELECT source_id FROM %s WHERE target_id IS NOT NULL AND target_id IN %s",
-				linksSrc, idsList,
+				"    SELECT source_id FROM %s WHERE target_id IS NOT NULL AND target_id IN %s%s",
+				linksSrc, idsList, cond,
 			))
 		}
 
@@ -516,154 +1419,398 @@ func (d *NoteDAO) findRows(opts core.NoteFindOpts, selection noteSelection) (*sq
 		return nil
 	}
 
+	setupLinkFilter := func(tableAlias string, hrefs []string, direction int, negate, recursive bool, distance int, contexts []string) error {
+		if recursive && len(contexts) > 0 {
+			// The snippets of the transitive closure are the ones of the
+			// last links followed, which would be misleading.
+			return fmt.Errorf("--link-context can't be used with --recursive")
+		}
+		ids, err := d.findIdsByHrefs(hrefs, true /* allowPartialHrefs */, opts.IgnoreHrefCase)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return fmt.Errorf("could not find notes at: " + strings.Join(hrefs, ", "))
+		}
+		return setupLinkFilterIDs(tableAlias, ids, direction, negate, recursive, distance, contexts)
+	}
+
+	if opts.MatchAny {
+		switch {
+		case opts.MatchStrategy != core.MatchStrategyFts:
+			return "", nil, fmt.Errorf("--match-any can only be used with --match-strategy=fts")
+		case opts.MatchLiteral:
+			return "", nil, fmt.Errorf("--match-any can't be used with --literal")
+		case opts.MatchFuzzy:
+			return "", nil, fmt.Errorf("--match-any can't be used with --fuzzy")
+		case opts.MatchRaw:
+			return "", nil, fmt.Errorf("--match-any can't be used with --match-raw")
+		}
+	}
+
 	if 0 < len(opts.Match) {
-		switch opts.MatchStrategy {
+		matchStrategy := opts.MatchStrategy
+		if opts.MatchFuzzy {
+			switch {
+			case matchStrategy != core.MatchStrategyFts:
+				return "", nil, fmt.Errorf("--fuzzy can only be used with --match-strategy=fts")
+			case opts.MatchRaw:
+				// The trigram index doesn't cover the raw content either.
+				return "", nil, fmt.Errorf("--fuzzy can't be used with --match-raw")
+			}
+		}
+		if opts.MatchRaw && matchStrategy == core.MatchStrategyFts {
+			// The FTS index doesn't cover the raw content of the notes.
+			matchStrategy = core.MatchStrategyExact
+		}
+
+		switch matchStrategy {
 		case core.MatchStrategyExact:
+			counts := []string{}
 			for _, match := range opts.Match {
 				whereExprs = append(whereExprs, `n.raw_content LIKE '%' || ? || '%' ESCAPE '\'`)
 				args = append(args, escapeLikeTerm(match, '\\'))
+				// Like LIKE, LOWER() ignores the case of ASCII characters
+				// only. The term is inlined, as the ORDER BY clause comes
+				// after the other arguments.
+				term := quoteSQLString(strings.ToLower(match))
+				counts = append(counts, fmt.Sprintf("(LENGTH(n.raw_content) - LENGTH(REPLACE(LOWER(n.raw_content), %[1]s, ''))) / LENGTH(%[1]s)", term))
 			}
+			matchCountExpr = "(" + strings.Join(counts, " + ") + ")"
 		case core.MatchStrategyFts:
-			snippetCol = `snippet(fts_match.notes_fts, 2, '<zk:match>', '</zk:match>', '…', 20)`
+			if opts.MatchFuzzy {
+				ftsTerms := []string{}
+				for _, match := range opts.Match {
+					query := parseFuzzyQuery(match)
+					if query.fts != "" {
+						ftsTerms = append(ftsTerms, query.fts)
+					}
+					whereExprs = append(whereExprs, query.exprs...)
+					args = append(args, query.args...)
+				}
+				if len(ftsTerms) == 0 {
+					break
+				}
+				// The candidate notes share at least one trigram with the
+				// queries, the closest ones first.
+				snippetCol = fmt.Sprintf(`snippet(fts_fuzzy.notes_trigram_fts, 2, %s, %s, '…', 20)`, matchOpen, matchClose)
+				joinClauses = append(joinClauses, "JOIN notes_trigram_fts fts_fuzzy ON n.id = fts_fuzzy.rowid")
+				additionalOrderTerms = append(additionalOrderTerms, `bm25(fts_fuzzy.notes_trigram_fts, 1000.0, 500.0, 1.0)`)
+				scoreCol = `-bm25(fts_fuzzy.notes_trigram_fts, 1000.0, 500.0, 1.0)`
+				whereExprs = append(whereExprs, "fts_fuzzy.notes_trigram_fts MATCH ?")
+				args = append(args, strings.Join(ftsTerms, " OR "))
+				break
+			}
+			snippetCol = fmt.Sprintf(`snippet(fts_match.notes_fts, 2, %s, %s, '…', 20)`, matchOpen, matchClose)
 			joinClauses = append(joinClauses, "JOIN notes_fts fts_match ON n.id = fts_match.rowid")
 			additionalOrderTerms = append(additionalOrderTerms, `bm25(fts_match.notes_fts, 1000.0, 500.0, 1.0)`)
+			// bm25() returns lower values for better matches, which is
+			// inverted to expose a more natural score.
+			scoreCol = `-bm25(fts_match.notes_fts, 1000.0, 500.0, 1.0)`
+			// highlight() marks each matched token of the title and body,
+			// which are then counted.
+			matchCountExpr = `(
+				LENGTH(highlight(fts_match.notes_fts, 1, char(2), '') || highlight(fts_match.notes_fts, 2, char(2), ''))
+				- LENGTH(REPLACE(highlight(fts_match.notes_fts, 1, char(2), '') || highlight(fts_match.notes_fts, 2, char(2), ''), char(2), ''))
+			)`
 			for _, match := range opts.Match {
 				whereExprs = append(whereExprs, "fts_match.notes_fts MATCH ?")
-				args = append(args, fts5.ConvertQuery(match))
+				args = append(args, convertFtsQuery(match, opts))
 			}
 		case core.MatchStrategyRe:
 			for _, match := range opts.Match {
 				whereExprs = append(whereExprs, "n.raw_content REGEXP ?")
-				args = append(args, match)
+				args = append(args, convertRegexQuery(match, opts))
 			}
 			break
 		}
 	}
 
+	if 0 < len(opts.LeadMatch) {
+		if opts.MatchStrategy != core.MatchStrategyFts {
+			return "", nil, fmt.Errorf("--lead-match can only be used with --match-strategy=fts")
+		}
+
+		joinClauses = append(joinClauses, "JOIN notes_lead_fts fts_lead ON n.id = fts_lead.rowid")
+		additionalOrderTerms = append(additionalOrderTerms, `bm25(fts_lead.notes_lead_fts)`)
+		// The snippets and score of --match take precedence.
+		if len(opts.Match) == 0 {
+			snippetCol = fmt.Sprintf(`snippet(fts_lead.notes_lead_fts, 0, %s, %s, '…', 20)`, matchOpen, matchClose)
+			scoreCol = `-bm25(fts_lead.notes_lead_fts)`
+		}
+		for _, match := range opts.LeadMatch {
+			whereExprs = append(whereExprs, "fts_lead.notes_lead_fts MATCH ?")
+			args = append(args, convertFtsQuery(match, opts))
+		}
+	}
+
 	if opts.IncludeHrefs != nil {
-		ids, err := d.findIdsByHrefs(opts.IncludeHrefs, opts.AllowPartialHrefs)
+		ids, err := d.findIdsByHrefs(opts.IncludeHrefs, opts.AllowPartialHrefs, opts.IgnoreHrefCase)
 		if err != nil {
-			return nil, err
+			return "", nil, err
 		}
 		opts = opts.IncludingIDs(ids)
 	}
 
 	if opts.ExcludeHrefs != nil {
-		ids, err := d.findIdsByHrefs(opts.ExcludeHrefs, opts.AllowPartialHrefs)
+		ids, err := d.findIdsByHrefs(opts.ExcludeHrefs, opts.AllowPartialHrefs, opts.IgnoreHrefCase)
 		if err != nil {
-			return nil, err
+			return "", nil, err
 		}
 		opts = opts.ExcludingIDs(ids)
 	}
 
+	if len(opts.Dirs) > 0 {
+		// Unlike a REGEXP or GLOB, a range of paths can use the index on
+		// notes.path. The root directory of the notebook matches all the
+		// notes.
+		if !strutil.Contains(opts.Dirs, "") {
+			dirExprs := []string{}
+			for _, dir := range opts.Dirs {
+				start, end := dirPathRange(dir)
+				dirExprs = append(dirExprs, "(n.path >= ? AND n.path < ?)")
+				args = append(args, start, end)
+			}
+			whereExprs = append(whereExprs, "("+strings.Join(dirExprs, " OR ")+")")
+		}
+	}
+
+	if opts.PathRegex != "" {
+		whereExprs = append(whereExprs, "n.path REGEXP ?")
+		args = append(args, opts.PathRegex)
+	}
+
+	if opts.BodyRegex != "" {
+		// Unlike --match, this can't use the FTS index and scans every note.
+		whereExprs = append(whereExprs, "n.body REGEXP ?")
+		args = append(args, opts.BodyRegex)
+	}
+
 	if opts.Tags != nil {
-		separatorRegex := regexp.MustCompile(`(\ OR\ )|\|`)
 		for _, tagsArg := range opts.Tags {
-			tags := separatorRegex.Split(tagsArg, -1)
-
-			negate := false
-			globs := make([]string, 0)
-			for _, tag := range tags {
-				tag = strings.TrimSpace(tag)
-
-				if strings.HasPrefix(tag, "-") {
-					negate = true
-					tag = strings.TrimPrefix(tag, "-")
-				} else if strings.HasPrefix(tag, "NOT") {
-					negate = true
-					tag = strings.TrimPrefix(tag, "NOT")
-				}
-
-				tag = strings.TrimSpace(tag)
-				if len(tag) == 0 {
-					continue
-				}
-				globs = append(globs, "t.name GLOB ?")
-				args = append(args, tag)
+			expr, tagArgs, err := parseTagQuery(tagsArg, opts.TagPrefixes)
+			if err != nil {
+				return "", nil, err
 			}
-
-			if len(globs) == 0 {
+			if expr == "" {
 				continue
 			}
-			if negate && len(globs) > 1 {
-				return nil, fmt.Errorf("cannot negate a tag in a OR group: %s", tagsArg)
-			}
+			whereExprs = append(whereExprs, expr)
+			args = append(args, tagArgs...)
+		}
+	}
 
-			expr := "n.id"
-			if negate {
-				expr += " NOT"
-			}
-			expr += fmt.Sprintf(` IN (
+	if len(opts.Aliases) > 0 {
+		whereExprs = append(whereExprs, fmt.Sprintf(`n.id IN (
 SELECT note_id FROM notes_collections
-WHERE collection_id IN (SELECT id FROM collections t WHERE kind = '%s' AND (%s))
+WHERE collection_id IN (SELECT id FROM collections WHERE kind = '%s' AND name IN (%s))
 )`,
-				core.CollectionKindTag,
-				strings.Join(globs, " OR "),
-			)
-			whereExprs = append(whereExprs, expr)
+			core.CollectionKindAlias, "?"+strings.Repeat(", ?", len(opts.Aliases)-1),
+		))
+		for _, alias := range opts.Aliases {
+			args = append(args, alias)
 		}
 	}
 
 	if opts.MentionedBy != nil {
-		ids, err := d.findIdsByHrefs(opts.MentionedBy, true /* allowPartialHrefs */)
+		ids, err := d.findIdsByHrefs(opts.MentionedBy, true /* allowPartialHrefs */, opts.IgnoreHrefCase)
 		if err != nil {
-			return nil, err
+			return "", nil, err
 		}
 		if len(ids) == 0 {
-			return nil, fmt.Errorf("could not find notes at: " + strings.Join(opts.MentionedBy, ", "))
+			return "", nil, fmt.Errorf("could not find notes at: " + strings.Join(opts.MentionedBy, ", "))
 		}
 
 		// Exclude the mentioning notes from the results.
 		opts = opts.ExcludingIDs(ids)
 
-		snippetCol = `snippet(nsrc.notes_fts, 2, '<zk:match>', '</zk:match>', '…', 20)`
-		joinClauses = append(joinClauses, "JOIN notes_fts nsrc ON nsrc.rowid IN ("+joinNoteIDs(ids, ",")+") AND nsrc.notes_fts MATCH mention_query(n.title, n.metadata)")
+		// Joining the mentioning notes would return a note once for each of
+		// them, so they are looked up with subqueries instead. The LIMIT
+		// prevents SQLite from flattening the snippets subquery, because FTS
+		// auxiliary functions are not available in an aggregate query.
+		mentionsQuery := "FROM notes_fts nsrc WHERE nsrc.notes_fts MATCH mention_query(n.title, n.metadata, " + quoteSQLString(joinAliasKeys(opts.AliasKeys)) + ") AND nsrc.rowid IN (" + joinNoteIDs(ids, ",") + ")"
+		snippetCol = fmt.Sprintf("(SELECT GROUP_CONCAT(snippet, '\x01') FROM (SELECT snippet(nsrc.notes_fts, 2, %s, %s, '…', 20) AS snippet %s LIMIT -1))", matchOpen, matchClose, mentionsQuery)
+		whereExprs = append(whereExprs, "EXISTS (SELECT 1 "+mentionsQuery+")")
 	}
 
 	if opts.LinkedBy != nil {
 		filter := opts.LinkedBy
-		maxDistance = filter.MaxDistance
-		err := setupLinkFilter("l_by", filter.Hrefs, -1, filter.Negate, filter.Recursive)
+		err := setupLinkFilter("l_by", filter.Hrefs, -1, filter.Negate, filter.Recursive, filter.MaxDistance, filter.Contexts)
 		if err != nil {
-			return nil, err
+			return "", nil, err
 		}
 	}
 
 	if opts.LinkTo != nil {
 		filter := opts.LinkTo
-		maxDistance = filter.MaxDistance
-		err := setupLinkFilter("l_to", filter.Hrefs, 1, filter.Negate, filter.Recursive)
+		err := setupLinkFilter("l_to", filter.Hrefs, 1, filter.Negate, filter.Recursive, filter.MaxDistance, filter.Contexts)
 		if err != nil {
-			return nil, err
+			return "", nil, err
+		}
+	}
+
+	if opts.LinkToTitles != nil {
+		ids, err := d.findIdsByTitles(opts.LinkToTitles)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(ids) == 0 {
+			return "", nil, fmt.Errorf("could not find notes titled: " + strings.Join(opts.LinkToTitles, ", "))
 		}
+		err = setupLinkFilterIDs("l_to_title", ids, 1, false, false, 0, nil)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	// Unlike LinkTo, each href requires its own link to one of the notes it
+	// matches.
+	for _, href := range opts.LinkToAll {
+		ids, err := d.findIdsByHrefs([]string{href}, true /* allowPartialHrefs */, opts.IgnoreHrefCase)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(ids) == 0 {
+			return "", nil, fmt.Errorf("could not find notes at: " + href)
+		}
+
+		whereExprs = append(whereExprs, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM links WHERE source_id = n.id AND target_id IN (%s) AND external = 0)",
+			joinNoteIDs(ids, ","),
+		))
 	}
 
 	if opts.Related != nil {
-		maxDistance = 2
-		err := setupLinkFilter("l_rel", opts.Related, 0, false, true)
+		ids, err := d.findIdsByHrefs(opts.Related, true /* allowPartialHrefs */, opts.IgnoreHrefCase)
 		if err != nil {
-			return nil, err
+			return "", nil, err
+		}
+		if len(ids) == 0 {
+			return "", nil, fmt.Errorf("could not find notes at: " + strings.Join(opts.Related, ", "))
+		}
+		// A note is never related to itself, even with cyclic links.
+		opts = opts.ExcludingIDs(ids)
+
+		err = setupLinkFilterIDs("l_rel", ids, 0, false, true, 2, nil)
+		if err != nil {
+			return "", nil, err
 		}
 		groupBy += " HAVING MIN(l_rel.distance) = 2"
 	}
 
+	// When combining --linked-by and --link-to, the snippets of both kinds
+	// of links are kept.
+	switch len(linkSnippetCols) {
+	case 0:
+	case 1:
+		snippetCol = linkSnippetCols[0]
+	default:
+		for i, col := range linkSnippetCols {
+			linkSnippetCols[i] = "COALESCE(" + col + ", '')"
+		}
+		snippetCol = strings.Join(linkSnippetCols, " || '\x01' || ")
+	}
+
+	if len(opts.ExternalLinkTo) > 0 {
+		// The domains are inlined, as they are also used in the snippet
+		// column which comes before the other arguments in the query.
+		hrefConds := []string{}
+		for _, domain := range opts.ExternalLinkTo {
+			hrefConds = append(hrefConds, `href LIKE '%' || `+quoteSQLString(escapeLikeTerm(domain, '\\'))+` || '%' ESCAPE '\'`)
+		}
+		externalLinksQuery := "FROM links WHERE source_id = n.id AND external = 1 AND (" + strings.Join(hrefConds, " OR ") + ")"
+
+		whereExprs = append(whereExprs, "EXISTS (SELECT 1 "+externalLinksQuery+")")
+		// Show the matched URLs, unless another filter already provides
+		// the snippets.
+		if snippetCol == `n.lead` {
+			snippetCol = "(SELECT GROUP_CONCAT(href, '\x01') " + externalLinksQuery + ")"
+		}
+	}
+
 	if opts.Orphan {
 		whereExprs = append(whereExprs, `n.id NOT IN (
-			SELECT target_id FROM links WHERE target_id IS NOT NULL
+			SELECT target_id FROM links WHERE target_id IS NOT NULL AND external = 0
 		)`)
 	}
 
+	if opts.MinBacklinks > 0 || !opts.MaxBacklinks.IsNull() {
+		// Counts the distinct notes linking to this one, ignoring the
+		// links to itself.
+		backlinksCount := `(
+			SELECT COUNT(DISTINCT source_id) FROM links
+			 WHERE target_id = n.id AND source_id != n.id AND external = 0
+		)`
+		if opts.MinBacklinks > 0 {
+			whereExprs = append(whereExprs, backlinksCount+" >= ?")
+			args = append(args, opts.MinBacklinks)
+		}
+		if !opts.MaxBacklinks.IsNull() {
+			whereExprs = append(whereExprs, backlinksCount+" <= ?")
+			args = append(args, opts.MaxBacklinks.Unwrap())
+		}
+	}
+
 	if opts.Tagless {
 		whereExprs = append(whereExprs, `tags IS NULL`)
 	}
 
+	if !opts.HasFrontmatter.IsNull() {
+		// Add stores an empty JSON object for the notes without any
+		// frontmatter keys.
+		if opts.HasFrontmatter.Unwrap() {
+			whereExprs = append(whereExprs, `n.metadata != '{}'`)
+		} else {
+			whereExprs = append(whereExprs, `n.metadata = '{}'`)
+		}
+	}
+
+	for _, filter := range opts.ExcludeMetadata {
+		// json_each() yields a single row for a scalar value, and none when
+		// the key is missing. Booleans are compared with their YAML spelling.
+		whereExprs = append(whereExprs, `NOT EXISTS (
+			SELECT 1 FROM json_each(n.metadata, ?)
+			 WHERE CASE type WHEN 'true' THEN 'true' WHEN 'false' THEN 'false' ELSE CAST(atom AS TEXT) END = ?
+		)`)
+		args = append(args, metadataJSONPath(filter.Key), filter.Value)
+	}
+
+	if opts.UntypedLinks {
+		whereExprs = append(whereExprs, `EXISTS (
+			SELECT 1 FROM links
+			 WHERE source_id = n.id AND rels = '' AND external = 0
+		)`)
+	}
+
+	for _, feature := range opts.Has {
+		regex, ok := markdownFeatureRegexes[feature]
+		if !ok {
+			return "", nil, fmt.Errorf("%s: unknown Markdown feature", feature)
+		}
+		whereExprs = append(whereExprs, "n.raw_content REGEXP ?")
+		args = append(args, regex)
+	}
+
+	if opts.WordCountTopPercent > 0 {
+		// The threshold is the word count of the note ranked at the given
+		// percentile among all the notes of the notebook.
+		whereExprs = append(whereExprs, `n.word_count >= (
+			SELECT word_count FROM notes
+			 ORDER BY word_count DESC
+			 LIMIT 1 OFFSET (SELECT (COUNT(*) * ? + 99) / 100 - 1 FROM notes)
+		)`)
+		args = append(args, opts.WordCountTopPercent)
+	}
+
+	// Undated notes never match a date range, as their actual date is
+	// unknown. The lower bounds already exclude them.
 	if opts.CreatedStart != nil {
 		whereExprs = append(whereExprs, "created >= ?")
 		args = append(args, opts.CreatedStart)
 	}
 
 	if opts.CreatedEnd != nil {
-		whereExprs = append(whereExprs, "created < ?")
+		whereExprs = append(whereExprs, "created < ? AND NOT "+undatedExpr("created"))
 		args = append(args, opts.CreatedEnd)
 	}
 
@@ -673,10 +1820,58 @@ WHERE collection_id IN (SELECT id FROM collections t WHERE kind = '%s' AND (%s))
 	}
 
 	if opts.ModifiedEnd != nil {
-		whereExprs = append(whereExprs, "modified < ?")
+		whereExprs = append(whereExprs, "modified < ? AND NOT "+undatedExpr("modified"))
 		args = append(args, opts.ModifiedEnd)
 	}
 
+	yearsExpr := func(col string, years []int) {
+		placeholders := make([]string, 0, len(years))
+		for _, year := range years {
+			placeholders = append(placeholders, "?")
+			args = append(args, fmt.Sprintf("%04d", year))
+		}
+		whereExprs = append(whereExprs, fmt.Sprintf("strftime('%%Y', %s) IN (%s)", col, strings.Join(placeholders, ", ")))
+	}
+	if len(opts.CreatedYears) > 0 {
+		yearsExpr("created", opts.CreatedYears)
+	}
+	if len(opts.ModifiedYears) > 0 {
+		yearsExpr("modified", opts.ModifiedYears)
+	}
+
+	// The dates are stored in UTC, but the days are compared in the local
+	// time zone of the user. The undated notes would otherwise be found on
+	// every January 1st.
+	onThisDayExpr := func(col string, date *time.Time) {
+		localDate := date.Local()
+		whereExprs = append(whereExprs, fmt.Sprintf("strftime('%%m-%%d', %[1]s, 'localtime') = ? AND strftime('%%Y', %[1]s, 'localtime') < ? AND NOT %[2]s", col, undatedExpr(col)))
+		args = append(args, localDate.Format("01-02"), fmt.Sprintf("%04d", localDate.Year()))
+	}
+	if opts.CreatedOnThisDay != nil {
+		onThisDayExpr("created", opts.CreatedOnThisDay)
+	}
+	if opts.ModifiedOnThisDay != nil {
+		onThisDayExpr("modified", opts.ModifiedOnThisDay)
+	}
+
+	if opts.Undated {
+		whereExprs = append(whereExprs, undatedExpr("created"))
+	}
+
+	if opts.AnomalousDates {
+		whereExprs = append(whereExprs, "julianday(modified) < julianday(created)")
+	}
+
+	if opts.StaleDays > 0 {
+		whereExprs = append(whereExprs, "julianday(modified) - julianday(created) < ? AND NOT "+undatedExpr("created"))
+		args = append(args, opts.StaleDays)
+	}
+
+	if opts.ActivelyEditedDays > 0 {
+		whereExprs = append(whereExprs, "julianday(modified) - julianday(created) >= ? AND NOT "+undatedExpr("created"))
+		args = append(args, opts.ActivelyEditedDays)
+	}
+
 	if opts.IncludeIDs != nil {
 		whereExprs = append(whereExprs, "n.id IN ("+joinNoteIDs(opts.IncludeIDs, ",")+")")
 	}
@@ -685,12 +1880,28 @@ WHERE collection_id IN (SELECT id FROM collections t WHERE kind = '%s' AND (%s))
 		whereExprs = append(whereExprs, "n.id NOT IN ("+joinNoteIDs(opts.ExcludeIDs, ",")+")")
 	}
 
-	orderTerms := []string{}
+	if opts.SnippetSource == core.SnippetSourceLead {
+		snippetCol = `n.lead`
+	}
+
 	for _, sorter := range opts.Sorters {
-		orderTerms = append(orderTerms, orderTerm(sorter))
+		if sorter.Field == core.NoteSortMentions && matchCountExpr == "" {
+			return "", nil, fmt.Errorf("--sort mentions requires a --match query with the fts or exact strategy, without --or or --invert")
+		}
+	}
+
+	if opts.AfterID != 0 {
+		expr, err := d.afterIDExpr(opts, additionalOrderTerms)
+		if err != nil {
+			return "", nil, err
+		}
+		whereExprs = append(whereExprs, expr)
+	}
+
+	orderTerms := findOrderTerms(opts.Sorters, opts.RandomSeed, matchCountExpr, additionalOrderTerms)
+	if len(opts.PinnedPaths) > 0 {
+		orderTerms = append([]string{pinnedOrderTerm(opts.PinnedPaths)}, orderTerms...)
 	}
-	orderTerms = append(orderTerms, additionalOrderTerms...)
-	orderTerms = append(orderTerms, `n.title ASC`)
 
 	query := ""
 
@@ -701,6 +1912,7 @@ WHERE collection_id IN (SELECT id FROM collections t WHERE kind = '%s' AND (%s))
            1 AS distance,
            '.' || source_id || '.' || target_id || '.' AS path
       FROM links
+     WHERE external = 0
  
      UNION ALL
  
@@ -710,7 +1922,8 @@ WHERE collection_id IN (SELECT id FROM collections t WHERE kind = '%s' AND (%s))
       FROM links AS l
       JOIN transitive_closure AS tc
         ON l.source_id = tc.target_id
-     WHERE tc.path NOT LIKE '%.' || l.target_id || '.%'`
+     WHERE l.external = 0
+       AND tc.path NOT LIKE '%.' || l.target_id || '.%'`
 
 		if maxDistance != 0 {
 			query += fmt.Sprintf(" AND tc.distance < %d", maxDistance)
@@ -722,11 +1935,22 @@ WHERE collection_id IN (SELECT id FROM collections t WHERE kind = '%s' AND (%s))
 		query += "\n)\n"
 	}
 
-	query += "SELECT n.id"
-	if selection != noteSelectionID {
+	if selection == noteSelectionPath {
+		// Skip the other columns which are expensive to compute, such as the
+		// snippets.
+		query += "SELECT n.path"
+	} else {
+		query += "SELECT n.id"
+	}
+	if selection != noteSelectionID && selection != noteSelectionPath {
 		query += ", n.path, n.title, n.metadata"
 		if selection != noteSelectionMinimal {
-			query += fmt.Sprintf(", n.lead, n.body, n.raw_content, n.word_count, n.created, n.modified, n.checksum, n.tags, %s AS snippet", snippetCol)
+			bodyCols := "n.body, n.raw_content"
+			if opts.ExcludeBody {
+				// Keep the same columns to share the scanner.
+				bodyCols = "'' AS body, '' AS raw_content"
+			}
+			query += fmt.Sprintf(", n.lead, %s, n.word_count, n.created, n.modified, n.checksum, n.tags, %s AS snippet, %s AS score", bodyCols, snippetCol, scoreCol)
 		}
 	}
 
@@ -750,10 +1974,99 @@ WHERE collection_id IN (SELECT id FROM collections t WHERE kind = '%s' AND (%s))
 		query += fmt.Sprintf("LIMIT %d\n", opts.Limit)
 	}
 
-	// d.logger.Println(query)
-	// d.logger.Println(args)
+	return query, args, nil
+}
 
-	return d.tx.Query(query, args...)
+// afterIDExpr returns the SQL expression selecting the notes sorted after
+// the one with opts.AfterID, for keyset pagination.
+//
+// The notes are compared with the sorted date of the cursor note, then with
+// the tie-breakers added by findOrderTerms: the creation date and the path.
+// Other order terms, such as the relevance of a full-text search, can't be
+// compared this way.
+func (d *NoteDAO) afterIDExpr(opts core.NoteFindOpts, additionalOrderTerms []string) (string, error) {
+	if len(opts.Sorters) != 1 || (opts.Sorters[0].Field != core.NoteSortCreated && opts.Sorters[0].Field != core.NoteSortModified) {
+		return "", fmt.Errorf("--after-id requires a single --sort created or --sort modified criterion")
+	}
+	if len(opts.PinnedPaths) > 0 {
+		return "", fmt.Errorf("--after-id can't be used with --order-file")
+	}
+	if len(additionalOrderTerms) > 0 {
+		return "", fmt.Errorf("--after-id can't be used with a full-text search or --recursive, which also order the notes by relevance or distance")
+	}
+
+	rows, err := d.tx.Query("SELECT 1 FROM notes WHERE id = ?", opts.AfterID)
+	if err != nil {
+		return "", err
+	}
+	found := rows.Next()
+	rows.Close()
+	if !found {
+		return "", fmt.Errorf("--after-id: note %d not found", opts.AfterID)
+	}
+
+	cursor := func(col string) string {
+		return fmt.Sprintf("(SELECT %s FROM notes WHERE id = %d)", col, opts.AfterID)
+	}
+
+	after := fmt.Sprintf("n.path > %s", cursor("path"))
+	after = fmt.Sprintf("n.created > %[1]s OR (n.created = %[1]s AND %[2]s)", cursor("created"), after)
+
+	sorter := opts.Sorters[0]
+	col := "created"
+	if sorter.Field == core.NoteSortModified {
+		col = "modified"
+	}
+	op := ">"
+	if !sorter.Ascending {
+		op = "<"
+	}
+	return fmt.Sprintf("(n.%[1]s %[2]s %[3]s OR (n.%[1]s = %[3]s AND (%[4]s)))", col, op, cursor(col), after), nil
+}
+
+// metadataJSONPath returns the JSON path to the given top-level metadata key,
+// for the SQLite JSON functions.
+func metadataJSONPath(key string) string {
+	return `$."` + strings.ReplaceAll(key, `"`, "") + `"`
+}
+
+// dirPathRange returns the bounds of the range of paths located in the given
+// directory, from dir + "/" included to dir + "0" excluded, as "0" is the
+// character following "/".
+func dirPathRange(dir string) (string, string) {
+	dir = strings.TrimSuffix(dir, "/")
+	return dir + "/", dir + "0"
+}
+
+// tableCell matches a cell of the delimiter row of a Markdown table, e.g.
+// ` :---: `.
+const tableCell = `[ \t]*:?-+:?[ \t]*`
+
+// taskItem matches the start of a task list item, up to its checkbox, e.g.
+// `- [`.
+const taskItem = `(?m)^[ \t]*([-+*]|[0-9]+[.)])[ \t]+\[`
+
+// markdownFeatureRegexes maps each Markdown feature to a regular expression
+// matching its syntax in the raw content of a note.
+var markdownFeatureRegexes = map[core.MarkdownFeature]string{
+	core.MarkdownFeatureCode: "(?m)^ {0,3}(```|~~~)",
+	// The delimiter row requires at least one pipe, to tell it apart from
+	// thematic breaks and setext headings.
+	core.MarkdownFeatureTable:    `(?m)^ {0,3}(\|` + tableCell + `(\|` + tableCell + `)*\|?|` + tableCell + `(\|` + tableCell + `)+\|?)[ \t]*$`,
+	core.MarkdownFeatureImage:    `!\[[^\]]*\][(\[]`,
+	core.MarkdownFeatureLink:     `(^|[^!])\[[^\]]*\]\(|\[\[[^\]]+\]\]|<[a-zA-Z][a-zA-Z0-9+.-]*:[^\s>]+>`,
+	core.MarkdownFeatureTask:     taskItem + `[ xX]\]([ \t]|$)`,
+	core.MarkdownFeatureOpenTask: taskItem + ` \]([ \t]|$)`,
+}
+
+// undatedExpr returns a SQL expression matching the notes whose given date
+// column is missing.
+//
+// The date columns can't be NULL in the current schema, but a note indexed
+// without any date holds the zero time.Time, stored as
+// "0001-01-01 00:00:00+00:00". Both sort before any actual date.
+func undatedExpr(col string) string {
+	return fmt.Sprintf("(%[1]s IS NULL OR %[1]s < '0001-01-02')", col)
 }
 
 func (d *NoteDAO) scanNoteID(row RowScanner) (core.NoteID, error) {
@@ -799,15 +2112,16 @@ func (d *NoteDAO) scanMinimalNote(row RowScanner) (*core.MinimalNote, error) {
 func (d *NoteDAO) scanNote(row RowScanner) (*core.ContextualNote, error) {
 	var (
 		id, wordCount                 int
+		score                         float64
 		title, lead, body, rawContent string
 		snippets, tags                sql.NullString
 		path, metadataJSON, checksum  string
-		created, modified             time.Time
+		created, modified             sql.NullTime
 	)
 
 	err := row.Scan(
 		&id, &path, &title, &metadataJSON, &lead, &body, &rawContent,
-		&wordCount, &created, &modified, &checksum, &tags, &snippets,
+		&wordCount, &created, &modified, &checksum, &tags, &snippets, &score,
 	)
 	switch {
 	case err == sql.ErrNoRows:
@@ -822,6 +2136,7 @@ func (d *NoteDAO) scanNote(row RowScanner) (*core.ContextualNote, error) {
 
 		return &core.ContextualNote{
 			Snippets: parseListFromNullString(snippets),
+			Score:    score,
 			Note: core.Note{
 				ID:         core.NoteID(id),
 				Path:       path,
@@ -833,43 +2148,211 @@ func (d *NoteDAO) scanNote(row RowScanner) (*core.ContextualNote, error) {
 				Links:      []core.Link{},
 				Tags:       parseListFromNullString(tags),
 				Metadata:   metadata,
-				Created:    created,
-				Modified:   modified,
+				Created:    created.Time,
+				Modified:   modified.Time,
 				Checksum:   checksum,
 			},
 		}, nil
 	}
 }
 
-func orderTerm(sorter core.NoteSorter) string {
-	order := " ASC"
-	if !sorter.Ascending {
-		order = " DESC"
+// findOrderTerms returns the ORDER BY terms for the given sorters, followed by
+// the additional terms (e.g. the relevance of a match) and tiebreakers.
+//
+// Without any sorter, the notes are ordered by title. Otherwise, ties are
+// broken by creation date, which is more meaningful than the title after a
+// bulk modification of the notes. The path always comes last to guarantee a
+// stable order.
+//
+// A random sorter takes precedence over any following term, to make sure the
+// results are truly shuffled when a limit is set. When a non-zero randomSeed
+// is given, the shuffle is reproducible and only the path is used to break
+// ties.
+func findOrderTerms(sorters []core.NoteSorter, randomSeed int64, matchCountExpr string, additionalOrderTerms []string) []string {
+	orderTerms := []string{}
+	for _, sorter := range sorters {
+		orderTerms = append(orderTerms, orderTerm(sorter, randomSeed, matchCountExpr))
+		if sorter.Field == core.NoteSortRandom {
+			if randomSeed != 0 {
+				orderTerms = append(orderTerms, `n.path ASC`)
+			}
+			return orderTerms
+		}
 	}
+	orderTerms = append(orderTerms, additionalOrderTerms...)
+	if len(sorters) == 0 {
+		orderTerms = append(orderTerms, `n.title ASC`)
+	} else {
+		orderTerms = append(orderTerms, `n.created ASC`)
+	}
+	orderTerms = append(orderTerms, `n.path ASC`)
+	return orderTerms
+}
 
+// orderTerm returns the ORDER BY term of the given sorter.
+//
+// The notes without any value for the sorted field are always listed last,
+// unless NullsFirst is set, whatever the direction of the sort.
+//
+// matchCountExpr counts the occurrences of the --match terms in a note, used
+// by NoteSortMentions.
+func orderTerm(sorter core.NoteSorter, randomSeed int64, matchCountExpr string) string {
+	var expr string
 	switch sorter.Field {
 	case core.NoteSortCreated:
-		return "n.created" + order
+		expr = "n.created"
 	case core.NoteSortModified:
-		return "n.modified" + order
+		expr = "n.modified"
 	case core.NoteSortPath:
-		return "n.path" + order
+		expr = "n.path"
 	case core.NoteSortRandom:
+		if randomSeed != 0 {
+			return seededRandomTerm(randomSeed)
+		}
 		return "RANDOM()"
 	case core.NoteSortTitle:
-		return "n.title" + order
+		expr = "n.title"
 	case core.NoteSortWordCount:
-		return "n.word_count" + order
+		expr = "n.word_count"
+	case core.NoteSortTitleLength:
+		expr = "LENGTH(n.title)"
+	case core.NoteSortFilename:
+		// SQLite can't search a string backward, so the parent directories
+		// are found by trimming all the characters of the path except /.
+		expr = "substr(n.path, LENGTH(rtrim(n.path, replace(n.path, '/', ''))) + 1)"
+	case core.NoteSortLinked:
+		// A correlated subquery avoids interfering with the GROUP BY clause
+		// used by the link filters.
+		expr = `(
+			SELECT MAX(s.modified) FROM links l
+			  JOIN notes s ON s.id = l.source_id
+			 WHERE l.target_id = n.id AND l.source_id != n.id AND l.external = 0
+		)`
+	case core.NoteSortMetadata:
+		expr = metadataOrderExpr(sorter.MetadataKey)
+	case core.NoteSortMentions:
+		expr = matchCountExpr
 	default:
 		panic(fmt.Sprintf("%v: unknown core.NoteSortField", sorter.Field))
 	}
+
+	order := " ASC"
+	if !sorter.Ascending {
+		order = " DESC"
+	}
+	nulls := " NULLS LAST"
+	if sorter.NullsFirst {
+		nulls = " NULLS FIRST"
+	}
+	return expr + order + nulls
+}
+
+// metadataOrderExpr returns an expression sorting the notes by the value of
+// the given metadata key.
+//
+// Strings looking like numbers, e.g. a quoted priority: "2", are cast to
+// compare numerically with the actual numbers. Other strings are listed after
+// the numbers, while a missing key yields NULL.
+func metadataOrderExpr(key string) string {
+	path := quoteSQLString(metadataJSONPath(key))
+	value := fmt.Sprintf("json_extract(n.metadata, %s)", path)
+	return fmt.Sprintf(`CASE
+			WHEN json_type(n.metadata, %s) = 'text' AND %s GLOB '*[0-9]*' AND %s NOT GLOB '*[^0-9.eE+-]*'
+			THEN CAST(%s AS REAL)
+			ELSE %s
+		END`, path, value, value, value, value)
+}
+
+// pinnedOrderTerm returns a term ordering first the notes at the given paths,
+// following their position in the list. The other notes are ranked last, and
+// keep the order of the next terms.
+func pinnedOrderTerm(paths []string) string {
+	term := "CASE n.path"
+	for i, path := range paths {
+		term += fmt.Sprintf(" WHEN %s THEN %d", quoteSQLString(path), i)
+	}
+	return term + fmt.Sprintf(" ELSE %d END", len(paths))
+}
+
+// seededRandomTerm returns a term shuffling the notes in a reproducible order
+// for the given seed, by hashing their IDs.
+//
+// The hash is computed modulo the Mersenne prime 2^31-1 to stay within the
+// range of SQLite integers: a Lehmer step is followed by a squaring to mix in
+// the seed non-linearly, otherwise every seed would yield the same cycle.
+func seededRandomTerm(seed int64) string {
+	const prime = 2147483647
+	seed %= prime
+	if seed < 0 {
+		seed += prime
+	}
+	return fmt.Sprintf("((((n.id + %d) * 48271) %% %d) * (((n.id + %d) * 48271) %% %d) + %d) %% %d", seed, prime, seed, prime, seed, prime)
+}
+
+// tagGlobToRegex converts a tag glob pattern into an equivalent regular
+// expression, taking into account tag hierarchies separated by /.
+//
+// A single * (or ?) never spans a /, while ** matches any descendant tags,
+// e.g. project/** matches project/zk and project/zk/indexing.
+func tagGlobToRegex(glob string) string {
+	var regex strings.Builder
+	regex.WriteString("^")
+
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				regex.WriteString(".*")
+				i++
+			} else {
+				regex.WriteString("[^/]*")
+			}
+		case '?':
+			regex.WriteString("[^/]")
+		case '[':
+			// Like GLOB, a ] right after the opening [ (or [^) is part of the
+			// character class instead of closing it.
+			start := i + 1
+			if start < len(glob) && glob[start] == '^' {
+				start++
+			}
+			if start < len(glob) && glob[start] == ']' {
+				start++
+			}
+			end := strings.IndexByte(glob[start:], ']')
+			if end < 0 {
+				regex.WriteString(regexp.QuoteMeta(string(c)))
+				continue
+			}
+			end += start
+			class := glob[i+1 : end]
+			regex.WriteString("[" + strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`).Replace(class) + "]")
+			i = end
+		default:
+			regex.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	regex.WriteString("$")
+	return regex.String()
+}
+
+// joinAliasKeys concatenates the given alias metadata keys for
+// buildMentionQuery, falling back on core.DefaultAliasKeys when nil.
+func joinAliasKeys(keys []string) string {
+	if keys == nil {
+		keys = core.DefaultAliasKeys
+	}
+	return strings.Join(keys, "\x01")
 }
 
 // buildMentionQuery creates an FTS5 predicate to match the given note's title
 // (or aliases from the metadata) in the content of another note.
 //
+// The metadata keys holding the aliases are delimited by \x01 in aliasKeys.
+//
 // It is exposed as a custom SQLite function as `mention_query()`.
-func buildMentionQuery(title, metadataJSON string) string {
+func buildMentionQuery(title, metadataJSON, aliasKeys string) string {
 	titles := []string{}
 
 	appendTitle := func(t string) {
@@ -882,12 +2365,10 @@ func buildMentionQuery(title, metadataJSON string) string {
 
 	appendTitle(title)
 
-	// Support `aliases` key in the YAML frontmatter, like Obsidian:
-	// https://publish.obsidian.md/help/How+to/Add+aliases+to+note
 	metadata, err := unmarshalMetadata(metadataJSON)
-	if err == nil {
-		if aliases, ok := metadata["aliases"]; ok {
-			switch aliases := aliases.(type) {
+	if err == nil && aliasKeys != "" {
+		for _, key := range strings.Split(aliasKeys, "\x01") {
+			switch aliases := metadata[key].(type) {
 			case []interface{}:
 				for _, alias := range aliases {
 					appendTitle(fmt.Sprint(alias))
@@ -3,6 +3,7 @@ package sqlite
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -102,6 +103,33 @@ func TestNoteDAOIndexed(t *testing.T) {
 	})
 }
 
+func TestNoteDAOIndexedSince(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		// The lower bound is inclusive.
+		c, err := dao.IndexedSince(time.Date(2020, 11, 10, 8, 20, 18, 0, time.UTC))
+		assert.Nil(t, err)
+
+		actual := make([]paths.Metadata, 0)
+		for a := range c {
+			actual = append(actual, a)
+		}
+		assert.Equal(t, actual, []paths.Metadata{
+			{
+				Path:     "log/2021-01-03.md",
+				Modified: time.Date(2020, 11, 22, 16, 27, 45, 0, time.UTC),
+			},
+			{
+				Path:     "log/2021-01-04.md",
+				Modified: time.Date(2020, 11, 29, 8, 20, 18, 0, time.UTC),
+			},
+			{
+				Path:     "log/2021-02-04.md",
+				Modified: time.Date(2020, 11, 10, 8, 20, 18, 0, time.UTC),
+			},
+		})
+	})
+}
+
 func TestNoteDAOAdd(t *testing.T) {
 	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
 		_, err := dao.Add(core.Note{
@@ -136,10 +164,28 @@ func TestNoteDAOAdd(t *testing.T) {
 }
 
 // Check that we can't add a duplicate note with an existing path.
+// The notes without any frontmatter are stored with an empty JSON object, to
+// be found with a reliable comparison.
+func TestNoteDAOAddWithoutMetadata(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := dao.Add(core.Note{Path: "log/added.md"})
+		assert.Nil(t, err)
+
+		row, err := queryNoteRow(tx, `path = "log/added.md"`)
+		assert.Nil(t, err)
+		assert.Equal(t, row.Metadata, "{}")
+	})
+}
+
 func TestNoteDAOAddExistingNote(t *testing.T) {
 	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
 		_, err := dao.Add(core.Note{Path: "ref/test/a.md"})
-		assert.Err(t, err, "UNIQUE constraint failed: notes.path")
+		assert.Equal(t, err, core.ErrNoteIndexed{Path: "ref/test/a.md"})
+
+		// The existing note is left untouched.
+		row, err := queryNoteRow(tx, `path = "ref/test/a.md"`)
+		assert.Nil(t, err)
+		assert.Equal(t, row.Title, "Another nested note")
 	})
 }
 
@@ -206,6 +252,161 @@ func TestNoteDAORemoveUnknown(t *testing.T) {
 	})
 }
 
+func TestNoteDAORename(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		err := dao.Rename("ref/test/a.md", "archive/old/a.md")
+		assert.Nil(t, err)
+
+		_, err = queryNoteRow(tx, `path = "ref/test/a.md"`)
+		assert.Equal(t, err, sql.ErrNoRows)
+
+		var sortablePath string
+		err = tx.QueryRow(`SELECT sortable_path FROM notes WHERE id = 6 AND path = "archive/old/a.md"`).Scan(&sortablePath)
+		assert.Nil(t, err)
+		assert.Equal(t, sortablePath, "archive\x01old\x01a.md")
+
+		// Inbound links are still resolved.
+		links := queryLinkRows(t, tx, `id = 5`)
+		assert.Equal(t, *links[0].TargetId, core.NoteID(6))
+	})
+}
+
+func TestNoteDAORenameUnknown(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		err := dao.Rename("unknown/unknown.md", "new.md")
+		assert.Err(t, err, "unknown/unknown.md: note not found in the index")
+	})
+}
+
+func TestNoteDAORenameToExistingPath(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		err := dao.Rename("ref/test/a.md", "index.md")
+		assert.Err(t, err, "index.md: a note already exists at this path")
+	})
+}
+
+func TestNoteDAOTouch(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		before, err := queryNoteRow(tx, `path = "ref/test/a.md"`)
+		assert.Nil(t, err)
+		linksBefore := queryLinkRows(t, tx, `source_id = 6 OR target_id = 6`)
+
+		modified := time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC)
+		err = dao.Touch("ref/test/a.md", modified)
+		assert.Nil(t, err)
+
+		// Only the modification date changed.
+		after, err := queryNoteRow(tx, `path = "ref/test/a.md"`)
+		assert.Nil(t, err)
+		before.Modified = modified
+		assert.Equal(t, after, before)
+		assert.Equal(t, queryLinkRows(t, tx, `source_id = 6 OR target_id = 6`), linksBefore)
+	})
+}
+
+func TestNoteDAOTouchUnknown(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		err := dao.Touch("unknown/unknown.md", time.Now())
+		assert.Err(t, err, "unknown/unknown.md: note not found in the index")
+	})
+}
+
+func TestNoteDAOChecksum(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		checksum, err := dao.Checksum("ref/test/a.md")
+		assert.Nil(t, err)
+		assert.Equal(t, checksum, "iecywst")
+
+		checksum, err = dao.Checksum("unknown.md")
+		assert.Nil(t, err)
+		assert.Equal(t, checksum, "")
+	})
+}
+
+func TestNoteDAOFindById(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		note, err := dao.FindById(6)
+		assert.Nil(t, err)
+		assert.Equal(t, note, &core.ContextualNote{
+			Note: core.Note{
+				ID:         6,
+				Path:       "ref/test/a.md",
+				Title:      "Another nested note",
+				Lead:       "It shall appear before b.md",
+				Body:       "It shall appear before b.md",
+				RawContent: "#Another nested note\nIt shall appear before b.md\nMatch [exact% ch\\ar_acters]",
+				WordCount:  5,
+				Links:      []core.Link{},
+				Tags:       []string{},
+				Metadata: map[string]interface{}{
+					"alias": "a.md",
+				},
+				Created:  time.Date(2019, 11, 20, 20, 32, 56, 0, time.UTC),
+				Modified: time.Date(2019, 11, 20, 20, 34, 6, 0, time.UTC),
+				Checksum: "iecywst",
+			},
+			Snippets: []string{"It shall appear before b.md"},
+		})
+	})
+}
+
+func TestNoteDAOFindByIdNotFound(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		note, err := dao.FindById(999)
+		assert.Nil(t, err)
+		assert.Nil(t, note)
+	})
+}
+
+func TestNoteDAOFindByChecksum(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		notes, err := dao.FindByChecksum("iecywst")
+		assert.Nil(t, err)
+		assert.Equal(t, notes, []core.ContextualNote{
+			{
+				Note: core.Note{
+					ID:         6,
+					Path:       "ref/test/a.md",
+					Title:      "Another nested note",
+					Lead:       "It shall appear before b.md",
+					Body:       "It shall appear before b.md",
+					RawContent: "#Another nested note\nIt shall appear before b.md\nMatch [exact% ch\\ar_acters]",
+					WordCount:  5,
+					Links:      []core.Link{},
+					Tags:       []string{},
+					Metadata: map[string]interface{}{
+						"alias": "a.md",
+					},
+					Created:  time.Date(2019, 11, 20, 20, 32, 56, 0, time.UTC),
+					Modified: time.Date(2019, 11, 20, 20, 34, 6, 0, time.UTC),
+					Checksum: "iecywst",
+				},
+				Snippets: []string{"It shall appear before b.md"},
+			},
+		})
+
+		notes, err = dao.FindByChecksum("unknown")
+		assert.Nil(t, err)
+		assert.Equal(t, len(notes), 0)
+	})
+}
+
+func TestNoteDAOFindByChecksumReturnsDuplicates(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := tx.Exec(`UPDATE notes SET checksum = "iecywst" WHERE id IN (1, 5)`)
+		assert.Nil(t, err)
+
+		notes, err := dao.FindByChecksum("iecywst")
+		assert.Nil(t, err)
+
+		actual := make([]string, 0)
+		for _, n := range notes {
+			actual = append(actual, n.Path)
+		}
+		assert.Equal(t, actual, []string{"log/2021-01-03.md", "ref/test/a.md", "ref/test/b.md"})
+	})
+}
+
 // Also remove the outbound links, and set the target_id of inbound links to NULL.
 func TestNoteDAORemoveCascadeLinks(t *testing.T) {
 	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
@@ -241,6 +442,35 @@ func TestNoteDAOFindIdsByHref(t *testing.T) {
 	// Filename takes precedence over the rest of the path.
 	// See https://github.com/zk-org/zk/issues/111
 	test("ref", true, []core.NoteID{8})
+
+	// All the notes sharing a path prefix are returned.
+	test("log/2021-01", false, []core.NoteID{1, 2})
+	test("ref/test/", true, []core.NoteID{6, 5, 8})
+}
+
+func TestNoteDAOFindIgnoringHrefCase(t *testing.T) {
+	test := func(opts core.NoteFindOpts, expected []string) {
+		testNoteDAOFindPaths(t, opts, expected)
+	}
+
+	test(core.NoteFindOpts{IncludeHrefs: []string{"LOG/2021-01"}}, []string{})
+	test(core.NoteFindOpts{
+		IncludeHrefs:   []string{"LOG/2021-01"},
+		IgnoreHrefCase: true,
+	}, []string{"log/2021-01-03.md", "log/2021-01-04.md"})
+	test(core.NoteFindOpts{
+		IncludeHrefs:      []string{"Test"},
+		AllowPartialHrefs: true,
+		IgnoreHrefCase:    true,
+	}, []string{"ref/test/ref.md", "ref/test/b.md", "ref/test/a.md"})
+	test(core.NoteFindOpts{
+		ExcludeHrefs:   []string{"Ref", "Log", "F39C8"},
+		IgnoreHrefCase: true,
+	}, []string{"index.md"})
+	test(core.NoteFindOpts{
+		LinkTo:         &core.LinkFilter{Hrefs: []string{"Log/2021-01-03"}},
+		IgnoreHrefCase: true,
+	}, []string{"f39c8.md"})
 }
 
 func TestNoteDAOFindIncludingHrefs(t *testing.T) {
@@ -326,7 +556,7 @@ func TestNoteDAOFindMinimalWithFilter(t *testing.T) {
 			{ID: 3, Path: "index.md", Title: "Index", Metadata: map[string]interface{}{
 				"aliases": []interface{}{"First page"},
 			}},
-			{ID: 7, Path: "log/2021-02-04.md", Title: "February 4, 2021", Metadata: map[string]interface{}{}},
+			{ID: 2, Path: "log/2021-01-04.md", Title: "January 4, 2021", Metadata: map[string]interface{}{}},
 		})
 	})
 }
@@ -363,6 +593,340 @@ func TestNoteDAOFindTag(t *testing.T) {
 	test([]string{"-fiction"}, []string{"ref/test/ref.md", "ref/test/b.md", "f39c8.md", "ref/test/a.md", "log/2021-02-04.md", "index.md", "log/2021-01-04.md"})
 	test([]string{"NOT   fiction"}, []string{"ref/test/ref.md", "ref/test/b.md", "f39c8.md", "ref/test/a.md", "log/2021-02-04.md", "index.md", "log/2021-01-04.md"})
 	test([]string{"NOTfiction"}, []string{"ref/test/ref.md", "ref/test/b.md", "f39c8.md", "ref/test/a.md", "log/2021-02-04.md", "index.md", "log/2021-01-04.md"})
+
+	test([]string{"(fiction | fantasy) AND science"}, []string{"f39c8.md"})
+	test([]string{"science AND NOT (fantasy OR fiction)"}, []string{"ref/test/b.md"})
+	test([]string{"adventure AND -(fiction)"}, []string{"ref/test/b.md"})
+	test([]string{"NOT (fiction | science)"}, []string{"ref/test/ref.md", "ref/test/a.md", "log/2021-02-04.md", "index.md", "log/2021-01-04.md"})
+	test([]string{"-fiction | fantasy"}, []string{"ref/test/ref.md", "ref/test/b.md", "f39c8.md", "ref/test/a.md", "log/2021-02-04.md", "index.md", "log/2021-01-04.md"})
+	test([]string{"((adventure))", "NOT NOT science"}, []string{"ref/test/b.md"})
+}
+
+func TestNoteDAOFindInvalidTagExpression(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := dao.FindPaths(core.NoteFindOpts{Tags: []string{"(fiction | fantasy"}})
+		assert.Err(t, err, `invalid tag expression "(fiction | fantasy": missing closing parenthesis`)
+	})
+}
+
+func TestNoteDAOFindOr(t *testing.T) {
+	sortByPath := []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}}
+
+	// Notes in the log folder, or tagged with fantasy.
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			IncludeHrefs: []string{"log"},
+			Or:           []core.NoteFindOpts{{Tags: []string{"fantasy"}}},
+			Sorters:      sortByPath,
+		},
+		[]string{"f39c8.md", "log/2021-01-03.md", "log/2021-01-04.md", "log/2021-02-04.md"},
+	)
+
+	// Each group of filters can use its own joins, and a note matching
+	// several groups is found once.
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			Match:         []string{"daily"},
+			MatchStrategy: core.MatchStrategyFts,
+			Or: []core.NoteFindOpts{
+				{LinkTo: &core.LinkFilter{Hrefs: []string{"log/2021-01-03"}}},
+				{Tags: []string{"fiction"}},
+			},
+			Sorters: sortByPath,
+		},
+		[]string{"f39c8.md", "log/2021-01-03.md", "log/2021-01-04.md", "log/2021-02-04.md"},
+	)
+
+	// The limit applies to the union of the groups.
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			IncludeHrefs: []string{"log"},
+			Or:           []core.NoteFindOpts{{Tags: []string{"fantasy"}}},
+			Sorters:      sortByPath,
+			Limit:        2,
+		},
+		[]string{"f39c8.md", "log/2021-01-03.md"},
+	)
+
+	// No notes match any of the groups.
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			Tags: []string{"unknown"},
+			Or:   []core.NoteFindOpts{{Tags: []string{"empty"}}},
+		},
+		[]string{},
+	)
+
+	// The nested groups of an alternative are evaluated as well.
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			IncludeHrefs: []string{"ref/test/a"},
+			Or: []core.NoteFindOpts{{
+				Tags: []string{"unknown"},
+				Or:   []core.NoteFindOpts{{Tags: []string{"fantasy"}}},
+			}},
+			Sorters: sortByPath,
+		},
+		[]string{"f39c8.md", "ref/test/a.md"},
+	)
+}
+
+func TestNoteDAOFindInvert(t *testing.T) {
+	sortByPath := []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}}
+
+	// Notes outside the log folder.
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			IncludeHrefs: []string{"log"},
+			Invert:       true,
+			Sorters:      sortByPath,
+		},
+		[]string{"f39c8.md", "index.md", "ref/test/a.md", "ref/test/b.md", "ref/test/ref.md"},
+	)
+
+	// The union of the OR groups is inverted, and the limit applies to the
+	// inverted results.
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			IncludeHrefs: []string{"log"},
+			Or:           []core.NoteFindOpts{{Tags: []string{"fantasy"}}},
+			Invert:       true,
+			Sorters:      sortByPath,
+			Limit:        2,
+		},
+		[]string{"index.md", "ref/test/a.md"},
+	)
+
+	// Inverting a filter matching no notes finds all of them.
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		notes, err := dao.Find(core.NoteFindOpts{Tags: []string{"unknown"}, Invert: true})
+		assert.Nil(t, err)
+		assert.Equal(t, len(notes), 8)
+	})
+
+	// The inverted results are the complement of the matching notes, even for
+	// the filters relying on joins or comparing NULL values.
+	for _, opts := range []core.NoteFindOpts{
+		{Match: []string{"daily"}, MatchStrategy: core.MatchStrategyFts},
+		{LinkTo: &core.LinkFilter{Hrefs: []string{"log/2021-01-03"}}},
+		{LinkedBy: &core.LinkFilter{Hrefs: []string{"f39c8"}, Recursive: true}},
+		{Related: []string{"log/2021-02-04"}},
+		{Tagless: true},
+		{HasFrontmatter: opt.NewBool(false)},
+		{Orphan: true},
+		{ExcludeMetadata: []core.MetadataFilter{{Key: "author", Value: "Dom"}}},
+		{},
+	} {
+		testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+			found := map[string]bool{}
+			notes, err := dao.Find(opts)
+			assert.Nil(t, err)
+			for _, note := range notes {
+				found[note.Path] = true
+			}
+
+			opts.Invert = true
+			inverted, err := dao.Find(opts)
+			assert.Nil(t, err)
+			assert.Equal(t, len(notes)+len(inverted), 8)
+			for _, note := range inverted {
+				assert.False(t, found[note.Path])
+			}
+		})
+	}
+}
+
+// The full tag list of the notes is loaded whatever the filters, including
+// the tag filters matching only some of them.
+func TestNoteDAOFindLoadsAllTags(t *testing.T) {
+	test := func(opts core.NoteFindOpts) {
+		testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+			opts.IncludeHrefs = []string{"log/2021-01-03.md"}
+			notes, err := dao.Find(opts)
+			assert.Nil(t, err)
+			assert.Equal(t, len(notes), 1)
+			assert.Equal(t, notes[0].Tags, []string{"fiction", "adventure"})
+		})
+	}
+
+	test(core.NoteFindOpts{})
+	test(core.NoteFindOpts{Tags: []string{"fiction"}})
+	test(core.NoteFindOpts{Tags: []string{"adventure", "-fantasy"}})
+	test(core.NoteFindOpts{Match: []string{"daily"}, MatchStrategy: core.MatchStrategyFts})
+	test(core.NoteFindOpts{LinkTo: &core.LinkFilter{Hrefs: []string{"log/2021-01-04"}}})
+	test(core.NoteFindOpts{LinkedBy: &core.LinkFilter{Hrefs: []string{"f39c8"}, Recursive: true}})
+	test(core.NoteFindOpts{ExcludeBody: true})
+	test(core.NoteFindOpts{Sorters: []core.NoteSorter{{Field: core.NoteSortTitle, Ascending: true}}})
+}
+
+func TestNoteDAOFindTagHierarchy(t *testing.T) {
+	test := func(tags []string, expectedPaths []string) {
+		testNoteDAOFindPathsWithFixtures(t, "tag-hierarchy", core.NoteFindOpts{Tags: tags}, expectedPaths)
+	}
+
+	test([]string{"project"}, []string{"a.md"})
+	test([]string{"project/*"}, []string{"b.md"})
+	test([]string{"project/**"}, []string{"b.md", "c.md"})
+	test([]string{"project/*/indexing"}, []string{"c.md"})
+	test([]string{"project*"}, []string{"a.md", "d.md"})
+	test([]string{"project**"}, []string{"a.md", "b.md", "c.md", "d.md"})
+	test([]string{"project?"}, []string{"d.md"})
+	test([]string{"project/[a-z]k"}, []string{"b.md"})
+	test([]string{"-project/**"}, []string{"a.md", "d.md"})
+	test([]string{"q[1-4]-review"}, []string{"a.md", "b.md"})
+	test([]string{"q[^1-4]-review"}, []string{"c.md"})
+	test([]string{"q[13]-review | q[5]-review"}, []string{"a.md", "b.md", "c.md"})
+}
+
+func TestTagGlobToRegex(t *testing.T) {
+	test := func(glob string, expected string) {
+		assert.Equal(t, tagGlobToRegex(glob), expected)
+	}
+
+	test("project", "^project$")
+	test("project/*", "^project/[^/]*$")
+	test("project/**", "^project/.*$")
+	test("p?oj.ect", "^p[^/]oj\\.ect$")
+	test("[^ab]*", "^[^ab][^/]*$")
+	test("[unclosed", "^\\[unclosed$")
+	test("q[1-4]-review", "^q[1-4]-review$")
+	test("[]x]", "^[\\]x]$")
+	test("[^]x]y", "^[^\\]x]y$")
+	test("[[x]", "^[\\[x]$")
+	test("[]", "^\\[\\]$")
+}
+
+func TestNoteDAOFindExcludingBody(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		notes, err := dao.Find(core.NoteFindOpts{
+			IncludeHrefs: []string{"log/2021-01-03.md"},
+			ExcludeBody:  true,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, len(notes), 1)
+
+		note := notes[0].Note
+		assert.Equal(t, note.Title, "Daily note")
+		assert.Equal(t, note.Lead, "A daily note")
+		assert.Equal(t, note.WordCount, 3)
+		assert.Equal(t, note.Body, "")
+		assert.Equal(t, note.RawContent, "")
+	})
+}
+
+func TestNoteDAOFindLiteralMatch(t *testing.T) {
+	test := func(strategy core.MatchStrategy, match string, literal bool, expected []string) {
+		testNoteDAOFindPaths(t, core.NoteFindOpts{
+			Match:         []string{match},
+			MatchStrategy: strategy,
+			MatchLiteral:  literal,
+		}, expected)
+	}
+
+	test(core.MatchStrategyFts, "Zettelkasten OR nothing", false, []string{"index.md"})
+	test(core.MatchStrategyFts, "Zettelkasten OR nothing", true, []string{})
+	test(core.MatchStrategyFts, "of the", true, []string{"index.md"})
+	test(core.MatchStrategyFts, "the of", true, []string{})
+	test(core.MatchStrategyRe, "Zettel.asten", false, []string{"index.md"})
+	test(core.MatchStrategyRe, "Zettel.asten", true, []string{})
+	test(core.MatchStrategyRe, "# Index", true, []string{"index.md"})
+}
+
+func TestNoteDAOFindLiteralMatchRejectsMention(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := dao.Find(core.NoteFindOpts{
+			Mention:       []string{"log/2021-01-03.md"},
+			MatchStrategy: core.MatchStrategyFts,
+			MatchLiteral:  true,
+		})
+		assert.Err(t, err, "--mention can't be used with --literal")
+	})
+}
+
+func TestNoteDAOFindFuzzyMatch(t *testing.T) {
+	test := func(match string, expected []string) {
+		testNoteDAOFindPaths(t, core.NoteFindOpts{
+			Match:         []string{match},
+			MatchStrategy: core.MatchStrategyFts,
+			MatchFuzzy:    true,
+		}, expected)
+	}
+
+	test("Zetelkasten", []string{"index.md"})
+	test("surprize", []string{"f39c8.md"})
+	test("nested", []string{"ref/test/a.md", "ref/test/b.md"})
+	test("sub directry", []string{"ref/test/b.md"})
+	test("a sub directry", []string{"ref/test/b.md"})
+	test("qwerty", []string{})
+
+	// Without --fuzzy, typos are not tolerated.
+	testNoteDAOFindPaths(t, core.NoteFindOpts{
+		Match:         []string{"Zetelkasten"},
+		MatchStrategy: core.MatchStrategyFts,
+	}, []string{})
+}
+
+func TestNoteDAOFindFuzzyMatchRejectsOtherStrategies(t *testing.T) {
+	test := func(opts core.NoteFindOpts, expected string) {
+		testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+			opts.MatchFuzzy = true
+			_, err := dao.Find(opts)
+			assert.Err(t, err, expected)
+		})
+	}
+
+	test(core.NoteFindOpts{Match: []string{"index"}, MatchStrategy: core.MatchStrategyRe}, "--fuzzy can only be used with --match-strategy=fts")
+	test(core.NoteFindOpts{Match: []string{"index"}, MatchStrategy: core.MatchStrategyFts, MatchRaw: true}, "--fuzzy can't be used with --match-raw")
+	test(core.NoteFindOpts{Mention: []string{"log/2021-01-03.md"}, MatchStrategy: core.MatchStrategyFts}, "--mention can't be used with --fuzzy")
+}
+
+func TestNoteDAOFindLocateMatch(t *testing.T) {
+	test := func(opts core.NoteFindOpts, expected map[string]int) {
+		testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+			opts.IncludeHrefs = []string{"index.md"}
+			opts.LocateMatch = true
+			notes, err := dao.Find(opts)
+			assert.Nil(t, err)
+			actual := map[string]int{}
+			for _, note := range notes {
+				actual[note.Path] = note.MatchOffset
+			}
+			assert.Equal(t, actual, expected)
+		})
+	}
+
+	// The raw content of index.md is "# Index\nIndex of the Zettelkasten".
+	test(core.NoteFindOpts{Match: []string{"zettelkasten"}, MatchStrategy: core.MatchStrategyFts}, map[string]int{"index.md": 21})
+	test(core.NoteFindOpts{Match: []string{"of the"}, MatchStrategy: core.MatchStrategyExact}, map[string]int{"index.md": 14})
+	test(core.NoteFindOpts{Match: []string{"INDEX"}, MatchStrategy: core.MatchStrategyExact}, map[string]int{"index.md": 2})
+	test(core.NoteFindOpts{Match: []string{"Z\\w+", "th."}, MatchStrategy: core.MatchStrategyRe}, map[string]int{"index.md": 17})
+	test(core.NoteFindOpts{}, map[string]int{"index.md": -1})
+	// The full-text matches are located by FTS5, regardless of their case and
+	// of the stemming of the terms.
+	test(core.NoteFindOpts{Match: []string{"indexes"}, MatchStrategy: core.MatchStrategyFts}, map[string]int{"index.md": 2})
+	test(core.NoteFindOpts{Match: []string{"body:index"}, MatchStrategy: core.MatchStrategyFts}, map[string]int{"index.md": 8})
+	test(core.NoteFindOpts{Match: []string{"zettel*"}, MatchStrategy: core.MatchStrategyFts}, map[string]int{"index.md": 21})
+	test(core.NoteFindOpts{Match: []string{"zettelkastn"}, MatchStrategy: core.MatchStrategyFts, MatchFuzzy: true}, map[string]int{"index.md": 21})
+	// The raw content is loaded again when the body is excluded.
+	test(core.NoteFindOpts{Match: []string{"zettelkasten"}, MatchStrategy: core.MatchStrategyFts, ExcludeBody: true}, map[string]int{"index.md": 21})
+	test(core.NoteFindOpts{Match: []string{"of the"}, MatchStrategy: core.MatchStrategyExact, ExcludeBody: true}, map[string]int{"index.md": 14})
+}
+
+func TestNoteDAOFindLocateMatchCountsCharacters(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := tx.Exec(`UPDATE notes SET raw_content = '# Été À voir' WHERE path = 'index.md'`)
+		assert.Nil(t, err)
+
+		notes, err := dao.Find(core.NoteFindOpts{
+			IncludeHrefs:  []string{"index.md"},
+			Match:         []string{"voir"},
+			MatchStrategy: core.MatchStrategyExact,
+			LocateMatch:   true,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, len(notes), 1)
+		assert.Equal(t, notes[0].MatchOffset, 8)
+	})
 }
 
 func TestNoteDAOFindMatch(t *testing.T) {
@@ -391,6 +955,7 @@ func TestNoteDAOFindMatch(t *testing.T) {
 					Checksum: "iaefhv",
 				},
 				Snippets: []string{"<zk:match>Index</zk:match> of the Zettelkasten"},
+				Score:    3.538607157563684,
 			},
 			{
 				Note: core.Note{
@@ -411,6 +976,7 @@ func TestNoteDAOFindMatch(t *testing.T) {
 					Checksum: "qwfpgj",
 				},
 				Snippets: []string{"A <zk:match>daily</zk:match> note\n\nWith lot of content"},
+				Score:    0.9915145139573839,
 			},
 			{
 				Note: core.Note{
@@ -429,6 +995,7 @@ func TestNoteDAOFindMatch(t *testing.T) {
 					Checksum:   "earkte",
 				},
 				Snippets: []string{"A third <zk:match>daily</zk:match> note"},
+				Score:    0.43884884996365736,
 			},
 			{
 				Note: core.Note{
@@ -447,6 +1014,7 @@ func TestNoteDAOFindMatch(t *testing.T) {
 					Checksum:   "arstde",
 				},
 				Snippets: []string{"A second <zk:match>daily</zk:match> note"},
+				Score:    0.43884884996365736,
 			},
 		},
 	)
@@ -467,14 +1035,63 @@ func TestNoteDAOFindMatchWithMultiMatch(t *testing.T) {
 	)
 }
 
-func TestNoteDAOFindMatchWithSort(t *testing.T) {
-	testNoteDAOFindPaths(t,
-		core.NoteFindOpts{
-			Match:         []string{"daily | index"},
-			MatchStrategy: core.MatchStrategyFts,
-			Sorters: []core.NoteSorter{
-				{Field: core.NoteSortPath, Ascending: false},
-			},
+func TestNoteDAOFindMatchAny(t *testing.T) {
+	test := func(opts core.NoteFindOpts, expected []string) {
+		opts.MatchStrategy = core.MatchStrategyFts
+		opts.Sorters = []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}}
+		testNoteDAOFindPaths(t, opts, expected)
+	}
+
+	test(core.NoteFindOpts{Match: []string{"second zettelkasten"}}, []string{})
+	test(core.NoteFindOpts{Match: []string{"second zettelkasten"}, MatchAny: true}, []string{"index.md", "log/2021-01-04.md"})
+	// The explicit operators are kept.
+	test(core.NoteFindOpts{Match: []string{"second third -february"}, MatchAny: true}, []string{"log/2021-01-04.md"})
+	// Each --match query must still match.
+	test(core.NoteFindOpts{Match: []string{"second zettelkasten", "daily"}, MatchAny: true}, []string{"log/2021-01-04.md"})
+	test(core.NoteFindOpts{LeadMatch: []string{"second zettelkasten"}, MatchAny: true}, []string{"index.md", "log/2021-01-04.md"})
+
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		for opts, expected := range map[*core.NoteFindOpts]string{
+			{MatchStrategy: core.MatchStrategyExact}:                   "--match-any can only be used with --match-strategy=fts",
+			{MatchStrategy: core.MatchStrategyFts, MatchLiteral: true}: "--match-any can't be used with --literal",
+			{MatchStrategy: core.MatchStrategyFts, MatchFuzzy: true}:   "--match-any can't be used with --fuzzy",
+			{MatchStrategy: core.MatchStrategyFts, MatchRaw: true}:     "--match-any can't be used with --match-raw",
+		} {
+			opts.Match = []string{"daily"}
+			opts.MatchAny = true
+			_, err := dao.Find(*opts)
+			assert.Err(t, err, expected)
+		}
+	})
+}
+
+func TestNoteDAOFindMatchWithColumnFilters(t *testing.T) {
+	test := func(match string, expected []string) {
+		testNoteDAOFindPaths(t,
+			core.NoteFindOpts{
+				Match:         []string{match},
+				MatchStrategy: core.MatchStrategyFts,
+				Sorters:       []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}},
+			},
+			expected,
+		)
+	}
+
+	test("title:daily body:content", []string{"log/2021-01-03.md"})
+	test("body:content", []string{"f39c8.md", "log/2021-01-03.md"})
+	test("{title path}:index", []string{"index.md"})
+	test("{path title}:log* daily", []string{"log/2021-01-03.md", "log/2021-01-04.md", "log/2021-02-04.md"})
+	test("{path title}:log* -{body}:second", []string{"log/2021-01-03.md", "log/2021-02-04.md"})
+}
+
+func TestNoteDAOFindMatchWithSort(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			Match:         []string{"daily | index"},
+			MatchStrategy: core.MatchStrategyFts,
+			Sorters: []core.NoteSorter{
+				{Field: core.NoteSortPath, Ascending: false},
+			},
 		},
 		[]string{
 			"log/2021-02-04.md",
@@ -502,6 +1119,561 @@ func TestNoteDAOFindExactMatch(t *testing.T) {
 	test(`[exact% ch\ar_acters]`, []string{"ref/test/a.md"})
 }
 
+func TestNoteDAOFindWordCountTopPercent(t *testing.T) {
+	test := func(percent int, expected []string) {
+		testNoteDAOFindPaths(t,
+			core.NoteFindOpts{
+				WordCountTopPercent: percent,
+				Sorters:             []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}},
+			},
+			expected,
+		)
+	}
+
+	test(10, []string{"ref/test/b.md"})
+	test(50, []string{"f39c8.md", "ref/test/a.md", "ref/test/b.md", "ref/test/ref.md"})
+	test(100, []string{"f39c8.md", "index.md", "log/2021-01-03.md", "log/2021-01-04.md", "log/2021-02-04.md", "ref/test/a.md", "ref/test/b.md", "ref/test/ref.md"})
+}
+
+func TestNoteDAOFindPathRegex(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{PathRegex: `^log/2021-0[12]-04\.md$`},
+		[]string{"log/2021-02-04.md", "log/2021-01-04.md"},
+	)
+
+	// Combined with hrefs.
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			IncludeHrefs: []string{"ref"},
+			PathRegex:    `/[ab]\.md$`,
+		},
+		[]string{"ref/test/b.md", "ref/test/a.md"},
+	)
+}
+
+func TestNoteDAOFindBodyRegex(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{BodyRegex: `\bdaily\b[^.]*content`},
+		[]string{"log/2021-01-03.md"},
+	)
+
+	// The title is not part of the body.
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{BodyRegex: `Zettelkasten$`},
+		[]string{"index.md"},
+	)
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{BodyRegex: `^Index$`},
+		[]string{},
+	)
+}
+
+func TestNoteDAOFindExternalLinkTo(t *testing.T) {
+	testNoteDAOFindSnippets(t,
+		core.NoteFindOpts{ExternalLinkTo: []string{"domain.com"}},
+		[][]string{{"https://domain.com"}},
+	)
+	testNoteDAOFindSnippets(t,
+		core.NoteFindOpts{ExternalLinkTo: []string{"other.org", "domain"}},
+		[][]string{{"https://domain.com"}},
+	)
+	// LIKE wildcards are escaped.
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{ExternalLinkTo: []string{"domain%com"}},
+		[]string{},
+	)
+	// Internal links are ignored.
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{ExternalLinkTo: []string{"log/2021-01-04"}},
+		[]string{},
+	)
+}
+
+func TestNoteDAOFindBacklinksCount(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		// Links to itself are ignored.
+		_, err := tx.Exec(`
+			INSERT INTO links (source_id, target_id, title, href, type, external, rels, snippet)
+			VALUES (2, 6, 'Link', 'ref/test/a', 'wiki-link', 0, '', ''),
+			       (6, 6, 'Self', 'ref/test/a', 'wiki-link', 0, '', '')
+		`)
+		assert.Nil(t, err)
+
+		test := func(opts core.NoteFindOpts, expected []string) {
+			opts.Sorters = []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}}
+			matches, err := dao.Find(opts)
+			assert.Nil(t, err)
+
+			actual := make([]string, 0)
+			for _, m := range matches {
+				actual = append(actual, m.Path)
+			}
+			assert.Equal(t, actual, expected)
+		}
+
+		test(core.NoteFindOpts{MinBacklinks: 2}, []string{"ref/test/a.md"})
+		test(core.NoteFindOpts{MaxBacklinks: opt.NewInt(0)}, []string{"log/2021-02-04.md", "ref/test/b.md", "ref/test/ref.md"})
+		test(core.NoteFindOpts{MinBacklinks: 1, MaxBacklinks: opt.NewInt(1)}, []string{"f39c8.md", "index.md", "log/2021-01-03.md", "log/2021-01-04.md"})
+		test(core.NoteFindOpts{MinBacklinks: 1, Tags: []string{"fiction"}}, []string{"log/2021-01-03.md"})
+	})
+}
+
+func TestNoteDAOFindIgnoresExternalLinksInGraph(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		// An external link whose href happens to match a note.
+		_, err := tx.Exec("UPDATE links SET target_id = 5 WHERE id = 3")
+		assert.Nil(t, err)
+
+		test := func(opts core.NoteFindOpts, expected []string) {
+			opts.Sorters = []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}}
+			matches, err := dao.Find(opts)
+			assert.Nil(t, err)
+
+			actual := make([]string, 0)
+			for _, m := range matches {
+				actual = append(actual, m.Path)
+			}
+			assert.Equal(t, actual, expected)
+		}
+
+		test(core.NoteFindOpts{LinkedBy: &core.LinkFilter{Hrefs: []string{"log/2021-01-03.md"}}}, []string{"log/2021-01-04.md"})
+		test(core.NoteFindOpts{LinkedBy: &core.LinkFilter{Hrefs: []string{"log/2021-01-03.md"}, Recursive: true, MaxDistance: 1}}, []string{"log/2021-01-04.md"})
+		test(core.NoteFindOpts{LinkTo: &core.LinkFilter{Hrefs: []string{"ref/test/b.md"}}}, []string{})
+		test(core.NoteFindOpts{Orphan: true}, []string{"log/2021-02-04.md", "ref/test/b.md", "ref/test/ref.md"})
+		test(core.NoteFindOpts{MaxBacklinks: opt.NewInt(0)}, []string{"log/2021-02-04.md", "ref/test/b.md", "ref/test/ref.md"})
+	})
+}
+
+func TestNoteDAOFindUntypedLinks(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := tx.Exec("UPDATE links SET rels = ? WHERE source_id IN (1, 4)", "\x01up\x01")
+		assert.Nil(t, err)
+
+		matches, err := dao.Find(core.NoteFindOpts{
+			UntypedLinks: true,
+			Sorters:      []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}},
+		})
+		assert.Nil(t, err)
+
+		actual := make([]string, 0)
+		for _, m := range matches {
+			actual = append(actual, m.Path)
+		}
+		assert.Equal(t, actual, []string{"index.md", "log/2021-01-04.md"})
+	})
+}
+
+func TestNoteDAOFindDirs(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		findPaths := func(opts core.NoteFindOpts) []string {
+			opts.Sorters = []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}}
+			matches, err := dao.Find(opts)
+			assert.Nil(t, err)
+
+			actual := make([]string, 0)
+			for _, m := range matches {
+				actual = append(actual, m.Path)
+			}
+			return actual
+		}
+
+		// The results are identical to the path filter, for a plain folder.
+		for _, dir := range []string{"log", "ref", "ref/test"} {
+			assert.Equal(t,
+				findPaths(core.NoteFindOpts{Dirs: []string{dir}}),
+				findPaths(core.NoteFindOpts{IncludeHrefs: []string{dir}}),
+			)
+		}
+
+		assert.Equal(t, findPaths(core.NoteFindOpts{Dirs: []string{"log/"}}), []string{"log/2021-01-03.md", "log/2021-01-04.md", "log/2021-02-04.md"})
+		assert.Equal(t, findPaths(core.NoteFindOpts{Dirs: []string{"ref/test", "log"}}), []string{
+			"log/2021-01-03.md", "log/2021-01-04.md", "log/2021-02-04.md",
+			"ref/test/a.md", "ref/test/b.md", "ref/test/ref.md",
+		})
+		assert.Equal(t, findPaths(core.NoteFindOpts{Dirs: []string{"ref", "missing"}}), []string{"ref/test/a.md", "ref/test/b.md", "ref/test/ref.md"})
+		// The root directory matches all the notes.
+		assert.Equal(t, len(findPaths(core.NoteFindOpts{Dirs: []string{"log", ""}})), 8)
+
+		// Sibling files and folders sharing the same prefix are not part of
+		// the directory.
+		_, err := tx.Exec("UPDATE notes SET path = 'logs/index.md' WHERE path = 'index.md'")
+		assert.Nil(t, err)
+		_, err = tx.Exec("UPDATE notes SET path = 'log.md' WHERE path = 'f39c8.md'")
+		assert.Nil(t, err)
+		assert.Equal(t, findPaths(core.NoteFindOpts{Dirs: []string{"log"}}), []string{"log/2021-01-03.md", "log/2021-01-04.md", "log/2021-02-04.md"})
+	})
+}
+
+func TestNoteDAOFindHasMarkdownFeatures(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		contents := map[string]string{
+			"index.md":          "# Index\n\n```go\nfmt.Println()\n```\n\nSee [[log/2021-01-03]].",
+			"f39c8.md":          "| Name | Age |\n|:-----|----:|\n| Bob  | 42  |\n\n![Cover](cover.png)",
+			"log/2021-01-03.md": "A [link](https://example.com).\n\n---\n\n    ```not fenced",
+			"ref/test/a.md":     "Title\n-----\n\n![Only an image](img.png)",
+			"ref/test/b.md":     "- [x] Done\n  1. [ ] Nested and open",
+			"log/2021-01-04.md": "* [X] Done\n\n[ ] Not in a list\n- [ ]not a task",
+		}
+		_, err := tx.Exec("UPDATE notes SET raw_content = ''")
+		assert.Nil(t, err)
+		for path, content := range contents {
+			_, err := tx.Exec("UPDATE notes SET raw_content = ? WHERE path = ?", content, path)
+			assert.Nil(t, err)
+		}
+
+		test := func(has []core.MarkdownFeature, expected []string) {
+			matches, err := dao.Find(core.NoteFindOpts{
+				Has:     has,
+				Sorters: []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}},
+			})
+			assert.Nil(t, err)
+
+			actual := make([]string, 0)
+			for _, m := range matches {
+				actual = append(actual, m.Path)
+			}
+			assert.Equal(t, actual, expected)
+		}
+
+		test([]core.MarkdownFeature{core.MarkdownFeatureCode}, []string{"index.md"})
+		test([]core.MarkdownFeature{core.MarkdownFeatureTable}, []string{"f39c8.md"})
+		test([]core.MarkdownFeature{core.MarkdownFeatureImage}, []string{"f39c8.md", "ref/test/a.md"})
+		test([]core.MarkdownFeature{core.MarkdownFeatureLink}, []string{"index.md", "log/2021-01-03.md"})
+		// Several features must all be present.
+		test([]core.MarkdownFeature{core.MarkdownFeatureCode, core.MarkdownFeatureLink}, []string{"index.md"})
+		test([]core.MarkdownFeature{core.MarkdownFeatureCode, core.MarkdownFeatureTable}, []string{})
+		test([]core.MarkdownFeature{core.MarkdownFeatureTask}, []string{"log/2021-01-04.md", "ref/test/b.md"})
+		test([]core.MarkdownFeature{core.MarkdownFeatureOpenTask}, []string{"ref/test/b.md"})
+	})
+}
+
+func TestNoteDAOFindByEditingGap(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := tx.Exec("UPDATE notes SET modified = ? WHERE path = 'index.md'", "2020-01-15T11:59:11Z")
+		assert.Nil(t, err)
+		_, err = tx.Exec("UPDATE notes SET modified = ? WHERE path = 'f39c8.md'", "2020-01-23T10:58:41Z")
+		assert.Nil(t, err)
+
+		test := func(opts core.NoteFindOpts, expected []string) {
+			opts.Sorters = []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}}
+			matches, err := dao.Find(opts)
+			assert.Nil(t, err)
+
+			actual := make([]string, 0)
+			for _, m := range matches {
+				actual = append(actual, m.Path)
+			}
+			assert.Equal(t, actual, expected)
+		}
+
+		test(core.NoteFindOpts{StaleDays: 1}, []string{"log/2021-01-03.md", "log/2021-01-04.md", "log/2021-02-04.md", "ref/test/a.md", "ref/test/b.md", "ref/test/ref.md"})
+		test(core.NoteFindOpts{StaleDays: 5}, []string{"f39c8.md", "log/2021-01-03.md", "log/2021-01-04.md", "log/2021-02-04.md", "ref/test/a.md", "ref/test/b.md", "ref/test/ref.md"})
+		test(core.NoteFindOpts{ActivelyEditedDays: 4}, []string{"f39c8.md", "index.md"})
+		test(core.NoteFindOpts{ActivelyEditedDays: 5}, []string{"index.md"})
+		test(core.NoteFindOpts{StaleDays: 30, ActivelyEditedDays: 4}, []string{"f39c8.md"})
+	})
+}
+
+func TestNoteDAOFindHasFrontmatter(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{HasFrontmatter: opt.NewBool(true)},
+		[]string{"ref/test/a.md", "log/2021-01-03.md", "index.md"},
+	)
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{HasFrontmatter: opt.NewBool(false)},
+		[]string{"ref/test/ref.md", "ref/test/b.md", "f39c8.md", "log/2021-02-04.md", "log/2021-01-04.md"},
+	)
+}
+
+func TestNoteDAOFindExcludeMetadata(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		for path, metadata := range map[string]string{
+			"log/2021-01-04.md": `{"draft":true}`,
+			"f39c8.md":          `{"draft":"true"}`,
+			"ref/test/b.md":     `{"draft":false}`,
+			"log/2021-02-04.md": `{"status":["draft","wip"],"rating":3}`,
+		} {
+			_, err := tx.Exec("UPDATE notes SET metadata = ? WHERE path = ?", metadata, path)
+			assert.Nil(t, err)
+		}
+
+		test := func(filters []core.MetadataFilter, expected []string) {
+			paths, err := dao.FindPaths(core.NoteFindOpts{
+				ExcludeMetadata: filters,
+				Sorters:         []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}},
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, paths, expected)
+		}
+
+		// Notes without the key are kept.
+		test([]core.MetadataFilter{{Key: "draft", Value: "true"}},
+			[]string{"index.md", "log/2021-01-03.md", "log/2021-02-04.md", "ref/test/a.md", "ref/test/b.md", "ref/test/ref.md"})
+		test([]core.MetadataFilter{{Key: "draft", Value: "false"}},
+			[]string{"f39c8.md", "index.md", "log/2021-01-03.md", "log/2021-01-04.md", "log/2021-02-04.md", "ref/test/a.md", "ref/test/ref.md"})
+		// Any item of a list can match.
+		test([]core.MetadataFilter{{Key: "status", Value: "wip"}, {Key: "author", Value: "Dom"}},
+			[]string{"f39c8.md", "index.md", "log/2021-01-04.md", "ref/test/a.md", "ref/test/b.md", "ref/test/ref.md"})
+		test([]core.MetadataFilter{{Key: "rating", Value: "3"}},
+			[]string{"f39c8.md", "index.md", "log/2021-01-03.md", "log/2021-01-04.md", "ref/test/a.md", "ref/test/b.md", "ref/test/ref.md"})
+	})
+}
+
+// FindPaths returns the same notes as Find, in the same order.
+func TestNoteDAOFindPaths(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		test := func(opts core.NoteFindOpts) {
+			notes, err := dao.Find(opts)
+			assert.Nil(t, err)
+			expected := make([]string, 0)
+			for _, n := range notes {
+				expected = append(expected, n.Path)
+			}
+			assert.Equal(t, len(expected) > 0, true)
+
+			paths, err := dao.FindPaths(opts)
+			assert.Nil(t, err)
+			assert.Equal(t, paths, expected)
+		}
+
+		test(core.NoteFindOpts{
+			Sorters: []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}},
+			Limit:   3,
+		})
+		test(core.NoteFindOpts{
+			Match:         []string{"daily"},
+			MatchStrategy: core.MatchStrategyFts,
+		})
+		test(core.NoteFindOpts{
+			LinkedBy: &core.LinkFilter{Hrefs: []string{"log/2021-01-03.md"}},
+		})
+		test(core.NoteFindOpts{
+			Mention:       []string{"log/2021-01-03.md"},
+			MatchStrategy: core.MatchStrategyFts,
+		})
+	})
+}
+
+func TestNoteDAOFindUndated(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := tx.Exec("UPDATE notes SET created = ? WHERE path = 'ref/test/b.md'", time.Time{})
+		assert.Nil(t, err)
+
+		test := func(opts core.NoteFindOpts, expected []string) {
+			if opts.Sorters == nil {
+				opts.Sorters = []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}}
+			}
+			matches, err := dao.Find(opts)
+			assert.Nil(t, err)
+
+			actual := make([]string, 0)
+			for _, m := range matches {
+				actual = append(actual, m.Path)
+			}
+			assert.Equal(t, actual, expected)
+		}
+
+		test(core.NoteFindOpts{Undated: true}, []string{"ref/test/b.md"})
+
+		// Undated notes are excluded from any date range.
+		start := time.Date(2019, time.December, 1, 0, 0, 0, 0, time.UTC)
+		test(core.NoteFindOpts{CreatedEnd: &start}, []string{"ref/test/a.md", "ref/test/ref.md"})
+		test(core.NoteFindOpts{CreatedStart: &start}, []string{"f39c8.md", "index.md", "log/2021-01-03.md", "log/2021-01-04.md", "log/2021-02-04.md"})
+		test(core.NoteFindOpts{ActivelyEditedDays: 1}, []string{})
+
+		// They are listed first when sorting by ascending creation date, and
+		// last otherwise.
+		test(core.NoteFindOpts{
+			IncludeIDs: []core.NoteID{5, 6, 3},
+			Sorters:    []core.NoteSorter{{Field: core.NoteSortCreated, Ascending: true}},
+		}, []string{"ref/test/b.md", "ref/test/a.md", "index.md"})
+		test(core.NoteFindOpts{
+			IncludeIDs: []core.NoteID{5, 6, 3},
+			Sorters:    []core.NoteSorter{{Field: core.NoteSortCreated, Ascending: false}},
+		}, []string{"index.md", "ref/test/a.md", "ref/test/b.md"})
+
+		// The zero time is read back as is.
+		notes, err := dao.Find(core.NoteFindOpts{Undated: true})
+		assert.Nil(t, err)
+		assert.Equal(t, notes[0].Created.IsZero(), true)
+	})
+}
+
+func TestNoteDAOFindCreatedYears(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{CreatedYears: []int{2019}},
+		[]string{"ref/test/ref.md", "ref/test/b.md", "ref/test/a.md", "index.md"},
+	)
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{CreatedYears: []int{2020, 2021}},
+		[]string{"f39c8.md", "log/2021-01-03.md", "log/2021-02-04.md", "log/2021-01-04.md"},
+	)
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{CreatedYears: []int{2018}},
+		[]string{},
+	)
+}
+
+func TestNoteDAOFindModifiedYears(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{ModifiedYears: []int{2020}, CreatedYears: []int{2020}},
+		[]string{"f39c8.md", "log/2021-01-03.md", "log/2021-02-04.md", "log/2021-01-04.md"},
+	)
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{ModifiedYears: []int{2019}, CreatedYears: []int{2020}},
+		[]string{},
+	)
+}
+
+func TestNoteDAOFindOnThisDay(t *testing.T) {
+	// The days are compared in the local time zone, so the dates of the
+	// notes are set in it to get the same results anywhere.
+	local := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, time.Local)
+	}
+
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		setDate := func(col string, path string, date time.Time) {
+			_, err := tx.Exec("UPDATE notes SET "+col+" = ? WHERE path = ?", date, path)
+			assert.Nil(t, err)
+		}
+		_, err := tx.Exec("UPDATE notes SET created = ?, modified = ?", local(2000, time.June, 15, 12, 0), local(2000, time.June, 15, 12, 0))
+		assert.Nil(t, err)
+		setDate("created", "ref/test/a.md", local(2019, time.November, 20, 23, 30))
+		setDate("created", "ref/test/b.md", local(2019, time.November, 20, 0, 30))
+		setDate("created", "ref/test/ref.md", local(2020, time.November, 21, 0, 30))
+		setDate("modified", "index.md", local(2020, time.March, 1, 23, 30))
+
+		test := func(opts core.NoteFindOpts, expected []string) {
+			opts.Sorters = []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}}
+			paths, err := dao.FindPaths(opts)
+			assert.Nil(t, err)
+			assert.Equal(t, paths, expected)
+		}
+		date := func(date time.Time) *time.Time {
+			return &date
+		}
+
+		test(core.NoteFindOpts{CreatedOnThisDay: date(local(2021, time.November, 20, 12, 0))}, []string{"ref/test/a.md", "ref/test/b.md"})
+		test(core.NoteFindOpts{CreatedOnThisDay: date(local(2021, time.November, 21, 12, 0))}, []string{"ref/test/ref.md"})
+		test(core.NoteFindOpts{ModifiedOnThisDay: date(local(2022, time.March, 1, 8, 0))}, []string{"index.md"})
+		// The given date is compared in the local time zone as well.
+		test(core.NoteFindOpts{CreatedOnThisDay: date(local(2021, time.November, 20, 23, 0).UTC())}, []string{"ref/test/a.md", "ref/test/b.md"})
+		// Only the prior years are considered.
+		test(core.NoteFindOpts{CreatedOnThisDay: date(local(2020, time.November, 21, 12, 0))}, []string{})
+
+		// Undated notes are not found on January 1st.
+		setDate("created", "ref/test/b.md", time.Time{})
+		test(core.NoteFindOpts{CreatedOnThisDay: date(local(2022, time.January, 1, 12, 0))}, []string{})
+	})
+}
+
+func TestNoteDAOFindAnomalousDates(t *testing.T) {
+	testNoteDAOFindPaths(t, core.NoteFindOpts{AnomalousDates: true}, []string{"log/2021-02-04.md"})
+
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		// A single second before the creation is enough.
+		_, err := tx.Exec("UPDATE notes SET modified = '2019-12-04T11:59:10Z' WHERE path = 'index.md'")
+		assert.Nil(t, err)
+
+		paths, err := dao.FindPaths(core.NoteFindOpts{
+			AnomalousDates: true,
+			Sorters:        []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, paths, []string{"index.md", "log/2021-02-04.md"})
+	})
+}
+
+func TestNoteDAOFindSnippetsFromLead(t *testing.T) {
+	testNoteDAOFindSnippets(t,
+		core.NoteFindOpts{
+			Match:         []string{"daily"},
+			MatchStrategy: core.MatchStrategyFts,
+			SnippetSource: core.SnippetSourceLead,
+		},
+		[][]string{
+			{"A daily note"},
+			{"A third daily note"},
+			{"A second daily note"},
+		},
+	)
+
+	testNoteDAOFindSnippets(t,
+		core.NoteFindOpts{
+			LinkTo:        &core.LinkFilter{Hrefs: []string{"log/2021-01-03.md"}},
+			SnippetSource: core.SnippetSourceLead,
+		},
+		[][]string{{"Its content will surprise you"}},
+	)
+}
+
+func TestNoteDAOFindLeadMatch(t *testing.T) {
+	// "content" is also found in the body of log/2021-01-03.md.
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			MatchStrategy: core.MatchStrategyFts,
+			LeadMatch:     []string{"content"},
+		},
+		[]string{"f39c8.md"},
+	)
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			MatchStrategy: core.MatchStrategyFts,
+			LeadMatch:     []string{"page"},
+		},
+		[]string{},
+	)
+	testNoteDAOFindSnippets(t,
+		core.NoteFindOpts{
+			MatchStrategy: core.MatchStrategyFts,
+			LeadMatch:     []string{"surprise"},
+		},
+		[][]string{{"Its content will <zk:match>surprise</zk:match> you"}},
+	)
+	// Combined with --match, both must be found.
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			Match:         []string{"daily"},
+			MatchStrategy: core.MatchStrategyFts,
+			LeadMatch:     []string{"second OR third"},
+			Sorters:       []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}},
+		},
+		[]string{"log/2021-01-04.md", "log/2021-02-04.md"},
+	)
+}
+
+func TestNoteDAOFindLeadMatchRequiresFts(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := dao.Find(core.NoteFindOpts{
+			MatchStrategy: core.MatchStrategyRe,
+			LeadMatch:     []string{"content"},
+		})
+		assert.Err(t, err, "--lead-match can only be used with --match-strategy=fts")
+	})
+}
+
+func TestNoteDAOFindMatchRaw(t *testing.T) {
+	test := func(match string, strategy core.MatchStrategy, matchRaw bool, expected []string) {
+		testNoteDAOFindPaths(t,
+			core.NoteFindOpts{
+				Match:         []string{match},
+				MatchStrategy: strategy,
+				MatchRaw:      matchRaw,
+			},
+			expected,
+		)
+	}
+
+	test("first page", core.MatchStrategyFts, false, []string{"ref/test/b.md"})
+	test("first page", core.MatchStrategyFts, true, []string{})
+	test("# a second", core.MatchStrategyFts, true, []string{"log/2021-01-04.md"})
+	test("its content", core.MatchStrategyFts, true, []string{"f39c8.md"})
+	// Other strategies already search the raw content.
+	test("^#Another", core.MatchStrategyRe, true, []string{"ref/test/a.md"})
+	test("[exact%", core.MatchStrategyExact, true, []string{"ref/test/a.md"})
+}
+
 func TestNoteDAOFindMentionRequiresFtsMatchStrategy(t *testing.T) {
 	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
 		_, err := dao.Find(core.NoteFindOpts{
@@ -615,6 +1787,7 @@ func TestNoteDAOFindMentions(t *testing.T) {
 					Checksum:   "yvwbae",
 				},
 				Snippets: []string{"This one is in a sub sub directory, not the <zk:match>first page</zk:match>"},
+				Score:    1.2678716131140249,
 			},
 			{
 				Note: core.Note{
@@ -633,6 +1806,7 @@ func TestNoteDAOFindMentions(t *testing.T) {
 					Checksum:   "earkte",
 				},
 				Snippets: []string{"A third <zk:match>daily note</zk:match>"},
+				Score:    0.43884884996365736,
 			},
 			{
 				Note: core.Note{
@@ -651,11 +1825,41 @@ func TestNoteDAOFindMentions(t *testing.T) {
 					Checksum:   "arstde",
 				},
 				Snippets: []string{"A second <zk:match>daily note</zk:match>"},
+				Score:    0.43884884996365736,
 			},
 		},
 	)
 }
 
+func TestNoteDAOFindMentionAll(t *testing.T) {
+	test := func(mention []string, mentionAll bool, expected []string) {
+		testNoteDAOFindPaths(t,
+			core.NoteFindOpts{
+				MatchStrategy: core.MatchStrategyFts,
+				Mention:       mention,
+				MentionAll:    mentionAll,
+			},
+			expected,
+		)
+	}
+
+	test([]string{"log/2021-01-03.md", "index.md"}, false, []string{"ref/test/b.md", "log/2021-02-04.md", "log/2021-01-04.md"})
+	// No note mentions both "Daily note" and "Index".
+	test([]string{"log/2021-01-03.md", "index.md"}, true, []string{})
+	test([]string{"index.md"}, true, []string{"ref/test/b.md"})
+}
+
+func TestNoteDAOFindMentionAllRequiresEachNote(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := dao.Find(core.NoteFindOpts{
+			MatchStrategy: core.MatchStrategyFts,
+			Mention:       []string{"index.md", "unknown"},
+			MentionAll:    true,
+		})
+		assert.Err(t, err, "could not find notes at: unknown")
+	})
+}
+
 // Common use case: `--mention x --no-link-to x`
 func TestNoteDAOFindUnlinkedMentions(t *testing.T) {
 	testNoteDAOFindPaths(t,
@@ -671,6 +1875,17 @@ func TestNoteDAOFindUnlinkedMentions(t *testing.T) {
 	)
 }
 
+// All the notes matching a mentioned path prefix are expanded.
+func TestNoteDAOFindMentionWithPathPrefix(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			MatchStrategy: core.MatchStrategyFts,
+			Mention:       []string{"log/2021-01"},
+		},
+		[]string{"log/2021-02-04.md"},
+	)
+}
+
 func TestNoteDAOFindMentionUnknown(t *testing.T) {
 	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
 		opts := core.NoteFindOpts{
@@ -735,6 +1950,50 @@ func TestNoteDAOFindMentionedBy(t *testing.T) {
 	)
 }
 
+// The metadata keys holding the aliases are customizable.
+func TestNoteDAOFindMentionWithCustomAliasKeys(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := tx.Exec(`UPDATE notes SET metadata = '{"synonyms":["First page"]}' WHERE path = 'index.md'`)
+		assert.Nil(t, err)
+
+		test := func(opts core.NoteFindOpts, expected []string) {
+			opts.MatchStrategy = core.MatchStrategyFts
+			opts.Sorters = []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}}
+			paths, err := dao.FindPaths(opts)
+			assert.Nil(t, err)
+			assert.Equal(t, paths, expected)
+		}
+
+		// By default, only the `aliases` key is used.
+		test(core.NoteFindOpts{Mention: []string{"index.md"}}, []string{})
+		test(core.NoteFindOpts{MentionedBy: []string{"ref/test/b.md"}}, []string{})
+
+		aliasKeys := []string{"aliases", "synonyms"}
+		test(core.NoteFindOpts{Mention: []string{"index.md"}, AliasKeys: aliasKeys}, []string{"ref/test/b.md"})
+		test(core.NoteFindOpts{MentionedBy: []string{"ref/test/b.md"}, AliasKeys: aliasKeys}, []string{"index.md"})
+	})
+}
+
+// A note mentioned by several notes is found only once, with their snippets.
+func TestNoteDAOFindMentionedByManyNotes(t *testing.T) {
+	testNoteDAOFindSnippets(t,
+		core.NoteFindOpts{
+			MentionedBy: []string{"log/2021-01-04.md", "log/2021-02-04.md"},
+		},
+		[][]string{
+			{"A second <zk:match>daily note</zk:match>", "A third <zk:match>daily note</zk:match>"},
+		},
+	)
+
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			MentionedBy: []string{"log/2021-01-04.md", "log/2021-02-04.md"},
+			Limit:       1,
+		},
+		[]string{"log/2021-01-03.md"},
+	)
+}
+
 // Common use case: `--mentioned-by x --no-linked-by x`
 func TestNoteDAOFindUnlinkedMentionedBy(t *testing.T) {
 	testNoteDAOFindPaths(t,
@@ -801,6 +2060,56 @@ func TestNoteDAOFindLinkedByRecursiveWithMaxDistance(t *testing.T) {
 	)
 }
 
+func TestNoteDAOFindLinkToWithTruncatedSnippets(t *testing.T) {
+	testNoteDAOFindSnippets(t,
+		core.NoteFindOpts{
+			LinkTo:                &core.LinkFilter{Hrefs: []string{"log/2021-01-04.md"}},
+			MaxLinkSnippetsLength: 15,
+		},
+		[][]string{{"[[<zk:match>An "}},
+	)
+	testNoteDAOFindSnippets(t,
+		core.NoteFindOpts{
+			LinkTo:                &core.LinkFilter{Hrefs: []string{"log/2021-01-04.md"}},
+			MaxLinkSnippetsLength: 100,
+		},
+		[][]string{{"[[<zk:match>An internal link</zk:match>]]"}},
+	)
+}
+
+func TestNoteDAOFindLinkedByWithDuplicatedSnippets(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		// f39c8.md links twice to ref/test/a.md with the same text, then to
+		// another note.
+		_, err := tx.Exec("UPDATE links SET title = 'Link', snippet = 'See [[Link]]' WHERE id IN (5, 6)")
+		assert.Nil(t, err)
+		_, err = tx.Exec(`
+			INSERT INTO links (source_id, target_id, title, href, type, external, rels, snippet, snippet_start, snippet_end)
+			VALUES (4, 6, 'Other', 'ref/test/a', 'wiki-link', false, '', '[[Other]]', 80, 89)
+		`)
+		assert.Nil(t, err)
+
+		test := func(opts core.NoteFindOpts, expected []string) {
+			opts.LinkedBy = &core.LinkFilter{Hrefs: []string{"f39c8.md"}}
+			opts.IncludeHrefs = []string{"ref/test/a.md"}
+			notes, err := dao.Find(opts)
+			assert.Nil(t, err)
+			assert.Equal(t, len(notes), 1)
+			assert.Equal(t, notes[0].Snippets, expected)
+		}
+
+		test(core.NoteFindOpts{}, []string{
+			"See [[<zk:match>Link</zk:match>]]",
+			"[[<zk:match>Other</zk:match>]]",
+		})
+		// The duplicated snippets don't take the room of the other ones.
+		test(core.NoteFindOpts{MaxLinkSnippetsLength: 65}, []string{
+			"See [[<zk:match>Link</zk:match>]]",
+			"[[<zk:match>Other</zk:match>]]",
+		})
+	})
+}
+
 func TestNoteDAOFindLinkedByWithSnippets(t *testing.T) {
 	testNoteDAOFind(t,
 		core.NoteFindOpts{
@@ -856,44 +2165,206 @@ func TestNoteDAOFindLinkedByWithSnippets(t *testing.T) {
 	)
 }
 
-func TestNoteDAOFindLinkedByUnknown(t *testing.T) {
+// Common use case: notes between A and B, with `--linked-by A --link-to B`
+func TestNoteDAOFindLinkedByAndLinkTo(t *testing.T) {
+	testNoteDAOFindSnippets(t,
+		core.NoteFindOpts{
+			LinkedBy: &core.LinkFilter{Hrefs: []string{"f39c8.md"}},
+			LinkTo:   &core.LinkFilter{Hrefs: []string{"log/2021-01-04.md"}},
+		},
+		[][]string{
+			{"[[<zk:match>Another link</zk:match>]]", "[[<zk:match>An internal link</zk:match>]]"},
+		},
+	)
+
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			LinkedBy: &core.LinkFilter{Hrefs: []string{"f39c8.md"}},
+			LinkTo:   &core.LinkFilter{Hrefs: []string{"index.md"}},
+		},
+		[]string{},
+	)
+
+	// Each recursive filter is constrained by its own maximum distance.
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			LinkedBy: &core.LinkFilter{Hrefs: []string{"index.md"}, Recursive: true, MaxDistance: 1},
+			LinkTo:   &core.LinkFilter{Hrefs: []string{"index.md"}, Recursive: true, MaxDistance: 3},
+		},
+		[]string{"f39c8.md"},
+	)
+}
+
+func TestNoteDAOFindWithCustomMatchMarkers(t *testing.T) {
+	testNoteDAOFindSnippets(t,
+		core.NoteFindOpts{
+			LinkedBy:   &core.LinkFilter{Hrefs: []string{"f39c8.md"}},
+			MatchOpen:  opt.NewString("**"),
+			MatchClose: opt.NewString("'s"),
+		},
+		[][]string{
+			{"[[**Link from 4 to 6's]]", "[[**Duplicated link's]]"},
+			{"[[**Another link's]]"},
+		},
+	)
+
+	testNoteDAOFindSnippets(t,
+		core.NoteFindOpts{
+			Match:         []string{"daily"},
+			MatchStrategy: core.MatchStrategyFts,
+			MatchOpen:     opt.NewString(""),
+			MatchClose:    opt.NewString(""),
+			Limit:         1,
+		},
+		[][]string{
+			{"A daily note\n\nWith lot of content"},
+		},
+	)
+}
+
+func TestNoteDAOFindLinkedByUnknown(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		opts := core.NoteFindOpts{
+			LinkedBy: &core.LinkFilter{
+				Hrefs: []string{"will-not-be-found"},
+			},
+		}
+		_, err := dao.Find(opts)
+		assert.Err(t, err, "could not find notes at: will-not-be-found")
+	})
+}
+
+func TestNoteDAOFindNotLinkedBy(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			LinkedBy: &core.LinkFilter{
+				Hrefs:     []string{"f39c8.md", "log/2021-01-03"},
+				Negate:    true,
+				Recursive: false,
+			},
+		},
+		[]string{"ref/test/ref.md", "ref/test/b.md", "f39c8.md", "log/2021-02-04.md", "index.md"},
+	)
+}
+
+func TestNoteDAOFindLinkTo(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			LinkTo: &core.LinkFilter{
+				Hrefs:     []string{"log/2021-01-04", "ref/test/a.md"},
+				Negate:    false,
+				Recursive: false,
+			},
+		},
+		[]string{"f39c8.md", "log/2021-01-03.md"},
+	)
+}
+
+func TestNoteDAOFindLinkToWithContext(t *testing.T) {
+	linkTo := func(contexts ...string) core.NoteFindOpts {
+		return core.NoteFindOpts{
+			LinkTo: &core.LinkFilter{
+				Hrefs:    []string{"log/2021-01-04", "ref/test/a.md"},
+				Contexts: contexts,
+			},
+		}
+	}
+
+	testNoteDAOFindPaths(t, linkTo("LINK"), []string{"f39c8.md", "log/2021-01-03.md"})
+	testNoteDAOFindPaths(t, linkTo("from 4"), []string{"f39c8.md"})
+	// All the terms must surround the same link.
+	testNoteDAOFindPaths(t, linkTo("internal", "link"), []string{"log/2021-01-03.md"})
+	testNoteDAOFindPaths(t, linkTo("internal", "duplicated"), []string{})
+	// The LIKE wildcards are matched literally.
+	testNoteDAOFindPaths(t, linkTo("internal%"), []string{})
+}
+
+func TestNoteDAOFindLinkedByWithContext(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			LinkedBy: &core.LinkFilter{
+				Hrefs:    []string{"f39c8.md"},
+				Contexts: []string{"another"},
+			},
+		},
+		[]string{"log/2021-01-03.md"},
+	)
+}
+
+func TestNoteDAOFindLinkContextRecursive(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := dao.Find(core.NoteFindOpts{
+			LinkTo: &core.LinkFilter{
+				Hrefs:     []string{"log/2021-01-04"},
+				Recursive: true,
+				Contexts:  []string{"link"},
+			},
+		})
+		assert.Err(t, err, "--link-context can't be used with --recursive")
+	})
+}
+
+func TestNoteDAOFindLinkToAll(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			LinkToAll: []string{"log/2021-01-03", "ref/test/a.md"},
+		},
+		[]string{"f39c8.md"},
+	)
+	// log/2021-01-03 links only to the first target and f39c8 only to the
+	// second one.
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			LinkToAll: []string{"log/2021-01-04", "ref/test/a.md"},
+		},
+		[]string{},
+	)
+}
+
+func TestNoteDAOFindLinkToAllUnknown(t *testing.T) {
 	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
-		opts := core.NoteFindOpts{
-			LinkedBy: &core.LinkFilter{
-				Hrefs: []string{"will-not-be-found"},
-			},
-		}
-		_, err := dao.Find(opts)
+		_, err := dao.Find(core.NoteFindOpts{
+			LinkToAll: []string{"log/2021-01-03", "will-not-be-found"},
+		})
 		assert.Err(t, err, "could not find notes at: will-not-be-found")
 	})
 }
 
-func TestNoteDAOFindNotLinkedBy(t *testing.T) {
+func TestNoteDAOFindLinkToTitles(t *testing.T) {
+	// The titles are matched regardless of their case.
 	testNoteDAOFindPaths(t,
-		core.NoteFindOpts{
-			LinkedBy: &core.LinkFilter{
-				Hrefs:     []string{"f39c8.md", "log/2021-01-03"},
-				Negate:    true,
-				Recursive: false,
-			},
-		},
-		[]string{"ref/test/ref.md", "ref/test/b.md", "f39c8.md", "log/2021-02-04.md", "index.md"},
+		core.NoteFindOpts{LinkToTitles: []string{"daily NOTE"}},
+		[]string{"f39c8.md"},
 	)
-}
-
-func TestNoteDAOFindLinkTo(t *testing.T) {
 	testNoteDAOFindPaths(t,
-		core.NoteFindOpts{
-			LinkTo: &core.LinkFilter{
-				Hrefs:     []string{"log/2021-01-04", "ref/test/a.md"},
-				Negate:    false,
-				Recursive: false,
-			},
-		},
-		[]string{"f39c8.md", "log/2021-01-03.md"},
+		core.NoteFindOpts{LinkToTitles: []string{"Daily note", "Index"}},
+		[]string{"f39c8.md", "log/2021-01-04.md"},
 	)
 }
 
+func TestNoteDAOFindLinkToAmbiguousTitle(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := tx.Exec("UPDATE notes SET title = 'Index' WHERE path = 'log/2021-01-04.md'")
+		assert.Nil(t, err)
+
+		// Every note with the title is a target.
+		notes, err := dao.Find(core.NoteFindOpts{LinkToTitles: []string{"Index"}})
+		assert.Nil(t, err)
+		paths := make([]string, 0)
+		for _, note := range notes {
+			paths = append(paths, note.Path)
+		}
+		assert.Equal(t, paths, []string{"log/2021-01-03.md", "log/2021-01-04.md"})
+	})
+}
+
+func TestNoteDAOFindLinkToUnknownTitle(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := dao.Find(core.NoteFindOpts{LinkToTitles: []string{"Unknown"}})
+		assert.Err(t, err, "could not find notes titled: Unknown")
+	})
+}
+
 func TestNoteDAOFindLinkToRecursive(t *testing.T) {
 	testNoteDAOFindPaths(t,
 		core.NoteFindOpts{
@@ -958,6 +2429,88 @@ func TestNoteDAOFindRelated(t *testing.T) {
 	)
 }
 
+func TestNoteDAOFindRelatedExcludesItselfWithCycles(t *testing.T) {
+	testNoteDAOFindPathsWithFixtures(t, "related-cycle",
+		core.NoteFindOpts{
+			Related: []string{"a.md"},
+		},
+		[]string{"d.md"},
+	)
+}
+
+func TestNoteDAOFindBacklinks(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := tx.Exec(`INSERT INTO links (source_id, target_id, title, href, type, external, rels, snippet) VALUES (5, 2, 'Nested', 'log/2021-01-04', 'wiki-link', 0, '', '')`)
+		assert.Nil(t, err)
+		// Self-links are not backlinks.
+		_, err = tx.Exec(`INSERT INTO links (source_id, target_id, title, href, type, external, rels, snippet) VALUES (6, 6, 'Self', 'ref/test/a', 'wiki-link', 0, '', '')`)
+		assert.Nil(t, err)
+
+		find := func(opts core.NoteFindOpts) map[string][]core.Backlink {
+			opts.IncludeHrefs = []string{"log/2021-01-03.md", "log/2021-01-04.md", "ref/test/a.md", "ref/test/b.md"}
+			notes, err := dao.Find(opts)
+			assert.Nil(t, err)
+
+			actual := map[string][]core.Backlink{}
+			for _, note := range notes {
+				actual[note.Path] = note.Backlinks
+			}
+			return actual
+		}
+
+		assert.Equal(t, find(core.NoteFindOpts{IncludeBacklinks: true}), map[string][]core.Backlink{
+			"log/2021-01-03.md": {{ID: 4, Path: "f39c8.md", Title: "An interesting note"}},
+			// The backlinks are ordered by title.
+			"log/2021-01-04.md": {
+				{ID: 5, Path: "ref/test/b.md", Title: "A nested note"},
+				{ID: 1, Path: "log/2021-01-03.md", Title: "Daily note"},
+			},
+			// The duplicated links are merged.
+			"ref/test/a.md": {{ID: 4, Path: "f39c8.md", Title: "An interesting note"}},
+			"ref/test/b.md": nil,
+		})
+
+		// The backlinks are only loaded on demand.
+		assert.Equal(t, find(core.NoteFindOpts{}), map[string][]core.Backlink{
+			"log/2021-01-03.md": nil,
+			"log/2021-01-04.md": nil,
+			"ref/test/a.md":     nil,
+			"ref/test/b.md":     nil,
+		})
+	})
+}
+
+func TestNoteDAOFindRelatedBridges(t *testing.T) {
+	test := func(fixtures string, related string, expected map[string][]core.RelatedBridge) {
+		testNoteDAOWithFixtures(t, fixtures, func(tx Transaction, dao *NoteDAO) {
+			notes, err := dao.Find(core.NoteFindOpts{Related: []string{related}})
+			assert.Nil(t, err)
+
+			actual := map[string][]core.RelatedBridge{}
+			for _, note := range notes {
+				actual[note.Path] = note.RelatedVia
+			}
+			assert.Equal(t, actual, expected)
+		})
+	}
+
+	// index.md is reached through log/2021-01-04.md, and links back to
+	// log/2021-01-03.md through f39c8.md.
+	test("default", "log/2021-01-03.md", map[string][]core.RelatedBridge{
+		"index.md": {
+			{ID: 2, Path: "log/2021-01-04.md", Outbound: true},
+			{ID: 4, Path: "f39c8.md", Outbound: false},
+		},
+	})
+
+	// The self-link of a.md is not a bridge.
+	test("related-cycle", "a.md", map[string][]core.RelatedBridge{
+		"d.md": {
+			{ID: 2, Path: "b.md", Outbound: true},
+		},
+	})
+}
+
 func TestNoteDAOFindOrphan(t *testing.T) {
 	testNoteDAOFindPaths(t,
 		core.NoteFindOpts{Orphan: true},
@@ -1029,26 +2582,140 @@ func TestNoteDAOFindModifiedAfter(t *testing.T) {
 	)
 }
 
+func TestNoteDAOFindCreatedAndModifiedRanges(t *testing.T) {
+	date := func(year int, month time.Month, day int) *time.Time {
+		res := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		return &res
+	}
+
+	// Notes created in 2020, and modified in the second half of November.
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			CreatedStart:  date(2020, 1, 1),
+			CreatedEnd:    date(2021, 1, 1),
+			ModifiedStart: date(2020, 11, 15),
+			ModifiedEnd:   date(2020, 12, 1),
+		},
+		[]string{"log/2021-01-03.md", "log/2021-01-04.md"},
+	)
+
+	// Each bound restricts the results further.
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			CreatedStart:  date(2020, 1, 1),
+			CreatedEnd:    date(2020, 11, 25),
+			ModifiedStart: date(2020, 1, 1),
+			ModifiedEnd:   date(2020, 12, 1),
+		},
+		[]string{"f39c8.md", "log/2021-01-03.md"},
+	)
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			CreatedStart:  date(2019, 12, 1),
+			CreatedEnd:    date(2020, 11, 25),
+			ModifiedStart: date(2019, 12, 5),
+			ModifiedEnd:   date(2020, 11, 1),
+		},
+		[]string{"f39c8.md"},
+	)
+}
+
 func TestNoteDAOFindSortCreated(t *testing.T) {
+	// Ties are broken by path.
 	testNoteDAOFindSort(t, core.NoteSortCreated, true, []string{
-		"ref/test/ref.md", "ref/test/b.md", "ref/test/a.md", "index.md", "f39c8.md",
-		"log/2021-01-03.md", "log/2021-02-04.md", "log/2021-01-04.md",
+		"ref/test/a.md", "ref/test/b.md", "ref/test/ref.md", "index.md", "f39c8.md",
+		"log/2021-01-03.md", "log/2021-01-04.md", "log/2021-02-04.md",
 	})
 	testNoteDAOFindSort(t, core.NoteSortCreated, false, []string{
-		"log/2021-02-04.md", "log/2021-01-04.md", "log/2021-01-03.md",
-		"f39c8.md", "index.md", "ref/test/ref.md", "ref/test/b.md", "ref/test/a.md",
+		"log/2021-01-04.md", "log/2021-02-04.md", "log/2021-01-03.md",
+		"f39c8.md", "index.md", "ref/test/a.md", "ref/test/b.md", "ref/test/ref.md",
 	})
 }
 
 func TestNoteDAOFindSortModified(t *testing.T) {
 	testNoteDAOFindSort(t, core.NoteSortModified, true, []string{
-		"ref/test/ref.md", "ref/test/b.md", "ref/test/a.md", "index.md", "f39c8.md",
+		"ref/test/a.md", "ref/test/b.md", "ref/test/ref.md", "index.md", "f39c8.md",
 		"log/2021-02-04.md", "log/2021-01-03.md", "log/2021-01-04.md",
 	})
 	testNoteDAOFindSort(t, core.NoteSortModified, false, []string{
 		"log/2021-01-04.md", "log/2021-01-03.md", "log/2021-02-04.md",
-		"f39c8.md", "index.md", "ref/test/ref.md", "ref/test/b.md", "ref/test/a.md",
+		"f39c8.md", "index.md", "ref/test/a.md", "ref/test/b.md", "ref/test/ref.md",
+	})
+}
+
+func TestNoteDAOFindAfterID(t *testing.T) {
+	test := func(field core.NoteSortField, ascending bool, afterID core.NoteID, expected []string) {
+		testNoteDAOFindPaths(t,
+			core.NoteFindOpts{
+				Sorters: []core.NoteSorter{{Field: field, Ascending: ascending}},
+				AfterID: afterID,
+			},
+			expected,
+		)
+	}
+
+	// The notes with the same date as the cursor are compared by path.
+	test(core.NoteSortCreated, false, 2, []string{
+		"log/2021-02-04.md", "log/2021-01-03.md",
+		"f39c8.md", "index.md", "ref/test/a.md", "ref/test/b.md", "ref/test/ref.md",
+	})
+	test(core.NoteSortCreated, false, 6, []string{"ref/test/b.md", "ref/test/ref.md"})
+	test(core.NoteSortCreated, true, 8, []string{
+		"index.md", "f39c8.md",
+		"log/2021-01-03.md", "log/2021-01-04.md", "log/2021-02-04.md",
 	})
+	test(core.NoteSortModified, true, 6, []string{
+		"ref/test/b.md", "ref/test/ref.md", "index.md", "f39c8.md",
+		"log/2021-02-04.md", "log/2021-01-03.md", "log/2021-01-04.md",
+	})
+	test(core.NoteSortModified, false, 7, []string{
+		"f39c8.md", "index.md", "ref/test/a.md", "ref/test/b.md", "ref/test/ref.md",
+	})
+	test(core.NoteSortModified, false, 8, []string{})
+
+	// The cursor applies to the union of the OR groups.
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			IncludeHrefs: []string{"log"},
+			Or:           []core.NoteFindOpts{{Tags: []string{"fantasy"}}},
+			Sorters:      []core.NoteSorter{{Field: core.NoteSortCreated, Ascending: false}},
+			AfterID:      2,
+		},
+		[]string{"log/2021-02-04.md", "log/2021-01-03.md", "f39c8.md"},
+	)
+}
+
+func TestNoteDAOFindAfterIDRequiresDateSort(t *testing.T) {
+	test := func(opts core.NoteFindOpts, expectedErr string) {
+		testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+			_, err := dao.Find(opts)
+			assert.Err(t, err, expectedErr)
+		})
+	}
+
+	created := []core.NoteSorter{{Field: core.NoteSortCreated}}
+
+	test(core.NoteFindOpts{AfterID: 1}, "--after-id requires a single --sort created or --sort modified criterion")
+	test(core.NoteFindOpts{
+		AfterID: 1,
+		Sorters: []core.NoteSorter{{Field: core.NoteSortCreated}, {Field: core.NoteSortTitle}},
+	}, "--after-id requires a single --sort created or --sort modified criterion")
+	test(core.NoteFindOpts{
+		AfterID: 1,
+		Sorters: []core.NoteSorter{{Field: core.NoteSortPath}},
+	}, "--after-id requires a single --sort created or --sort modified criterion")
+	test(core.NoteFindOpts{
+		AfterID:       1,
+		Sorters:       created,
+		Match:         []string{"daily"},
+		MatchStrategy: core.MatchStrategyFts,
+	}, "--after-id can't be used with a full-text search or --recursive, which also order the notes by relevance or distance")
+	test(core.NoteFindOpts{
+		AfterID:     1,
+		Sorters:     created,
+		PinnedPaths: []string{"index.md"},
+	}, "--after-id can't be used with --order-file")
+	test(core.NoteFindOpts{AfterID: 42, Sorters: created}, "--after-id: note 42 not found")
 }
 
 func TestNoteDAOFindSortPath(t *testing.T) {
@@ -1074,16 +2741,303 @@ func TestNoteDAOFindSortTitle(t *testing.T) {
 }
 
 func TestNoteDAOFindSortWordCount(t *testing.T) {
+	// Ties are broken by creation date, then path.
 	testNoteDAOFindSort(t, core.NoteSortWordCount, true, []string{
-		"log/2021-01-03.md", "log/2021-02-04.md", "index.md",
-		"log/2021-01-04.md", "ref/test/ref.md", "f39c8.md", "ref/test/a.md", "ref/test/b.md",
+		"log/2021-01-03.md", "index.md", "log/2021-01-04.md", "log/2021-02-04.md",
+		"ref/test/a.md", "ref/test/ref.md", "f39c8.md", "ref/test/b.md",
 	})
 	testNoteDAOFindSort(t, core.NoteSortWordCount, false, []string{
-		"ref/test/b.md", "ref/test/ref.md", "f39c8.md", "ref/test/a.md", "log/2021-02-04.md",
-		"index.md", "log/2021-01-04.md", "log/2021-01-03.md",
+		"ref/test/b.md", "ref/test/a.md", "ref/test/ref.md", "f39c8.md", "index.md",
+		"log/2021-01-04.md", "log/2021-02-04.md", "log/2021-01-03.md",
+	})
+}
+
+func TestNoteDAOFindSortTitleLength(t *testing.T) {
+	// Ties are broken by creation date, then path.
+	testNoteDAOFindSort(t, core.NoteSortTitleLength, true, []string{
+		"ref/test/ref.md", "index.md", "log/2021-01-03.md", "ref/test/b.md",
+		"log/2021-01-04.md", "log/2021-02-04.md", "ref/test/a.md", "f39c8.md",
+	})
+	testNoteDAOFindSort(t, core.NoteSortTitleLength, false, []string{
+		"ref/test/a.md", "f39c8.md", "log/2021-02-04.md", "log/2021-01-04.md",
+		"ref/test/b.md", "log/2021-01-03.md", "index.md", "ref/test/ref.md",
+	})
+}
+
+func TestNoteDAOFindSortFilename(t *testing.T) {
+	testNoteDAOFindSort(t, core.NoteSortFilename, true, []string{
+		"log/2021-01-03.md", "log/2021-01-04.md", "log/2021-02-04.md", "ref/test/a.md",
+		"ref/test/b.md", "f39c8.md", "index.md", "ref/test/ref.md",
+	})
+	testNoteDAOFindSort(t, core.NoteSortFilename, false, []string{
+		"ref/test/ref.md", "index.md", "f39c8.md", "ref/test/b.md",
+		"ref/test/a.md", "log/2021-02-04.md", "log/2021-01-04.md", "log/2021-01-03.md",
+	})
+}
+
+func TestNoteDAOFindSortLinked(t *testing.T) {
+	testNoteDAOFindSort(t, core.NoteSortLinked, false, []string{
+		"index.md", "log/2021-01-04.md", "ref/test/a.md", "log/2021-01-03.md",
+		"f39c8.md", "ref/test/b.md", "ref/test/ref.md", "log/2021-02-04.md",
+	})
+	// The notes without any backlinks are listed last in both directions.
+	testNoteDAOFindSort(t, core.NoteSortLinked, true, []string{
+		"f39c8.md", "ref/test/a.md", "log/2021-01-03.md", "log/2021-01-04.md",
+		"index.md", "ref/test/b.md", "ref/test/ref.md", "log/2021-02-04.md",
+	})
+}
+
+func TestNoteDAOFindSortMetadata(t *testing.T) {
+	test := func(ascending bool, expected []string) {
+		testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+			// Mixes actual numbers with quoted ones, which must be compared
+			// numerically, and a non-numeric string listed after them.
+			for path, metadata := range map[string]string{
+				"index.md":          `{"priority": 10}`,
+				"f39c8.md":          `{"priority": "9"}`,
+				"ref/test/a.md":     `{"priority": 2.5}`,
+				"log/2021-01-03.md": `{"priority": "high"}`,
+			} {
+				_, err := tx.Exec("UPDATE notes SET metadata = ? WHERE path = ?", metadata, path)
+				assert.Nil(t, err)
+			}
+
+			matches, err := dao.Find(core.NoteFindOpts{
+				Sorters: []core.NoteSorter{{Field: core.NoteSortMetadata, Ascending: ascending, MetadataKey: "priority"}},
+			})
+			assert.Nil(t, err)
+			actual := make([]string, 0)
+			for _, match := range matches {
+				actual = append(actual, match.Path)
+			}
+			assert.Equal(t, actual, expected)
+		})
+	}
+
+	// The notes without the key are listed last, by creation date.
+	test(true, []string{
+		"ref/test/a.md", "f39c8.md", "index.md", "log/2021-01-03.md",
+		"ref/test/b.md", "ref/test/ref.md", "log/2021-01-04.md", "log/2021-02-04.md",
+	})
+	test(false, []string{
+		"log/2021-01-03.md", "index.md", "f39c8.md", "ref/test/a.md",
+		"ref/test/b.md", "ref/test/ref.md", "log/2021-01-04.md", "log/2021-02-04.md",
+	})
+}
+
+func TestNoteDAOFindSortNullsFirst(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			Sorters: []core.NoteSorter{{Field: core.NoteSortLinked, Ascending: false, NullsFirst: true}},
+		},
+		[]string{
+			"ref/test/b.md", "ref/test/ref.md", "log/2021-02-04.md", "index.md",
+			"log/2021-01-04.md", "ref/test/a.md", "log/2021-01-03.md", "f39c8.md",
+		},
+	)
+}
+
+func TestNoteDAOCountByCreationDate(t *testing.T) {
+	test := func(opts core.NoteFindOpts, bucket core.DateBucket, expected []core.DateBucketCount) {
+		testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+			actual, err := dao.CountByCreationDate(opts, bucket)
+			assert.Nil(t, err)
+			assert.Equal(t, actual, expected)
+		})
+	}
+
+	test(core.NoteFindOpts{}, core.DateBucketYear, []core.DateBucketCount{
+		{Bucket: "2019", Count: 4},
+		{Bucket: "2020", Count: 4},
+	})
+	test(core.NoteFindOpts{}, core.DateBucketMonth, []core.DateBucketCount{
+		{Bucket: "2019-11", Count: 3},
+		{Bucket: "2019-12", Count: 1},
+		{Bucket: "2020-01", Count: 1},
+		{Bucket: "2020-11", Count: 3},
+	})
+	test(core.NoteFindOpts{}, core.DateBucketDay, []core.DateBucketCount{
+		{Bucket: "2019-11-20", Count: 3},
+		{Bucket: "2019-12-04", Count: 1},
+		{Bucket: "2020-01-19", Count: 1},
+		{Bucket: "2020-11-22", Count: 1},
+		{Bucket: "2020-11-29", Count: 2},
+	})
+
+	// The filtering options narrow the notes before bucketing.
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	test(core.NoteFindOpts{CreatedStart: &start}, core.DateBucketMonth, []core.DateBucketCount{
+		{Bucket: "2020-01", Count: 1},
+		{Bucket: "2020-11", Count: 3},
+	})
+	test(core.NoteFindOpts{Tags: []string{"adventure"}}, core.DateBucketYear, []core.DateBucketCount{
+		{Bucket: "2019", Count: 1},
+		{Bucket: "2020", Count: 1},
+	})
+}
+
+func TestNoteDAOFindPinnedPaths(t *testing.T) {
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			PinnedPaths: []string{"ref/test/b.md", "missing.md", "index.md"},
+			Sorters:     []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}},
+		},
+		[]string{"ref/test/b.md", "index.md", "f39c8.md", "log/2021-01-03.md", "log/2021-01-04.md", "log/2021-02-04.md", "ref/test/a.md", "ref/test/ref.md"},
+	)
+
+	// The pinned paths don't bypass the filters.
+	testNoteDAOFindPaths(t,
+		core.NoteFindOpts{
+			IncludeHrefs: []string{"log"},
+			PinnedPaths:  []string{"index.md", "log/2021-02-04.md"},
+		},
+		[]string{"log/2021-02-04.md", "log/2021-01-03.md", "log/2021-01-04.md"},
+	)
+}
+
+func TestNoteDAOFindSortMentions(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := tx.Exec(`UPDATE notes SET body = 'A note about notes, and other NOTES', raw_content = '# Index' || char(10) || 'A note about notes, and other NOTES' WHERE path = 'index.md'`)
+		assert.Nil(t, err)
+
+		findPaths := func(opts core.NoteFindOpts) []string {
+			opts.Sorters = []core.NoteSorter{{Field: core.NoteSortMentions, Ascending: false}}
+			opts.Limit = 2
+			matches, err := dao.Find(opts)
+			assert.Nil(t, err)
+			paths := []string{}
+			for _, m := range matches {
+				paths = append(paths, m.Path)
+			}
+			return paths
+		}
+
+		// The FTS matches are counted after stemming, in the title and body.
+		assert.Equal(t, findPaths(core.NoteFindOpts{Match: []string{"note"}, MatchStrategy: core.MatchStrategyFts}), []string{"index.md", "log/2021-01-03.md"})
+		// The exact matches are counted in the raw content, ignoring the case.
+		assert.Equal(t, findPaths(core.NoteFindOpts{Match: []string{"note"}, MatchStrategy: core.MatchStrategyExact}), []string{"index.md", "log/2021-01-03.md"})
+		assert.Equal(t, findPaths(core.NoteFindOpts{Match: []string{"notes"}, MatchStrategy: core.MatchStrategyExact}), []string{"index.md"})
+
+		// The occurrences can't be counted without a --match query.
+		for _, opts := range []core.NoteFindOpts{
+			{},
+			{Match: []string{"note"}, MatchStrategy: core.MatchStrategyRe},
+			{Match: []string{"note"}, MatchStrategy: core.MatchStrategyFts, Invert: true},
+		} {
+			opts.Sorters = []core.NoteSorter{{Field: core.NoteSortMentions, Ascending: false}}
+			_, err := dao.Find(opts)
+			assert.Err(t, err, "--sort mentions requires a --match query")
+		}
 	})
 }
 
+func TestNoteDAOFindExplain(t *testing.T) {
+	testTransaction(t, func(tx Transaction) {
+		logger := &recordingLogger{}
+		dao := NewNoteDAO(tx, logger)
+
+		paths, err := dao.FindPaths(core.NoteFindOpts{
+			PathRegex: "^log/",
+			Limit:     1,
+			Sorters:   []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}},
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, paths, []string{"log/2021-01-03.md"})
+		assert.Equal(t, len(logger.messages), 0)
+
+		paths, err = dao.FindPaths(core.NoteFindOpts{
+			PathRegex: "^log/",
+			Limit:     1,
+			Sorters:   []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}},
+			Explain:   true,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, paths, []string{"log/2021-01-03.md"})
+		assert.Equal(t, len(logger.messages), 1)
+
+		message := logger.messages[0]
+		assert.True(t, strings.Contains(message, "n.path REGEXP ?"))
+		assert.True(t, strings.Contains(message, "args:\n  1: \"^log/\"\n"))
+		assert.True(t, strings.Contains(message, "\n\nquery plan:\n  "))
+
+		// With --or and --invert, the queries of the groups are explained
+		// as well as the final one.
+		logger.messages = nil
+		paths, err = dao.FindPaths(core.NoteFindOpts{
+			PathRegex: "^log/",
+			Or:        []core.NoteFindOpts{{Tags: []string{"fantasy"}}},
+			Invert:    true,
+			Sorters:   []core.NoteSorter{{Field: core.NoteSortPath, Ascending: true}},
+			Explain:   true,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, paths, []string{"index.md", "ref/test/a.md", "ref/test/b.md", "ref/test/ref.md"})
+		assert.Equal(t, len(logger.messages), 3)
+		assert.True(t, strings.Contains(logger.messages[0], "n.path REGEXP ?"))
+		assert.True(t, strings.Contains(logger.messages[2], "n.id NOT IN ("))
+	})
+}
+
+func TestFormatQueryArgs(t *testing.T) {
+	assert.Equal(t, formatQueryArgs([]interface{}{}), "  (none)")
+	assert.Equal(t, formatQueryArgs([]interface{}{
+		"a \"quoted\" term",
+		42,
+		time.Date(2021, 1, 4, 10, 30, 0, 0, time.UTC),
+	}), "  1: \"a \\\"quoted\\\" term\"\n  2: 42\n  3: 2021-01-04T10:30:00Z")
+}
+
+func TestFindOrderTerms(t *testing.T) {
+	test := func(sorters []core.NoteSorter, expected []string) {
+		actual := findOrderTerms(sorters, 0, "", []string{"bm25()"})
+		assert.Equal(t, actual, expected)
+	}
+
+	test([]core.NoteSorter{}, []string{"bm25()", "n.title ASC", "n.path ASC"})
+	test([]core.NoteSorter{
+		{Field: core.NoteSortModified, Ascending: false},
+	}, []string{"n.modified DESC NULLS LAST", "bm25()", "n.created ASC", "n.path ASC"})
+	test([]core.NoteSorter{
+		{Field: core.NoteSortTitle, Ascending: true, NullsFirst: true},
+	}, []string{"n.title ASC NULLS FIRST", "bm25()", "n.created ASC", "n.path ASC"})
+	test([]core.NoteSorter{
+		{Field: core.NoteSortRandom, Ascending: true},
+	}, []string{"RANDOM()"})
+	test([]core.NoteSorter{
+		{Field: core.NoteSortPath, Ascending: true},
+		{Field: core.NoteSortRandom, Ascending: true},
+		{Field: core.NoteSortTitle, Ascending: true},
+	}, []string{"n.path ASC NULLS LAST", "RANDOM()"})
+
+	// A seeded shuffle is broken by path, for a reproducible order.
+	assert.Equal(t,
+		findOrderTerms([]core.NoteSorter{{Field: core.NoteSortRandom, Ascending: true}}, 42, "", []string{"bm25()"}),
+		[]string{seededRandomTerm(42), "n.path ASC"},
+	)
+}
+
+func TestNoteDAOFindSortRandomWithSeed(t *testing.T) {
+	find := func(seed int64) []string {
+		var paths []string
+		testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+			matches, err := dao.Find(core.NoteFindOpts{
+				Sorters:    []core.NoteSorter{{Field: core.NoteSortRandom, Ascending: true}},
+				RandomSeed: seed,
+			})
+			assert.Nil(t, err)
+			for _, m := range matches {
+				paths = append(paths, m.Path)
+			}
+		})
+		return paths
+	}
+
+	assert.Equal(t, find(42), find(42))
+	assert.Equal(t, find(-42), find(-42))
+	assert.NotEqual(t, find(42), find(43))
+	assert.Equal(t, len(find(42)), 8)
+}
+
 func testNoteDAOFindSort(t *testing.T, field core.NoteSortField, ascending bool, expected []string) {
 	testNoteDAOFindPaths(t,
 		core.NoteFindOpts{
@@ -1094,7 +3048,11 @@ func testNoteDAOFindSort(t *testing.T, field core.NoteSortField, ascending bool,
 }
 
 func testNoteDAOFindPaths(t *testing.T, opts core.NoteFindOpts, expected []string) {
-	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+	testNoteDAOFindPathsWithFixtures(t, "default", opts, expected)
+}
+
+func testNoteDAOFindPathsWithFixtures(t *testing.T, fixtures string, opts core.NoteFindOpts, expected []string) {
+	testNoteDAOWithFixtures(t, fixtures, func(tx Transaction, dao *NoteDAO) {
 		matches, err := dao.Find(opts)
 		assert.Nil(t, err)
 
@@ -1106,6 +3064,19 @@ func testNoteDAOFindPaths(t *testing.T, opts core.NoteFindOpts, expected []strin
 	})
 }
 
+func testNoteDAOFindSnippets(t *testing.T, opts core.NoteFindOpts, expected [][]string) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		matches, err := dao.Find(opts)
+		assert.Nil(t, err)
+
+		actual := make([][]string, 0)
+		for _, m := range matches {
+			actual = append(actual, m.Snippets)
+		}
+		assert.Equal(t, actual, expected)
+	})
+}
+
 func testNoteDAOFind(t *testing.T, opts core.NoteFindOpts, expected []core.ContextualNote) {
 	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
 		actual, err := dao.Find(opts)
@@ -1114,6 +3085,38 @@ func testNoteDAOFind(t *testing.T, opts core.NoteFindOpts, expected []core.Conte
 	})
 }
 
+func TestNoteDAOCheckIntegrity(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		actual, err := dao.CheckIntegrity()
+		assert.Nil(t, err)
+		assert.Equal(t, actual, []string{
+			"index.md: link to missing does not resolve to any note",
+		})
+	})
+}
+
+func TestNoteDAOCheckIntegrityFindsDanglingReferences(t *testing.T) {
+	testNoteDAO(t, func(tx Transaction, dao *NoteDAO) {
+		_, err := tx.Exec(`PRAGMA defer_foreign_keys = ON`)
+		assert.Nil(t, err)
+		_, err = tx.Exec(`
+			INSERT INTO links (source_id, target_id, title, href, type, external, rels, snippet, snippet_start, snippet_end)
+			VALUES (5, 99, 'Gone', 'gone', 'markdown', 0, '', '', 0, 0)
+		`)
+		assert.Nil(t, err)
+		_, err = tx.Exec(`INSERT INTO notes_collections (note_id, collection_id) VALUES (2, 99)`)
+		assert.Nil(t, err)
+
+		actual, err := dao.CheckIntegrity()
+		assert.Nil(t, err)
+		assert.Equal(t, actual, []string{
+			"ref/test/b.md: link to gone points to a missing note",
+			"log/2021-01-04.md: associated with the missing collection #99",
+			"index.md: link to missing does not resolve to any note",
+		})
+	})
+}
+
 func testNoteDAO(t *testing.T, callback func(tx Transaction, dao *NoteDAO)) {
 	testTransaction(t, func(tx Transaction) {
 		callback(tx, NewNoteDAO(tx, &util.NullLogger))
@@ -1126,6 +3129,25 @@ func testNoteDAOWithFixtures(t *testing.T, fixtures string, callback func(tx Tra
 	})
 }
 
+// recordingLogger keeps the messages logged by a DAO.
+type recordingLogger struct {
+	messages []string
+}
+
+func (l *recordingLogger) Printf(format string, v ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, v...))
+}
+
+func (l *recordingLogger) Println(v ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintln(v...))
+}
+
+func (l *recordingLogger) Err(err error) {
+	if err != nil {
+		l.messages = append(l.messages, err.Error())
+	}
+}
+
 type noteRow struct {
 	Path, Title, Lead, Body, RawContent, Checksum, Metadata string
 	WordCount                                               int
@@ -4,6 +4,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/zk-org/zk/internal/core"
 	"github.com/zk-org/zk/internal/util"
@@ -54,6 +55,33 @@ func (ni *NoteIndex) FindMinimal(opts core.NoteFindOpts) (notes []core.MinimalNo
 	return
 }
 
+// FindPaths implements core.NoteIndex.
+func (ni *NoteIndex) FindPaths(opts core.NoteFindOpts) (paths []string, err error) {
+	err = ni.commit(func(dao *dao) error {
+		paths, err = dao.notes.FindPaths(opts)
+		return err
+	})
+	return
+}
+
+// CountByCreationDate implements core.NoteIndex.
+func (ni *NoteIndex) CountByCreationDate(opts core.NoteFindOpts, bucket core.DateBucket) (counts []core.DateBucketCount, err error) {
+	err = ni.commit(func(dao *dao) error {
+		counts, err = dao.notes.CountByCreationDate(opts, bucket)
+		return err
+	})
+	return
+}
+
+// CheckIntegrity implements core.NoteIndex.
+func (ni *NoteIndex) CheckIntegrity() (problems []string, err error) {
+	err = ni.commit(func(dao *dao) error {
+		problems, err = dao.notes.CheckIntegrity()
+		return err
+	})
+	return
+}
+
 // FindLinkMatch implements core.NoteIndex.
 func (ni *NoteIndex) FindLinkMatch(baseDir string, href string, linkType core.LinkType) (id core.NoteID, err error) {
 	err = ni.commit(func(dao *dao) error {
@@ -94,6 +122,42 @@ func (ni *NoteIndex) FindLinksBetweenNotes(ids []core.NoteID) (links []core.Reso
 	return
 }
 
+// FindBacklinks implements core.NoteIndex.
+func (ni *NoteIndex) FindBacklinks(id core.NoteID) (links []core.ResolvedLink, err error) {
+	err = ni.commit(func(dao *dao) error {
+		links, err = dao.links.FindByTarget(id)
+		return err
+	})
+	return
+}
+
+// FindOutboundLinks implements core.NoteIndex.
+func (ni *NoteIndex) FindOutboundLinks(id core.NoteID) (links []core.ResolvedLink, err error) {
+	err = ni.commit(func(dao *dao) error {
+		links, err = dao.links.FindBySource(id)
+		return err
+	})
+	return
+}
+
+// CountBacklinks implements core.NoteIndex.
+func (ni *NoteIndex) CountBacklinks() (counts map[string]int, err error) {
+	err = ni.commit(func(dao *dao) error {
+		counts, err = dao.links.CountBacklinks()
+		return err
+	})
+	return
+}
+
+// FindUnresolvedLinks implements core.NoteIndex.
+func (ni *NoteIndex) FindUnresolvedLinks() (links []core.ResolvedLink, err error) {
+	err = ni.commit(func(dao *dao) error {
+		links, err = dao.links.FindUnresolved()
+		return err
+	})
+	return
+}
+
 // FindCollections implements core.NoteIndex.
 func (ni *NoteIndex) FindCollections(kind core.CollectionKind, sorters []core.CollectionSorter) (collections []core.Collection, err error) {
 	err = ni.commit(func(dao *dao) error {
@@ -113,6 +177,16 @@ func (ni *NoteIndex) IndexedPaths() (metadata <-chan paths.Metadata, err error)
 	return
 }
 
+// IndexedPathsSince implements core.NoteIndex.
+func (ni *NoteIndex) IndexedPathsSince(t time.Time) (metadata <-chan paths.Metadata, err error) {
+	err = ni.commit(func(dao *dao) error {
+		metadata, err = dao.notes.IndexedSince(t)
+		return err
+	})
+	err = errors.Wrap(err, "failed to get indexed notes")
+	return
+}
+
 // Add implements core.NoteIndex.
 func (ni *NoteIndex) Add(note core.Note) (id core.NoteID, err error) {
 	err = ni.commit(func(dao *dao) error {
@@ -132,7 +206,7 @@ func (ni *NoteIndex) Add(note core.Note) (id core.NoteID, err error) {
 			return err
 		}
 
-		return ni.associateTags(dao.collections, id, note.Tags)
+		return ni.associateCollections(dao.collections, id, note)
 	})
 
 	err = errors.Wrapf(err, "%v: failed to index the note", note.Path)
@@ -166,6 +240,37 @@ func (ni *NoteIndex) fixExistingLinks(dao *dao, id core.NoteID, path string) err
 	return nil
 }
 
+// RebuildLinkTargets implements core.NoteIndex.
+//
+// The links are resolved the same way as when indexing their source note, so
+// running it again without changing the notes has no effect.
+func (ni *NoteIndex) RebuildLinkTargets() error {
+	err := ni.commit(func(dao *dao) error {
+		links, err := dao.links.FindInternal()
+		if err != nil {
+			return err
+		}
+
+		for _, link := range links {
+			targetID, err := ni.findLinkMatch(dao, "" /* base dir */, link.Href, link.Type)
+			if err != nil {
+				return err
+			}
+			if targetID == link.TargetID {
+				continue
+			}
+			err = dao.links.SetTargetID(link.ID, targetID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return errors.Wrap(err, "failed to resolve the links")
+}
+
 // linkMatchesPath returns whether the given link can be used to reach the
 // given note path.
 func (ni *NoteIndex) linkMatchesPath(link core.ResolvedLink, path string) (bool, error) {
@@ -240,19 +345,27 @@ func (ni *NoteIndex) Update(note core.Note) error {
 		if err != nil {
 			return err
 		}
-		return ni.associateTags(dao.collections, id, note.Tags)
+		return ni.associateCollections(dao.collections, id, note)
 	})
 
 	return errors.Wrapf(err, "%v: failed to update note index", note.Path)
 }
 
-func (ni *NoteIndex) associateTags(collections *CollectionDAO, noteId core.NoteID, tags []string) error {
-	for _, tag := range tags {
-		tagId, err := collections.FindOrCreate(core.CollectionKindTag, tag)
+func (ni *NoteIndex) associateCollections(collections *CollectionDAO, noteId core.NoteID, note core.Note) error {
+	err := ni.associateCollectionsOfKind(collections, noteId, core.CollectionKindTag, note.Tags)
+	if err != nil {
+		return err
+	}
+	return ni.associateCollectionsOfKind(collections, noteId, core.CollectionKindAlias, note.Aliases)
+}
+
+func (ni *NoteIndex) associateCollectionsOfKind(collections *CollectionDAO, noteId core.NoteID, kind core.CollectionKind, names []string) error {
+	for _, name := range names {
+		collectionId, err := collections.FindOrCreate(kind, name)
 		if err != nil {
 			return err
 		}
-		_, err = collections.Associate(noteId, tagId)
+		_, err = collections.Associate(noteId, collectionId)
 		if err != nil {
 			return err
 		}
@@ -296,6 +409,14 @@ func (ni *NoteIndex) Remove(path string) error {
 	return errors.Wrapf(err, "%v: failed to remove note from index", path)
 }
 
+// Touch implements core.NoteIndex.
+func (ni *NoteIndex) Touch(path string, modified time.Time) error {
+	err := ni.commit(func(dao *dao) error {
+		return dao.notes.Touch(path, modified)
+	})
+	return errors.Wrapf(err, "%v: failed to touch note in the index", path)
+}
+
 // Commit implements core.NoteIndex.
 func (ni *NoteIndex) Commit(transaction func(idx core.NoteIndex) error) error {
 	return ni.commit(func(dao *dao) error {
@@ -329,6 +450,46 @@ func (ni *NoteIndex) SetNeedsReindexing(needsReindexing bool) error {
 	})
 }
 
+// LastListedAt implements core.NoteIndex.
+func (ni *NoteIndex) LastListedAt() (lastListedAt time.Time, err error) {
+	err = ni.commit(func(dao *dao) error {
+		res, err := dao.metadata.Get(lastListedAtKey)
+		if err != nil || res == "" {
+			return err
+		}
+		lastListedAt, err = time.Parse(time.RFC3339Nano, res)
+		return errors.Wrapf(err, "invalid %s metadata: %s", lastListedAtKey, res)
+	})
+	return
+}
+
+// SetLastListedAt implements core.NoteIndex.
+func (ni *NoteIndex) SetLastListedAt(t time.Time) error {
+	return ni.commit(func(dao *dao) error {
+		return dao.metadata.Set(lastListedAtKey, t.UTC().Format(time.RFC3339Nano))
+	})
+}
+
+// LastIndexedAt implements core.NoteIndex.
+func (ni *NoteIndex) LastIndexedAt() (lastIndexedAt time.Time, err error) {
+	err = ni.commit(func(dao *dao) error {
+		res, err := dao.metadata.Get(lastIndexedAtKey)
+		if err != nil || res == "" {
+			return err
+		}
+		lastIndexedAt, err = time.Parse(time.RFC3339Nano, res)
+		return errors.Wrapf(err, "invalid %s metadata: %s", lastIndexedAtKey, res)
+	})
+	return
+}
+
+// SetLastIndexedAt implements core.NoteIndex.
+func (ni *NoteIndex) SetLastIndexedAt(t time.Time) error {
+	return ni.commit(func(dao *dao) error {
+		return dao.metadata.Set(lastIndexedAtKey, t.UTC().Format(time.RFC3339Nano))
+	})
+}
+
 func (ni *NoteIndex) commit(transaction func(dao *dao) error) error {
 	if ni.dao != nil {
 		return transaction(ni.dao)
@@ -3,6 +3,7 @@ package sqlite
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/zk-org/zk/internal/core"
 	"github.com/zk-org/zk/internal/util"
@@ -113,6 +114,53 @@ func TestNoteIndexAddFillsLinksMissingTargetId(t *testing.T) {
 	})
 }
 
+func TestNoteIndexRebuildLinkTargets(t *testing.T) {
+	db, index := testNoteIndex(t)
+
+	// A stale target, and a note added without fixing the existing links.
+	_, err := db.db.Exec("UPDATE links SET target_id = 5 WHERE id = 2")
+	assert.Nil(t, err)
+	res, err := db.db.Exec(`
+		INSERT INTO notes (path, sortable_path, title, lead, body, raw_content, word_count, metadata, checksum, created, modified)
+		VALUES ('missing.md', 'missing.md', 'Missing', '', '', '', 0, '{}', '', '2020-01-01', '2020-01-01')
+	`)
+	assert.Nil(t, err)
+	missingID, err := res.LastInsertId()
+	assert.Nil(t, err)
+
+	// Resolving twice is idempotent.
+	for i := 0; i < 2; i++ {
+		err = index.RebuildLinkTargets()
+		assert.Nil(t, err)
+
+		rows := queryLinkRows(t, db.db, "id IN (1, 2, 3)")
+		assert.Equal(t, rows, []linkRow{
+			{
+				SourceId: 3,
+				TargetId: idPointer(missingID),
+				Title:    "Missing target",
+				Href:     "missing",
+				Snippet:  "There's a Missing target",
+			},
+			{
+				SourceId: 1,
+				TargetId: idPointer(2),
+				Title:    "An internal link",
+				Href:     "log/2021-01-04.md",
+				Snippet:  "[[An internal link]]",
+			},
+			{
+				SourceId:   1,
+				TargetId:   nil,
+				Title:      "An external link",
+				Href:       "https://domain.com",
+				IsExternal: true,
+				Snippet:    "[[An external link]]",
+			},
+		})
+	}
+}
+
 func TestNoteIndexUpdateWithLinks(t *testing.T) {
 	db, index := testNoteIndex(t)
 
@@ -218,6 +266,79 @@ func TestNoteIndexUpdateWithTags(t *testing.T) {
 	assertSQL(true)
 }
 
+func TestNoteIndexUpdateWithAliases(t *testing.T) {
+	_, index := testNoteIndex(t)
+
+	findPaths := func(aliases ...string) []string {
+		notes, err := index.FindMinimal(core.NoteFindOpts{Aliases: aliases})
+		assert.Nil(t, err)
+		paths := []string{}
+		for _, note := range notes {
+			paths = append(paths, note.Path)
+		}
+		return paths
+	}
+
+	err := index.Update(core.Note{
+		Path:    "log/2021-01-03.md",
+		Tags:    []string{"fiction"},
+		Aliases: []string{"Daily log", "Monday"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, findPaths("Monday"), []string{"log/2021-01-03.md"})
+	assert.Equal(t, findPaths("Unknown", "Daily log"), []string{"log/2021-01-03.md"})
+	// The aliases are matched exactly.
+	assert.Equal(t, findPaths("monday"), []string{})
+
+	// The aliases are not tags.
+	notes, err := index.FindMinimal(core.NoteFindOpts{Tags: []string{"Monday"}})
+	assert.Nil(t, err)
+	assert.Equal(t, len(notes), 0)
+
+	// Removed aliases don't match anymore.
+	err = index.Update(core.Note{
+		Path:    "log/2021-01-03.md",
+		Aliases: []string{"Daily log"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, findPaths("Monday"), []string{})
+	assert.Equal(t, findPaths("Daily log"), []string{"log/2021-01-03.md"})
+}
+
+func TestNoteIndexLastListedAt(t *testing.T) {
+	_, index := testNoteIndex(t)
+
+	// The notes were never listed with --new-since-last.
+	lastListedAt, err := index.LastListedAt()
+	assert.Nil(t, err)
+	assert.True(t, lastListedAt.IsZero())
+
+	listedAt := time.Date(2021, 3, 4, 10, 20, 30, 456, time.FixedZone("", 3600))
+	err = index.SetLastListedAt(listedAt)
+	assert.Nil(t, err)
+
+	lastListedAt, err = index.LastListedAt()
+	assert.Nil(t, err)
+	assert.Equal(t, lastListedAt, time.Date(2021, 3, 4, 9, 20, 30, 456, time.UTC))
+}
+
+func TestNoteIndexLastIndexedAt(t *testing.T) {
+	_, index := testNoteIndex(t)
+
+	// The notebook was never indexed.
+	lastIndexedAt, err := index.LastIndexedAt()
+	assert.Nil(t, err)
+	assert.True(t, lastIndexedAt.IsZero())
+
+	indexedAt := time.Date(2021, 3, 4, 10, 20, 30, 456, time.FixedZone("", 3600))
+	err = index.SetLastIndexedAt(indexedAt)
+	assert.Nil(t, err)
+
+	lastIndexedAt, err = index.LastIndexedAt()
+	assert.Nil(t, err)
+	assert.Equal(t, lastIndexedAt, time.Date(2021, 3, 4, 9, 20, 30, 456, time.UTC))
+}
+
 func testNoteIndex(t *testing.T) (*DB, *NoteIndex) {
 	db := testDB(t)
 	return db, NewNoteIndex("", db, &util.NullLogger)
@@ -0,0 +1,235 @@
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zk-org/zk/internal/core"
+)
+
+// parseTagQuery converts a boolean tag expression into a SQL condition on
+// the notes, with its arguments.
+//
+// The expression combines tags with AND, OR (or |) and NOT (or a - prefix),
+// and can group them with parentheses, e.g. (fiction | history) AND -draft.
+// NOT has the highest precedence, followed by AND and then OR. Tags are
+// matched as glob patterns, after stripping the given prefix characters.
+//
+// An empty condition is returned for a blank expression.
+func parseTagQuery(query string, prefixes string) (string, []interface{}, error) {
+	parser := tagQueryParser{tokens: lexTagQuery(query), prefixes: prefixes}
+	if len(parser.tokens) == 0 {
+		return "", nil, nil
+	}
+
+	expr, err := parser.parseOr()
+	if err == nil && parser.pos < len(parser.tokens) {
+		err = fmt.Errorf("unexpected %s", parser.tokens[parser.pos].text)
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid tag expression %q: %w", query, err)
+	}
+	return expr, parser.args, nil
+}
+
+type tagTokenKind int
+
+const (
+	tagTokenTag tagTokenKind = iota + 1
+	tagTokenAnd
+	tagTokenOr
+	tagTokenNot
+	tagTokenOpen
+	tagTokenClose
+)
+
+type tagToken struct {
+	kind tagTokenKind
+	text string
+	// Offset of the token in the query.
+	start int
+}
+
+// lexTagQuery splits a tag expression into tokens.
+//
+// Consecutive words which are not operators are merged into a single tag, to
+// support multi-word tags.
+func lexTagQuery(query string) []tagToken {
+	tokens := make([]tagToken, 0)
+
+	// Indicates whether the next token starts a new term, which is where a
+	// negation prefix is allowed.
+	atTermStart := func() bool {
+		if len(tokens) == 0 {
+			return true
+		}
+		switch tokens[len(tokens)-1].kind {
+		case tagTokenTag, tagTokenClose:
+			return false
+		default:
+			return true
+		}
+	}
+
+	isSeparator := func(c byte) bool {
+		return strings.IndexByte(" \t\n()|", c) >= 0
+	}
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(':
+			tokens = append(tokens, tagToken{kind: tagTokenOpen, text: "(", start: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, tagToken{kind: tagTokenClose, text: ")", start: i})
+			i++
+		case c == '|':
+			tokens = append(tokens, tagToken{kind: tagTokenOr, text: "|", start: i})
+			i++
+		// - is an alias to NOT, but only at the start of a term, to allow
+		// compound tags such as "well-known".
+		case c == '-' && atTermStart():
+			tokens = append(tokens, tagToken{kind: tagTokenNot, text: "-", start: i})
+			i++
+
+		default:
+			end := i
+			for end < len(query) && !isSeparator(query[end]) {
+				end++
+			}
+			word := query[i:end]
+
+			switch {
+			case word == "AND":
+				tokens = append(tokens, tagToken{kind: tagTokenAnd, text: word, start: i})
+			case word == "OR":
+				tokens = append(tokens, tagToken{kind: tagTokenOr, text: word, start: i})
+			case word == "NOT":
+				tokens = append(tokens, tagToken{kind: tagTokenNot, text: word, start: i})
+			// For backward compatibility, NOT can be glued to the negated tag.
+			case strings.HasPrefix(word, "NOT") && atTermStart():
+				tokens = append(tokens,
+					tagToken{kind: tagTokenNot, text: "NOT", start: i},
+					tagToken{kind: tagTokenTag, text: word[3:], start: i + 3},
+				)
+			case !atTermStart() && tokens[len(tokens)-1].kind == tagTokenTag:
+				last := &tokens[len(tokens)-1]
+				last.text = query[last.start:end]
+			default:
+				tokens = append(tokens, tagToken{kind: tagTokenTag, text: word, start: i})
+			}
+			i = end
+		}
+	}
+
+	return tokens
+}
+
+// tagQueryParser is a recursive descent parser building the SQL condition of
+// a tag expression.
+type tagQueryParser struct {
+	tokens []tagToken
+	pos    int
+	args   []interface{}
+	// Characters stripped from the start of the tags.
+	prefixes string
+}
+
+func (p *tagQueryParser) peek() tagTokenKind {
+	if p.pos >= len(p.tokens) {
+		return 0
+	}
+	return p.tokens[p.pos].kind
+}
+
+func (p *tagQueryParser) parseOr() (string, error) {
+	return p.parseBinary(tagTokenOr, " OR ", p.parseAnd)
+}
+
+func (p *tagQueryParser) parseAnd() (string, error) {
+	return p.parseBinary(tagTokenAnd, " AND ", p.parseUnary)
+}
+
+// parseBinary parses a sequence of operands separated by the given operator.
+func (p *tagQueryParser) parseBinary(op tagTokenKind, sqlOp string, parseOperand func() (string, error)) (string, error) {
+	operand, err := parseOperand()
+	if err != nil {
+		return "", err
+	}
+	operands := []string{operand}
+
+	for p.peek() == op {
+		p.pos++
+		operand, err := parseOperand()
+		if err != nil {
+			return "", err
+		}
+		operands = append(operands, operand)
+	}
+
+	if len(operands) == 1 {
+		return operands[0], nil
+	}
+	return "(" + strings.Join(operands, sqlOp) + ")", nil
+}
+
+func (p *tagQueryParser) parseUnary() (string, error) {
+	if p.pos >= len(p.tokens) {
+		return "", fmt.Errorf("unexpected end of expression")
+	}
+
+	token := p.tokens[p.pos]
+	p.pos++
+
+	switch token.kind {
+	case tagTokenNot:
+		expr, err := p.parseUnary()
+		if err != nil {
+			return "", err
+		}
+		return "NOT " + expr, nil
+
+	case tagTokenOpen:
+		expr, err := p.parseOr()
+		if err != nil {
+			return "", err
+		}
+		if p.peek() != tagTokenClose {
+			return "", fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return expr, nil
+
+	case tagTokenTag:
+		return p.tagCondition(token.text), nil
+
+	default:
+		return "", fmt.Errorf("unexpected %s", token.text)
+	}
+}
+
+// tagCondition returns the SQL condition matching the notes having a tag
+// matching the given glob pattern.
+func (p *tagQueryParser) tagCondition(tag string) string {
+	if trimmed := strings.TrimLeft(tag, p.prefixes); trimmed != "" {
+		tag = trimmed
+	}
+
+	cond := "t.name GLOB ?"
+	arg := tag
+	if strings.ContainsAny(tag, "*?[") {
+		cond = "t.name REGEXP ?"
+		arg = tagGlobToRegex(tag)
+	}
+	p.args = append(p.args, arg)
+
+	return fmt.Sprintf(`n.id IN (
+SELECT note_id FROM notes_collections
+WHERE collection_id IN (SELECT id FROM collections t WHERE kind = '%s' AND %s)
+)`,
+		core.CollectionKindTag, cond,
+	)
+}
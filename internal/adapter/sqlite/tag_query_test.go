@@ -0,0 +1,76 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/zk-org/zk/internal/util/test/assert"
+)
+
+func TestLexTagQuery(t *testing.T) {
+	test := func(query string, expected []string) {
+		tokens := lexTagQuery(query)
+		actual := make([]string, 0)
+		for _, token := range tokens {
+			actual = append(actual, token.text)
+		}
+		assert.Equal(t, actual, expected)
+	}
+
+	test("", []string{})
+	test("   ", []string{})
+	test("fiction", []string{"fiction"})
+	test(" science  fiction ", []string{"science  fiction"})
+	test("a|b OR c AND d", []string{"a", "|", "b", "OR", "c", "AND", "d"})
+	test("(a OR b) AND c", []string{"(", "a", "OR", "b", ")", "AND", "c"})
+	test("-done", []string{"-", "done"})
+	test("well-known", []string{"well-known"})
+	test("NOT done", []string{"NOT", "done"})
+	test("NOTdone", []string{"NOT", "done"})
+	test("NOT -(a)", []string{"NOT", "-", "(", "a", ")"})
+	test("year/201*", []string{"year/201*"})
+}
+
+func TestParseTagQuery(t *testing.T) {
+	test := func(query string, expectedArgs []interface{}) {
+		_, args, err := parseTagQuery(query, "")
+		assert.Nil(t, err)
+		assert.Equal(t, args, expectedArgs)
+	}
+
+	test("", nil)
+	test("fiction", []interface{}{"fiction"})
+	test("(fiction | history) AND -draft", []interface{}{"fiction", "history", "draft"})
+	test("project/**", []interface{}{"^project/.*$"})
+}
+
+func TestParseTagQueryStripsPrefixes(t *testing.T) {
+	test := func(query string, prefixes string, expectedArgs []interface{}) {
+		_, args, err := parseTagQuery(query, prefixes)
+		assert.Nil(t, err)
+		assert.Equal(t, args, expectedArgs)
+	}
+
+	test("#fiction", "", []interface{}{"#fiction"})
+	test("#fiction", "#", []interface{}{"fiction"})
+	test("#fiction | @history AND -#draft", "#@", []interface{}{"fiction", "history", "draft"})
+	test("NOT ##science fiction", "#", []interface{}{"science fiction"})
+	test("#project/**", "#", []interface{}{"^project/.*$"})
+	// Only the start of the tags is stripped.
+	test("c#", "#", []interface{}{"c#"})
+	// A tag made only of prefixes is kept as-is.
+	test("#", "#", []interface{}{"#"})
+}
+
+func TestParseTagQueryRejectsInvalidExpressions(t *testing.T) {
+	test := func(query string, expected string) {
+		_, _, err := parseTagQuery(query, "")
+		assert.Err(t, err, expected)
+	}
+
+	test("(fiction", `invalid tag expression "(fiction": missing closing parenthesis`)
+	test("fiction)", `invalid tag expression "fiction)": unexpected )`)
+	test("fiction AND", `invalid tag expression "fiction AND": unexpected end of expression`)
+	test("| fiction", `invalid tag expression "| fiction": unexpected |`)
+	test("fiction NOT history", `invalid tag expression "fiction NOT history": unexpected NOT`)
+	test("()", `invalid tag expression "()": unexpected )`)
+}
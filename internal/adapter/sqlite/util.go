@@ -30,6 +30,11 @@ func escapeLikeTerm(term string, escapeChar rune) string {
 	return escape(escape(escape(term, string(escapeChar)), "%"), "_")
 }
 
+// quoteSQLString returns the given string as a SQL string literal.
+func quoteSQLString(str string) string {
+	return "'" + strings.ReplaceAll(str, "'", "''") + "'"
+}
+
 func linkIDToSQL(id core.LinkID) sql.NullInt64 {
 	if id.IsValid() {
 		return sql.NullInt64{Int64: int64(id), Valid: true}
@@ -15,3 +15,13 @@ func TestEscapeLikeTerm(t *testing.T) {
 	test("foo%bar_with@", '@', "foo@%bar@_with@@")
 	test(`foo%bar_with\`, '\\', `foo\%bar\_with\\`)
 }
+
+func TestQuoteSQLString(t *testing.T) {
+	test := func(str string, expected string) {
+		assert.Equal(t, quoteSQLString(str), expected)
+	}
+
+	test("", "''")
+	test("<zk:match>", "'<zk:match>'")
+	test("it's", "'it''s'")
+}
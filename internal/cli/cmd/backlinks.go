@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/zk-org/zk/internal/cli"
+	"github.com/zk-org/zk/internal/core"
+	"github.com/zk-org/zk/internal/util/strings"
+)
+
+// Backlinks lists the notes linking to a given note.
+type Backlinks struct {
+	Note    string `arg help:"Path to the note whose backlinks are listed, a partial path is accepted."`
+	NoPager bool   `group:format short:P help:"Do not pipe output into a pager."`
+	Quiet   bool   `group:format short:q help:"Do not print the total number of backlinks found."`
+}
+
+func (cmd *Backlinks) Run(container *cli.Container) error {
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	target, err := notebook.FindByHref(cmd.Note, true /* allowPartialHref */)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return fmt.Errorf("could not find a note at: %s", cmd.Note)
+	}
+
+	links, err := notebook.FindBacklinks(target.ID)
+	if err != nil {
+		return err
+	}
+
+	titles, err := cmd.sourceTitles(notebook, links)
+	if err != nil {
+		return err
+	}
+
+	count := len(links)
+	if count > 0 {
+		err = container.Paginate(cmd.NoPager, func(out io.Writer) error {
+			var lastSourceID core.NoteID
+			for _, link := range links {
+				if link.SourceID != lastSourceID {
+					if lastSourceID.IsValid() {
+						fmt.Fprintln(out)
+					}
+					if title := titles[link.SourceID]; title != "" {
+						fmt.Fprintf(out, "%s (%s)\n", title, link.SourcePath)
+					} else {
+						fmt.Fprintln(out, link.SourcePath)
+					}
+					lastSourceID = link.SourceID
+				}
+
+				snippet := link.Snippet
+				if snippet == "" {
+					snippet = link.Title
+				}
+				fmt.Fprintf(out, "  %s\n", snippet)
+			}
+			return nil
+		})
+	}
+
+	if err == nil && !cmd.Quiet {
+		fmt.Fprintf(os.Stderr, "\nFound %d %s\n", count, strings.Pluralize("backlink", count))
+	}
+
+	return err
+}
+
+// sourceTitles returns the titles of the source notes of the given links,
+// indexed by their ID.
+func (cmd *Backlinks) sourceTitles(notebook *core.Notebook, links []core.ResolvedLink) (map[core.NoteID]string, error) {
+	titles := map[core.NoteID]string{}
+	if len(links) == 0 {
+		return titles, nil
+	}
+
+	ids := make([]core.NoteID, 0)
+	for _, link := range links {
+		ids = append(ids, link.SourceID)
+	}
+
+	notes, err := notebook.FindMinimalNotes(core.NoteFindOpts{IncludeIDs: ids})
+	if err != nil {
+		return titles, err
+	}
+	for _, note := range notes {
+		titles[note.ID] = note.Title
+	}
+	return titles, nil
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/zk-org/zk/internal/cli"
+	strutil "github.com/zk-org/zk/internal/util/strings"
+)
+
+// Doctor checks the notebook index for inconsistencies.
+type Doctor struct{}
+
+func (cmd *Doctor) Help() string {
+	return "Dangling references are fixed by `zk index --force`, unresolved links in the notes themselves."
+}
+
+func (cmd *Doctor) Run(container *cli.Container) error {
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	problems, err := notebook.CheckIntegrity()
+	if err != nil {
+		return err
+	}
+
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+
+	if count := len(problems); count > 0 {
+		return fmt.Errorf("found %d %s in the index", count, strutil.Pluralize("problem", count))
+	}
+	return nil
+}
@@ -7,15 +7,19 @@ import (
 
 	"github.com/zk-org/zk/internal/cli"
 	"github.com/zk-org/zk/internal/core"
+	"github.com/zk-org/zk/internal/util/errors"
 	"github.com/zk-org/zk/internal/util/paths"
+	strutil "github.com/zk-org/zk/internal/util/strings"
 	"github.com/schollz/progressbar/v3"
 )
 
 // Index indexes the content of all the notes in the notebook.
 type Index struct {
-	Force   bool `short:"f" help:"Force indexing all the notes."`
-	Verbose bool `short:"v" xor:"print" help:"Print detailed information about the indexing process."`
-	Quiet   bool `short:"q" xor:"print" help:"Do not print statistics nor progress."`
+	Force     bool `short:"f" help:"Force indexing all the notes."`
+	LinksOnly bool `help:"Only resolve the links between the indexed notes again, without reindexing their content."`
+	Strict    bool `help:"Fail when internal links don't point to any note after indexing, and list them."`
+	Verbose   bool `short:"v" xor:"print" help:"Print detailed information about the indexing process."`
+	Quiet     bool `short:"q" xor:"print" help:"Do not print statistics nor progress."`
 }
 
 func (cmd *Index) Help() string {
@@ -28,7 +32,40 @@ func (cmd *Index) Run(container *cli.Container) error {
 		return err
 	}
 
-	return cmd.RunWithNotebook(container, notebook)
+	if cmd.LinksOnly {
+		if cmd.Force {
+			return errors.New("--links-only can't be used with --force")
+		}
+		err = notebook.RebuildLinkTargets()
+	} else {
+		err = cmd.RunWithNotebook(container, notebook)
+	}
+	if err != nil || !cmd.Strict {
+		return err
+	}
+
+	return checkUnresolvedLinks(notebook)
+}
+
+// checkUnresolvedLinks prints the internal links which don't point to any
+// note, and fails if there are any.
+//
+// This is only done after a complete indexing, as links to notes which are
+// not indexed yet are resolved at the end of the process.
+func checkUnresolvedLinks(notebook *core.Notebook) error {
+	links, err := notebook.FindUnresolvedLinks()
+	if err != nil {
+		return err
+	}
+
+	for _, link := range links {
+		fmt.Fprintf(os.Stderr, "%s: link to %s does not resolve to any note\n", link.SourcePath, link.Href)
+	}
+
+	if count := len(links); count > 0 {
+		return fmt.Errorf("found %d unresolved %s", count, strutil.Pluralize("link", count))
+	}
+	return nil
 }
 
 func (cmd *Index) RunWithNotebook(container *cli.Container, notebook *core.Notebook) error {
@@ -4,23 +4,50 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
+	"regexp"
+	"sort"
+	"time"
 
 	"github.com/zk-org/zk/internal/adapter/fzf"
 	"github.com/zk-org/zk/internal/cli"
+	"github.com/zk-org/zk/internal/core"
 	"github.com/zk-org/zk/internal/util/errors"
+	"github.com/zk-org/zk/internal/util/opt"
 	"github.com/zk-org/zk/internal/util/strings"
 )
 
 // List displays notes matching a set of criteria.
 type List struct {
-	Format     string `group:format short:f placeholder:TEMPLATE   help:"Pretty print the list using a custom template or one of the predefined formats: oneline, short, medium, long, full, json, jsonl."`
-	Header     string `group:format                                help:"Arbitrary text printed at the start of the list."`
-	Footer     string `group:format default:\n                     help:"Arbitrary text printed at the end of the list."`
-	Delimiter  string "group:format short:d default:\n             help:\"Print notes delimited by the given separator.\""
-	Delimiter0 bool   "group:format short:0 name:delimiter0        help:\"Print notes delimited by ASCII NUL characters. This is useful when used in conjunction with `xargs -0`.\""
-	NoPager    bool   `group:format short:P help:"Do not pipe output into a pager."`
-	Quiet      bool   `group:format short:q help:"Do not print the total number of notes found."`
+	Format       string `group:format short:f placeholder:TEMPLATE   help:"Pretty print the list using a custom template or one of the predefined formats: oneline, short, medium, long, full, json, jsonl."`
+	Header       string `group:format                                help:"Arbitrary text printed at the start of the list."`
+	Footer       string `group:format default:\n                     help:"Arbitrary text printed at the end of the list."`
+	Delimiter    string "group:format short:d default:\n             help:\"Print notes delimited by the given separator.\""
+	Delimiter0   bool   "group:format short:0 name:delimiter0        help:\"Print notes delimited by ASCII NUL characters. This is useful when used in conjunction with `xargs -0`.\""
+	NoPager      bool   `group:format short:P help:"Do not pipe output into a pager."`
+	Quiet        bool   `group:format short:q help:"Do not print the total number of notes found."`
+	NoCount      bool   `group:format help:"Do not print the total number of notes found, but keep the other hints."`
+	Histogram    string `group:format placeholder:PERIOD help:"Print the number of notes created per period instead of listing them, among: day, week, month, year."`
+	GroupBy      string `group:format placeholder:KEY help:"Print the notes under a header for each group, among: folder, tag."`
+	NoBody       bool   `group:format help:"Do not load the note bodies, which speeds up listing large notebooks. The body and raw-content template variables are left empty."`
+	Backlinks    bool   `group:format help:"Load the notes linking to each note, for the backlinks template variable. This requires an additional query."`
+	SnippetFrom  string `group:format placeholder:SOURCE help:"Extract the note snippets from the given source among: body (matched terms, default), lead."`
+	Wrap         int    `group:format placeholder:COLUMNS help:"Wrap the lines of the leads and snippets at the given number of columns."`
+	Truncate     int    `group:format placeholder:COLUMNS help:"Truncate the lines of the leads and snippets longer than the given number of columns."`
+	MatchOpen    string `group:format placeholder:TEXT default:"<zk:match>" help:"Insert the given text before the matched terms of the snippets instead of styling them, e.g. an ANSI escape code. Use an empty string to leave them unmarked."`
+	MatchClose   string `group:format placeholder:TEXT default:"</zk:match>" help:"Insert the given text after the matched terms of the snippets instead of styling them."`
+	PathsOnly    bool   `group:format help:"Print only the paths of the notes, without loading them. This is the fastest way to pipe notes into other programs."`
+	MatchFile    string `group:filter placeholder:PATH help:"Run a separate search for each query listed in the given file, one per line. Use - to read them from the standard input."`
+	NewSinceLast bool   `group:filter help:"Find notes created since the last time this option was used."`
+	First        int    `group:filter placeholder:COUNT help:"Find the first notes in ascending order of the primary sort criterion, e.g. the oldest ones with --sort created."`
+	Last         int    `group:filter placeholder:COUNT help:"Find the last notes in descending order of the primary sort criterion, e.g. the newest ones with --sort created."`
 	cli.Filtering
+
+	// Limit read from the [list] config section, when --limit is not given.
+	defaultLimit int
+	// Whether some of the matching notes were not listed, because of --limit
+	// or a cancelled --interactive selection.
+	incomplete bool
 }
 
 func (cmd *List) Run(container *cli.Container) error {
@@ -69,6 +96,64 @@ func (cmd *List) Run(container *cli.Container) error {
 		}
 	}
 
+	if cmd.Wrap < 0 || cmd.Truncate < 0 {
+		return errors.New("--wrap and --truncate expect a positive number of columns")
+	}
+	if cmd.Wrap > 0 && cmd.Truncate > 0 {
+		return errors.New("--wrap and --truncate can't be used together")
+	}
+
+	if cmd.PathsOnly {
+		if cmd.Format != "" {
+			return errors.New("--paths-only can't be used with --format")
+		}
+		if cmd.Histogram != "" {
+			return errors.New("--paths-only can't be used with --histogram")
+		}
+		if cmd.Interactive {
+			return errors.New("--paths-only can't be used with --interactive")
+		}
+	}
+
+	if cmd.GroupBy != "" {
+		switch {
+		case cmd.GroupBy != "folder" && cmd.GroupBy != "tag":
+			return fmt.Errorf("%s: unknown grouping key\ntry folder or tag", cmd.GroupBy)
+		case cmd.Format == "json" || cmd.Format == "jsonl":
+			return errors.New("--group-by can't be used with JSON format")
+		case cmd.Histogram != "":
+			return errors.New("--group-by can't be used with --histogram")
+		case cmd.PathsOnly:
+			return errors.New("--group-by can't be used with --paths-only")
+		case cmd.MatchFile != "":
+			return errors.New("--group-by can't be used with --match-file")
+		}
+	}
+
+	if cmd.MatchFile != "" {
+		switch {
+		case cmd.Format == "json" || cmd.Format == "jsonl":
+			return errors.New("--match-file can't be used with JSON format")
+		case cmd.Histogram != "":
+			return errors.New("--match-file can't be used with --histogram")
+		case cmd.PathsOnly:
+			return errors.New("--match-file can't be used with --paths-only")
+		case cmd.Interactive:
+			return errors.New("--match-file can't be used with --interactive")
+		}
+	}
+
+	if cmd.Histogram != "" {
+		switch {
+		case !cmd.Limit.IsNull() && cmd.Limit.Unwrap() > 0:
+			return errors.New("--histogram can't be used with --limit")
+		case cmd.First > 0 || cmd.Last > 0:
+			return errors.New("--histogram can't be used with --first or --last")
+		case cmd.Interactive:
+			return errors.New("--histogram can't be used with --interactive")
+		}
+	}
+
 	notebook, err := container.CurrentNotebook()
 	if err != nil {
 		return err
@@ -78,16 +163,79 @@ func (cmd *List) Run(container *cli.Container) error {
 	if err != nil {
 		return err
 	}
+	format = cmd.fitNoteText(format)
+
+	err = cmd.applyFirstOrLast()
+	if err != nil {
+		return errors.Wrapf(err, "incorrect criteria")
+	}
+
+	err = cmd.applyDefaultLimit(notebook)
+	if err != nil {
+		return errors.Wrapf(err, "incorrect criteria")
+	}
 
 	findOpts, err := cmd.Filtering.NewNoteFindOpts(notebook)
 	if err != nil {
 		return errors.Wrapf(err, "incorrect criteria")
 	}
+	err = cmd.applyFirstOrLastOrder(&findOpts)
+	if err != nil {
+		return errors.Wrapf(err, "incorrect criteria")
+	}
+
+	if !cmd.NewSinceLast {
+		return cmd.list(container, notebook, findOpts, format)
+	}
+
+	// The listing time is taken before querying, to not miss the notes
+	// created in the meantime on the next run.
+	listedAt := time.Now().UTC()
+	err = cmd.applyNewSinceLast(notebook, &findOpts)
+	if err != nil {
+		return err
+	}
+	err = cmd.list(container, notebook, findOpts, format)
+	if err != nil || cmd.incomplete {
+		// The notes which were not listed will be found on the next run.
+		return err
+	}
+	return notebook.SetLastListedAt(listedAt)
+}
+
+// list prints the notes matching findOpts in the requested format.
+func (cmd *List) list(container *cli.Container, notebook *core.Notebook, findOpts core.NoteFindOpts, format core.NoteFormatter) error {
+	var err error
+	if cmd.Histogram != "" {
+		return cmd.printHistogram(container, notebook, findOpts)
+	}
+	if cmd.PathsOnly {
+		return cmd.printPaths(container, notebook, findOpts)
+	}
+
+	findOpts.ExcludeBody = cmd.NoBody
+	findOpts.IncludeBacklinks = cmd.Backlinks
+	findOpts.MatchOpen = opt.NewString(cmd.MatchOpen)
+	findOpts.MatchClose = opt.NewString(cmd.MatchClose)
+	findOpts.SnippetSource, err = core.SnippetSourceFromString(cmd.SnippetFrom)
+	if err != nil {
+		return err
+	}
+
+	if cmd.MatchFile != "" {
+		return cmd.runMatchFile(container, notebook, findOpts, format)
+	}
 
+	limit := cmd.widenLimit(&findOpts)
 	notes, err := notebook.FindNotes(findOpts)
 	if err != nil {
 		return err
 	}
+	truncated := limit > 0 && len(notes) > limit
+	if truncated {
+		notes = notes[:limit]
+		cmd.incomplete = true
+	}
 
 	filter := container.NewNoteFilter(fzf.NoteFilterOpts{
 		Interactive:  cmd.Interactive,
@@ -98,27 +246,415 @@ func (cmd *List) Run(container *cli.Container) error {
 	notes, err = filter.Apply(notes)
 	if err != nil {
 		if err == fzf.ErrCancelled {
+			cmd.incomplete = true
 			return nil
 		}
 		return err
 	}
 
 	count := len(notes)
+	if count > 0 {
+		err = container.Paginate(cmd.NoPager, func(out io.Writer) error {
+			if cmd.GroupBy != "" {
+				return cmd.printGroups(out, groupNotes(notes, cmd.GroupBy), format)
+			}
+			return cmd.printNotes(out, notes, format)
+		})
+	}
+
+	if err == nil && !cmd.Quiet {
+		cmd.printCount(count, truncated)
+
+		if count == 0 {
+			err = cmd.printTagSuggestions(notebook, findOpts.Tags)
+		}
+	}
+
+	return err
+}
+
+// printNotes writes the given notes to out, between the header and footer.
+func (cmd *List) printNotes(out io.Writer, notes []core.ContextualNote, format core.NoteFormatter) error {
+	if cmd.Header != "" {
+		fmt.Fprint(out, cmd.Header)
+	}
+	for i, note := range notes {
+		if i > 0 {
+			fmt.Fprint(out, cmd.Delimiter)
+		}
+
+		ft, err := format(note)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(out, ft)
+	}
+	if cmd.Footer != "" {
+		fmt.Fprint(out, cmd.Footer)
+	}
+
+	return nil
+}
+
+// fitNoteText returns a formatter wrapping or truncating the leads and
+// snippets of the notes before formatting them, according to --wrap and
+// --truncate.
+func (cmd *List) fitNoteText(format core.NoteFormatter) core.NoteFormatter {
+	var fit func(text string) string
+	switch {
+	case cmd.Wrap > 0:
+		fit = func(text string) string {
+			return strings.WrapLines(text, cmd.Wrap, cmd.MatchOpen, cmd.MatchClose)
+		}
+	case cmd.Truncate > 0:
+		fit = func(text string) string {
+			return strings.TruncateLines(text, cmd.Truncate, cmd.MatchOpen, cmd.MatchClose)
+		}
+	default:
+		return format
+	}
+
+	return func(note core.ContextualNote) (string, error) {
+		note.Lead = fit(note.Lead)
+		snippets := make([]string, 0, len(note.Snippets))
+		for _, snippet := range note.Snippets {
+			snippets = append(snippets, fit(snippet))
+		}
+		note.Snippets = snippets
+		return format(note)
+	}
+}
+
+// noteGroup holds the notes sharing the same grouping key.
+type noteGroup struct {
+	Key   string
+	Notes []core.ContextualNote
+}
+
+// untaggedGroupKey is the key of the group listing the notes without any tag.
+const untaggedGroupKey = "(untagged)"
+
+// groupNotes partitions the notes by their leading folder, or by tag. A note
+// with several tags appears in the group of each of them.
+//
+// The groups are sorted by key, the notes at the root of the notebook being
+// grouped under "." and the untagged ones last. Each group keeps the order of
+// the given notes.
+func groupNotes(notes []core.ContextualNote, by string) []noteGroup {
+	groups := map[string][]core.ContextualNote{}
+	keys := []string{}
+	add := func(key string, note core.ContextualNote) {
+		if _, ok := groups[key]; !ok && key != untaggedGroupKey {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], note)
+	}
+
+	for _, note := range notes {
+		switch by {
+		case "folder":
+			add(leadingFolder(note.Path), note)
+		case "tag":
+			if len(note.Tags) == 0 {
+				add(untaggedGroupKey, note)
+			}
+			for _, tag := range note.Tags {
+				add(tag, note)
+			}
+		}
+	}
+
+	sort.Strings(keys)
+	if _, ok := groups[untaggedGroupKey]; ok {
+		keys = append(keys, untaggedGroupKey)
+	}
+
+	res := make([]noteGroup, 0, len(keys))
+	for _, key := range keys {
+		res = append(res, noteGroup{Key: key, Notes: groups[key]})
+	}
+	return res
+}
+
+// leadingFolder returns the top-level folder of the given notebook path, or
+// "." for a note at the root of the notebook.
+func leadingFolder(notePath string) string {
+	dir := path.Dir(notePath)
+	for parent := path.Dir(dir); parent != "."; parent = path.Dir(dir) {
+		dir = parent
+	}
+	return dir
+}
+
+// printGroups writes each group of notes to out, after a `==> KEY <==` line.
+func (cmd *List) printGroups(out io.Writer, groups []noteGroup, format core.NoteFormatter) error {
+	for i, group := range groups {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintf(out, "==> %s <==\n", group.Key)
+		err := cmd.printNotes(out, group.Notes, format)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runMatchFile runs a separate search for each query of --match-file, in
+// addition to the other criteria. The results of each query are printed
+// after a `==> QUERY <==` line.
+func (cmd *List) runMatchFile(container *cli.Container, notebook *core.Notebook, findOpts core.NoteFindOpts, format core.NoteFormatter) error {
+	queries, err := readMatchFile(cmd.MatchFile)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	truncated := false
+	err = container.Paginate(cmd.NoPager, func(out io.Writer) error {
+		for i, query := range queries {
+			opts := findOpts
+			opts.Match = append(append([]string{}, findOpts.Match...), query)
+			limit := cmd.widenLimit(&opts)
+			notes, err := notebook.FindNotes(opts)
+			if err != nil {
+				return errors.Wrapf(err, "%s", query)
+			}
+			if limit > 0 && len(notes) > limit {
+				notes = notes[:limit]
+				truncated = true
+				cmd.incomplete = true
+			}
+			count += len(notes)
+
+			if i > 0 {
+				fmt.Fprintln(out)
+			}
+			fmt.Fprintf(out, "==> %s <==\n", query)
+			if len(notes) > 0 {
+				err = cmd.printNotes(out, notes, format)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+
+	if err == nil {
+		cmd.printCount(count, truncated)
+	}
+	return err
+}
+
+// readMatchFile reads the queries listed in the given file, one per line.
+// The standard input is read when the path is -. Blank lines are ignored.
+func readMatchFile(path string) ([]string, error) {
+	var content []byte
+	var err error
+	if path == "-" {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s: failed to read --match-file", path)
+	}
+
+	queries := strings.RemoveBlank(strings.SplitLines(string(content)))
+	if queries == nil {
+		queries = []string{}
+	}
+	return queries, nil
+}
+
+// applyDefaultLimit sets the limit configured in the [list] section when
+// --limit is not given, either directly or by a named filter.
+//
+// The default limit doesn't apply to --interactive, --histogram and
+// --new-since-last, which need all the matching notes.
+func (cmd *List) applyDefaultLimit(notebook *core.Notebook) error {
+	limit := notebook.Config.List.Limit
+	if limit == 0 || cmd.Interactive || cmd.Histogram != "" || cmd.NewSinceLast {
+		return nil
+	}
+
+	filtering, err := cmd.Filtering.ExpandNamedFilters(notebook.Config.Filters, []string{})
+	if err != nil {
+		return err
+	}
+	if filtering.Limit.IsNull() {
+		cmd.Limit = opt.NewInt(limit)
+		cmd.defaultLimit = limit
+	}
+	return nil
+}
+
+// applyFirstOrLast turns --first and --last into the equivalent --limit.
+func (cmd *List) applyFirstOrLast() error {
+	count := cmd.First + cmd.Last
+	switch {
+	case cmd.First < 0 || cmd.Last < 0:
+		return errors.New("--first and --last expect a positive number of notes")
+	case cmd.First > 0 && cmd.Last > 0:
+		return errors.New("--first and --last can't be used together")
+	case count > 0 && !cmd.Limit.IsNull():
+		return errors.New("--first and --last can't be used with --limit")
+	case count > 0:
+		cmd.Limit = opt.NewInt(count)
+	}
+	return nil
+}
+
+// applyFirstOrLastOrder sorts the primary criterion in ascending order for
+// --first and in descending order for --last, overriding the direction given
+// with --sort. Without --sort, the notes are sorted by title.
+func (cmd *List) applyFirstOrLastOrder(opts *core.NoteFindOpts) error {
+	if cmd.First == 0 && cmd.Last == 0 {
+		return nil
+	}
+	if len(opts.Sorters) == 0 {
+		opts.Sorters = []core.NoteSorter{{Field: core.NoteSortTitle}}
+	}
+	if opts.Sorters[0].Field == core.NoteSortRandom {
+		return errors.New("--first and --last can't be used with --sort random")
+	}
+	opts.Sorters[0].Ascending = cmd.First > 0
+	return nil
+}
+
+// applyNewSinceLast restricts findOpts to the notes created since the
+// previous run with --new-since-last. All the notes are found on the first
+// run, and a later --created-after date takes precedence.
+func (cmd *List) applyNewSinceLast(notebook *core.Notebook, findOpts *core.NoteFindOpts) error {
+	lastListedAt, err := notebook.LastListedAt()
+	if err != nil || lastListedAt.IsZero() {
+		return err
+	}
+	if findOpts.CreatedStart == nil || findOpts.CreatedStart.Before(lastListedAt) {
+		findOpts.CreatedStart = &lastListedAt
+	}
+	return nil
+}
+
+// widenLimit requests one more note than the --limit option, to find out
+// whether the results are truncated. It returns the original limit.
+func (cmd *List) widenLimit(opts *core.NoteFindOpts) int {
+	limit := opts.Limit
+	if limit > 0 {
+		opts.Limit = limit + 1
+	}
+	return limit
+}
+
+// printCount prints the total number of notes found on the standard error.
+//
+// Nothing is printed when the results are truncated by --limit, as the count
+// would only reflect the returned notes. A hint is printed instead when the
+// limit comes from the configuration, as the user didn't ask for it.
+func (cmd *List) printCount(count int, truncated bool) {
+	if cmd.Quiet {
+		return
+	}
+	if truncated && cmd.defaultLimit > 0 {
+		fmt.Fprintf(os.Stderr, "\nShowing the first %d %s, use --limit 0 to list them all\n", count, strings.Pluralize("note", count))
+		return
+	}
+	if cmd.NoCount || truncated {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\nFound %d %s\n", count, strings.Pluralize("note", count))
+}
+
+// printTagSuggestions prints the existing tags which are close to the given
+// tag filters, in case of a typo.
+func (cmd *List) printTagSuggestions(notebook *core.Notebook, tagFilters []string) error {
+	for _, filter := range tagFilters {
+		negate := false
+		for _, tag := range tagTokenRegex.FindAllString(filter, -1) {
+			// Only plain tags are suggested, negated ones and globs can't
+			// explain the absence of results.
+			switch {
+			case tag == "OR" || tag == "AND":
+				continue
+			case tag == "NOT":
+				negate = true
+				continue
+			case negate || tag[0] == '-' || tagGlobRegex.MatchString(tag):
+				negate = false
+				continue
+			}
+
+			similarTags, err := notebook.FindSimilarTags(tag)
+			if err != nil {
+				return err
+			}
+			if len(similarTags) > 3 {
+				similarTags = similarTags[:3]
+			}
+			if len(similarTags) > 0 {
+				fmt.Fprintf(os.Stderr, "No notes tagged `%s`, did you mean %s?\n", tag, joinTagSuggestions(similarTags))
+			}
+		}
+	}
+	return nil
+}
+
+var tagTokenRegex = regexp.MustCompile(`[^\s|()]+`)
+var tagGlobRegex = regexp.MustCompile(`[*?\[]`)
+
+// joinTagSuggestions formats a list of tags, e.g. `a`, `b` or `c`.
+func joinTagSuggestions(tags []string) string {
+	res := ""
+	for i, tag := range tags {
+		switch {
+		case i == 0:
+		case i == len(tags)-1:
+			res += " or "
+		default:
+			res += ", "
+		}
+		res += "`" + tag + "`"
+	}
+	return res
+}
+
+// printPaths prints only the paths of the notes, relative to the working
+// directory.
+func (cmd *List) printPaths(container *cli.Container, notebook *core.Notebook, findOpts core.NoteFindOpts) error {
+	limit := cmd.widenLimit(&findOpts)
+	paths, err := notebook.FindNotePaths(findOpts)
+	if err != nil {
+		return err
+	}
+	truncated := limit > 0 && len(paths) > limit
+	if truncated {
+		paths = paths[:limit]
+		cmd.incomplete = true
+	}
+
+	count := len(paths)
 	if count > 0 {
 		err = container.Paginate(cmd.NoPager, func(out io.Writer) error {
 			if cmd.Header != "" {
 				fmt.Fprint(out, cmd.Header)
 			}
-			for i, note := range notes {
+			for i, p := range paths {
 				if i > 0 {
 					fmt.Fprint(out, cmd.Delimiter)
 				}
 
-				ft, err := format(note)
+				path := core.NotebookPath{
+					Path:       p,
+					BasePath:   notebook.Path,
+					WorkingDir: container.WorkingDir,
+				}
+				relPath, err := path.PathRelToWorkingDir()
 				if err != nil {
 					return err
 				}
-				fmt.Fprint(out, ft)
+				fmt.Fprint(out, relPath)
 			}
 			if cmd.Footer != "" {
 				fmt.Fprint(out, cmd.Footer)
@@ -128,8 +664,39 @@ func (cmd *List) Run(container *cli.Container) error {
 		})
 	}
 
-	if err == nil && !cmd.Quiet {
-		fmt.Fprintf(os.Stderr, "\nFound %d %s\n", count, strings.Pluralize("note", count))
+	if err == nil {
+		cmd.printCount(count, truncated)
+	}
+
+	return err
+}
+
+// printHistogram prints the number of notes created per period of time.
+func (cmd *List) printHistogram(container *cli.Container, notebook *core.Notebook, findOpts core.NoteFindOpts) error {
+	bucket, err := core.DateBucketFromString(cmd.Histogram)
+	if err != nil {
+		return err
+	}
+	// All the matching notes are counted, even when a named filter sets a
+	// limit.
+	findOpts.Limit = 0
+
+	counts, err := notebook.CountNotesByCreationDate(findOpts, bucket)
+	if err != nil {
+		return err
+	}
+
+	total := 0
+	err = container.Paginate(cmd.NoPager, func(out io.Writer) error {
+		for _, count := range counts {
+			total += count.Count
+			fmt.Fprintf(out, "%s\t%d\n", count.Bucket, count.Count)
+		}
+		return nil
+	})
+
+	if err == nil {
+		cmd.printCount(total, false)
 	}
 
 	return err
@@ -3,6 +3,7 @@ package cmd
 import (
 	"testing"
 
+	"github.com/zk-org/zk/internal/core"
 	"github.com/zk-org/zk/internal/util/test/assert"
 )
 
@@ -65,3 +66,49 @@ func TestListFormatCustom(t *testing.T) {
 	// \n and \t in custom formats are expanded.
 	test(`{{title}}\t{{path}}\n{{snippet}}`, "{{title}}\t{{path}}\n{{snippet}}")
 }
+
+func TestListGroupNotes(t *testing.T) {
+	note := func(path string, tags ...string) core.ContextualNote {
+		return core.ContextualNote{Note: core.Note{Path: path, Tags: tags}}
+	}
+	paths := func(groups []noteGroup) map[string][]string {
+		res := map[string][]string{}
+		for _, group := range groups {
+			for _, n := range group.Notes {
+				res[group.Key] = append(res[group.Key], n.Path)
+			}
+		}
+		return res
+	}
+	keys := func(groups []noteGroup) []string {
+		res := []string{}
+		for _, group := range groups {
+			res = append(res, group.Key)
+		}
+		return res
+	}
+
+	notes := []core.ContextualNote{
+		note("ref/b.md", "science", "history"),
+		note("index.md"),
+		note("log/2021/a.md", "science"),
+		note("ref/a.md", "art"),
+	}
+
+	groups := groupNotes(notes, "folder")
+	assert.Equal(t, keys(groups), []string{".", "log", "ref"})
+	assert.Equal(t, paths(groups), map[string][]string{
+		".":   {"index.md"},
+		"log": {"log/2021/a.md"},
+		"ref": {"ref/b.md", "ref/a.md"},
+	})
+
+	groups = groupNotes(notes, "tag")
+	assert.Equal(t, keys(groups), []string{"art", "history", "science", "(untagged)"})
+	assert.Equal(t, paths(groups), map[string][]string{
+		"art":        {"ref/a.md"},
+		"history":    {"ref/b.md"},
+		"science":    {"ref/b.md", "log/2021/a.md"},
+		"(untagged)": {"index.md"},
+	})
+}
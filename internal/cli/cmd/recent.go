@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/alecthomas/kong"
+	"github.com/zk-org/zk/internal/cli"
+	"github.com/zk-org/zk/internal/util/opt"
+)
+
+// Recent lists the latest notes of the notebook.
+type Recent struct {
+	Count   int    `arg optional default:"10" help:"Number of notes to list."`
+	By      string `placeholder:FIELD default:"created" enum:"created,modified" help:"Date used to find the latest notes, among: created, modified."`
+	Format  string `group:format short:f placeholder:TEMPLATE help:"Pretty print the list using a custom template or one of the predefined formats: oneline, short, medium, long, full, json, jsonl."`
+	NoPager bool   `group:format short:P help:"Do not pipe output into a pager."`
+	Quiet   bool   `group:format short:q help:"Do not print the total number of notes found."`
+}
+
+func (cmd *Recent) Help() string {
+	return "This is a shortcut for `zk list --sort created- --limit 10`."
+}
+
+func (cmd *Recent) Run(container *cli.Container) error {
+	if cmd.Count < 1 {
+		return fmt.Errorf("%d: expected a positive number of notes", cmd.Count)
+	}
+
+	list, err := newDefaultList()
+	if err != nil {
+		return err
+	}
+	list.Format = cmd.Format
+	list.NoPager = cmd.NoPager
+	list.Quiet = cmd.Quiet
+	list.Limit = opt.NewInt(cmd.Count)
+	list.Sort = []string{cmd.By + "-"}
+	return list.Run(container)
+}
+
+// newDefaultList creates a List command with the default values of its flags,
+// as if `zk list` was run without any argument.
+func newDefaultList() (List, error) {
+	var list List
+	parser, err := kong.New(&list, cli.SortTermMapper, cli.FilterMapper)
+	if err != nil {
+		return list, err
+	}
+	_, err = parser.Parse([]string{})
+	return list, err
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zk-org/zk/internal/cli"
+	dateutil "github.com/zk-org/zk/internal/util/date"
+)
+
+// Touch updates the modification date of notes, without changing their
+// content.
+type Touch struct {
+	Notes []string `arg help:"Paths to the notes to touch, a partial path is accepted."`
+	Date  string   `placeholder:DATE help:"Set this modification date instead of the current time."`
+}
+
+func (cmd *Touch) Help() string {
+	return "This moves the notes to the top of `zk list --sort modified`. The modification time of the files is updated as well."
+}
+
+func (cmd *Touch) Run(container *cli.Container) error {
+	notebook, err := container.CurrentNotebook()
+	if err != nil {
+		return err
+	}
+
+	date := time.Now()
+	if cmd.Date != "" {
+		date, err = dateutil.TimeFromNatural(cmd.Date)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, href := range cmd.Notes {
+		note, err := notebook.FindByHref(href, true /* allowPartialHref */)
+		if err != nil {
+			return err
+		}
+		if note == nil {
+			return fmt.Errorf("could not find a note at: %s", href)
+		}
+
+		err = notebook.TouchNote(note.Path, date)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -104,7 +104,10 @@ func NewContainer(version string) (*Container, error) {
 					return nil, err
 				}
 
-				notebook := core.NewNotebook(path, config, core.NotebookPorts{
+				// The notebook is captured by the template helpers, which are
+				// only created once it is set up.
+				var notebook *core.Notebook
+				notebook = core.NewNotebook(path, config, core.NotebookPorts{
 					NoteIndex: sqlite.NewNoteIndex(path, db, logger),
 					NoteContentParser: markdown.NewParser(
 						markdown.ParserOpts{
@@ -131,6 +134,14 @@ func NewContainer(version string) (*Container, error) {
 							return nil, err
 						}
 						loader.RegisterHelper("format-link", hbhelpers.NewLinkHelper(linkFormatter, logger))
+						loader.RegisterHelper("link-title", hbhelpers.NewLinkTitleHelper(func(href string) (*core.MinimalNote, error) {
+							return notebook.FindByHref(href, true /* allowPartialHref */)
+						}, logger))
+						loader.RegisterHelper("backlink-count", hbhelpers.NewBacklinkCountHelper(func() (map[string]int, error) {
+							return notebook.CountBacklinks()
+						}, func(path string) (string, error) {
+							return notebook.RelPath(path)
+						}, logger))
 
 						return loader, nil
 					},
@@ -2,6 +2,13 @@ package cli
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/alecthomas/kong"
@@ -9,68 +16,132 @@ import (
 	"github.com/zk-org/zk/internal/core"
 	dateutil "github.com/zk-org/zk/internal/util/date"
 	"github.com/zk-org/zk/internal/util/errors"
-	"github.com/zk-org/zk/internal/util/strings"
+	"github.com/zk-org/zk/internal/util/fts5"
+	"github.com/zk-org/zk/internal/util/opt"
+	strutil "github.com/zk-org/zk/internal/util/strings"
 )
 
 // Filtering holds filtering options to select notes.
 type Filtering struct {
 	Path []string `kong:"group='filter',arg,optional,placeholder='PATH',help='Find notes matching the given path, including its descendants.'" json:"hrefs"`
 
-	Interactive    bool     `kong:"group='filter',short='i',help='Select notes interactively with fzf.'" json:"-"`
-	Limit          int      `kong:"group='filter',short='n',placeholder='COUNT',help='Limit the number of notes found.'" json:"limit"`
-	Match          []string `kong:"group='filter',short='m',placeholder='QUERY',help='Terms to search for in the notes.'" json:"match"`
-	MatchStrategy  string   `kong:"group='filter',short='M',default='fts',placeholder='STRATEGY',help='Text matching strategy among: fts, re, exact.'" json:"matchStrategy"`
-	Exclude        []string `kong:"group='filter',short='x',placeholder='PATH',help='Ignore notes matching the given path, including its descendants.'" json:"excludeHrefs"`
-	Tag            []string `kong:"group='filter',short='t',help='Find notes tagged with the given tags.'" json:"tags"`
-	Mention        []string `kong:"group='filter',placeholder='PATH',help='Find notes mentioning the title of the given ones.'" json:"mention"`
-	MentionedBy    []string `kong:"group='filter',placeholder='PATH',help='Find notes whose title is mentioned in the given ones.'" json:"mentionedBy"`
-	LinkTo         []string `kong:"group='filter',short='l',placeholder='PATH',help='Find notes which are linking to the given ones.'" json:"linkTo"`
-	NoLinkTo       []string `kong:"group='filter',placeholder='PATH',help='Find notes which are not linking to the given notes.'" json:"-"`
-	LinkedBy       []string `kong:"group='filter',short='L',placeholder='PATH',help='Find notes which are linked by the given ones.'" json:"linkedBy"`
-	NoLinkedBy     []string `kong:"group='filter',placeholder='PATH',help='Find notes which are not linked by the given ones.'" json:"-"`
-	Orphan         bool     `kong:"group='filter',help='Find notes which are not linked by any other note.'" json:"orphan"`
-	Tagless        bool     `kong:"group='filter',help='Find notes which have no tags.'" json:"tagless"`
-	Related        []string `kong:"group='filter',placeholder='PATH',help='Find notes which might be related to the given ones.'" json:"related"`
-	MaxDistance    int      `kong:"group='filter',placeholder='COUNT',help='Maximum distance between two linked notes.'" json:"maxDistance"`
-	Recursive      bool     `kong:"group='filter',short='r',help='Follow links recursively.'" json:"recursive"`
-	Created        string   `kong:"group='filter',placeholder='DATE',help:'Find notes created on the given date.'" json:"created"`
-	CreatedBefore  string   `kong:"group='filter',placeholder='DATE',help='Find notes created before the given date.'" json:"createdBefore"`
-	CreatedAfter   string   `kong:"group='filter',placeholder='DATE',help='Find notes created after the given date.'" json:"createdAfter"`
-	Modified       string   `kong:"group='filter',placeholder='DATE',help='Find notes modified on the given date.'" json:"modified"`
-	ModifiedBefore string   `kong:"group='filter',placeholder='DATE',help='Find notes modified before the given date.'" json:"modifiedBefore"`
-	ModifiedAfter  string   `kong:"group='filter',placeholder='DATE',help='Find notes modified after the given date.'" json:"modifiedAfter"`
-
-	Sort []string `kong:"group='sort',short='s',placeholder='TERM',help='Order the notes by the given criterion.'" json:"sort"`
+	Interactive     bool     `kong:"group='filter',short='i',help='Select notes interactively with fzf.'" json:"-"`
+	Limit           opt.Int  `kong:"group='filter',short='n',placeholder='COUNT',help='Limit the number of notes found.'" json:"limit"`
+	Match           []string `kong:"group='filter',short='m',placeholder='QUERY',help='Terms to search for in the notes.'" json:"match"`
+	MatchStrategy   string   `kong:"group='filter',short='M',default='fts',placeholder='STRATEGY',help='Text matching strategy among: fts, re, exact.'" json:"matchStrategy"`
+	MatchRaw        bool     `kong:"group='filter',help='Search the raw content of the notes, including their Markdown markup, instead of the processed body.'" json:"matchRaw"`
+	Literal         bool     `kong:"group='filter',help='Search the --match and --lead-match queries literally, without interpreting any operator.'" json:"literal"`
+	Fuzzy           bool     `kong:"group='filter',help='Tolerate typos in the --match terms, using a trigram search index.'" json:"fuzzy"`
+	MatchAny        bool     `kong:"group='filter',help='Find the notes matching any of the --match and --lead-match terms, instead of all of them.'" json:"matchAny"`
+	LeadMatch       []string `kong:"group='filter',placeholder='QUERY',help='Terms to search for in the lead paragraph of the notes only.'" json:"leadMatch"`
+	BodyRegex       string   `kong:"group='filter',placeholder='REGEX',help='Find notes whose body matches the given regular expression. This is slower than --match, which uses a search index.'" json:"bodyRegex"`
+	IgnorePathCase  bool     `kong:"group='filter',help='Match the paths regardless of their case, e.g. on case-insensitive file systems.'" json:"ignorePathCase"`
+	PathRegex       string   `kong:"group='filter',placeholder='REGEX',help='Find notes whose path matches the given regular expression.'" json:"pathRegex"`
+	Dir             []string `kong:"group='filter',placeholder='PATH',help='Find notes located in the given directory, including its subdirectories. This is faster than a path pattern on large notebooks.'" json:"dir"`
+	Exclude         []string `kong:"group='filter',short='x',placeholder='PATH',help='Ignore notes matching the given path, including its descendants.'" json:"excludeHrefs"`
+	Tag             []string `kong:"group='filter',short='t',help='Find notes tagged with the given tags.'" json:"tags"`
+	Alias           []string `kong:"group='filter',placeholder='ALIAS',help='Find notes declaring any of the given aliases in their metadata.'" json:"alias"`
+	UntaggedMention []string `kong:"name='untagged-mentions',group='filter',placeholder='TERM',help='Find notes mentioning the given term without being tagged with it.'" json:"untaggedMentions"`
+	Mention         []string `kong:"group='filter',placeholder='PATH',help='Find notes mentioning the title of the given ones.'" json:"mention"`
+	MentionAll      bool     `kong:"group='filter',help='Find the notes mentioning all the --mention notes, instead of any of them.'" json:"mentionAll"`
+	MentionedBy     []string `kong:"group='filter',placeholder='PATH',help='Find notes whose title is mentioned in the given ones.'" json:"mentionedBy"`
+	LinkTo          []string `kong:"group='filter',short='l',placeholder='PATH',help='Find notes which are linking to the given ones.'" json:"linkTo"`
+	NoLinkTo        []string `kong:"group='filter',placeholder='PATH',help='Find notes which are not linking to the given notes.'" json:"-"`
+	LinkToAll       []string `kong:"group='filter',placeholder='PATH',help='Find notes which are linking to all the given ones.'" json:"linkToAll"`
+	LinkToTitle     []string `kong:"group='filter',placeholder='TITLE',help='Find notes which are linking to the ones with the given titles.'" json:"linkToTitle"`
+	LinkedBy        []string `kong:"group='filter',short='L',placeholder='PATH',help='Find notes which are linked by the given ones.'" json:"linkedBy"`
+	NoLinkedBy      []string `kong:"group='filter',placeholder='PATH',help='Find notes which are not linked by the given ones.'" json:"-"`
+	LinkContext     []string `kong:"group='filter',placeholder='TERM',help='Only consider the links of the link filters surrounded by the given terms.'" json:"linkContext"`
+	ExternalLinkTo  []string `kong:"group='filter',placeholder='DOMAIN',help='Find notes having an external link to the given domains.'" json:"externalLinkTo"`
+	Orphan          bool     `kong:"group='filter',help='Find notes which are not linked by any other note.'" json:"orphan"`
+	MinBacklinks    int      `kong:"group='filter',placeholder='COUNT',help='Find notes linked by at least the given number of notes.'" json:"minBacklinks"`
+	MaxBacklinks    opt.Int  `kong:"group='filter',placeholder='COUNT',help='Find notes linked by at most the given number of notes.'" json:"maxBacklinks"`
+	Tagless         bool     `kong:"group='filter',help='Find notes which have no tags.'" json:"tagless"`
+	HasFrontmatter  bool     `kong:"group='filter',help='Find notes having a YAML frontmatter with at least one key.'" json:"hasFrontmatter"`
+	NoFrontmatter   bool     `kong:"group='filter',help='Find notes without any YAML frontmatter, or with an empty one.'" json:"noFrontmatter"`
+	ExcludeMetadata []string `kong:"group='filter',placeholder='KEY=VALUE',help='Ignore notes whose metadata key has the given value, e.g. draft=true.'" json:"excludeMetadata"`
+	UntypedLinks    bool     `kong:"group='filter',help='Find notes having internal links without any relation.'" json:"untypedLinks"`
+	Has             []string `kong:"group='filter',placeholder='FEATURE',help='Find notes containing the given Markdown features, among: code, table, image, link, task, open-task.'" json:"has"`
+	WordsTopPercent int      `kong:"group='filter',placeholder='PERCENT',help='Find the given percentage of the longest notes, by word count.'" json:"wordsTopPercent"`
+	Related         []string `kong:"group='filter',placeholder='PATH',help='Find notes which might be related to the given ones.'" json:"related"`
+	MaxDistance     int      `kong:"group='filter',placeholder='COUNT',help='Maximum distance between two linked notes.'" json:"maxDistance"`
+	Recursive       bool     `kong:"group='filter',short='r',help='Follow links recursively.'" json:"recursive"`
+	Created         string   `kong:"group='filter',placeholder='DATE',help:'Find notes created on the given date.'" json:"created"`
+	CreatedBefore   string   `kong:"group='filter',placeholder='DATE',help='Find notes created before the given date.'" json:"createdBefore"`
+	CreatedAfter    string   `kong:"group='filter',placeholder='DATE',help='Find notes created after the given date.'" json:"createdAfter"`
+	CreatedBetween  string   `kong:"group='filter',placeholder='RANGE',help='Find notes created between two dates, formatted as START..END.'" json:"createdBetween"`
+	CreatedLast     string   `kong:"group='filter',placeholder='DURATION',help='Find notes created in the last given duration, e.g. 30d, 2w, 1mo or 1y.'" json:"createdLast"`
+	CreatedYear     []int    `kong:"group='filter',placeholder='YEAR',help='Find notes created during any of the given years.'" json:"createdYear"`
+	Modified        string   `kong:"group='filter',placeholder='DATE',help='Find notes modified on the given date.'" json:"modified"`
+	ModifiedBefore  string   `kong:"group='filter',placeholder='DATE',help='Find notes modified before the given date.'" json:"modifiedBefore"`
+	ModifiedAfter   string   `kong:"group='filter',placeholder='DATE',help='Find notes modified after the given date.'" json:"modifiedAfter"`
+	ModifiedBetween string   `kong:"group='filter',placeholder='RANGE',help='Find notes modified between two dates, formatted as START..END.'" json:"modifiedBetween"`
+	ModifiedLast    string   `kong:"group='filter',placeholder='DURATION',help='Find notes modified in the last given duration, e.g. 7d, 2w, 1mo or 1y.'" json:"modifiedLast"`
+	ModifiedYear    []int    `kong:"group='filter',placeholder='YEAR',help='Find notes modified during any of the given years.'" json:"modifiedYear"`
+	OnThisDay       string   `kong:"group='filter',placeholder='FIELD',help='Find notes dated on the month and day of today during a prior year, given the date field among: created, modified.'" json:"onThisDay"`
+	Stale           int      `kong:"group='filter',placeholder='DAYS',help='Find notes modified less than the given number of days after their creation.'" json:"stale"`
+	ActivelyEdited  int      `kong:"group='filter',placeholder='DAYS',help='Find notes modified at least the given number of days after their creation.'" json:"activelyEdited"`
+	Undated         bool     `kong:"group='filter',help='Find notes without a creation date.'" json:"undated"`
+	AnomalousDates  bool     `kong:"group='filter',help='Find notes modified before their creation date, e.g. after an import swapped their timestamps.'" json:"anomalousDates"`
+	FutureDates     bool     `kong:"group='filter',help='Resolve ambiguous dates (e.g. monday) in the future instead of the past.'" json:"futureDates"`
+	Or              []string `kong:"group='filter',type='filter',placeholder='FILTER',help='Also find the notes matching the given filter options or named filter, e.g. --or \"--tag work\".'" json:"or"`
+	Invert          bool     `kong:"group='filter',help='Find the notes which do not match the other filters.'" json:"invert"`
+	Explain         bool     `kong:"group='filter',help='Print the SQL queries used to find the notes, with their arguments and query plan.'" json:"-"`
+
+	Sort       []string `kong:"group='sort',short='s',type='sortterm',placeholder='TERM',help='Order the notes by the given criterion.'" json:"sort"`
+	RandomSeed int64    `kong:"group='sort',placeholder='SEED',help='Shuffle the notes in a reproducible order with --sort random.'" json:"randomSeed"`
+	AfterID    int64    `kong:"name='after-id',group='sort',placeholder='ID',help='Find the notes sorted after the one with the given ID, to fetch the next page of results. This requires --sort created or modified.'" json:"afterId"`
+	OrderFile  string   `kong:"group='sort',placeholder='PATH',help='List first the notes whose paths are listed in the given file, in the same order.'" json:"orderFile"`
 
 	// Deprecated
 	ExactMatch bool `kong:"hidden,short='e'" json:"exactMatch"`
+
+	// Named filters already expanded when reading each of the Or filters, to
+	// prevent infinite loops when expanding them in turn.
+	orExpandedFilters [][]string
 }
 
+// SortTermMapper is a kong option decoding the comma-separated --sort terms,
+// which may start with a `-` order marker otherwise mistaken for a short flag.
+var SortTermMapper = kong.NamedMapper("sortterm", kong.MapperFunc(func(ctx *kong.DecodeContext, target reflect.Value) error {
+	token := ctx.Scan.Pop()
+	if token.IsEOL() || token.InferredType() == kong.FlagToken {
+		return fmt.Errorf("expected a sorting term but got %q", token)
+	}
+	for _, term := range kong.SplitEscaped(fmt.Sprint(token.Value), ',') {
+		target.Set(reflect.Append(target, reflect.ValueOf(term)))
+	}
+	return nil
+}))
+
+// FilterMapper is a kong option decoding the --or filters, which start with
+// dashes otherwise mistaken for flags.
+var FilterMapper = kong.NamedMapper("filter", kong.MapperFunc(func(ctx *kong.DecodeContext, target reflect.Value) error {
+	token := ctx.Scan.Pop()
+	if token.IsEOL() {
+		return fmt.Errorf("expected a filter but got %q", token)
+	}
+	target.Set(reflect.Append(target, reflect.ValueOf(fmt.Sprint(token.Value))))
+	return nil
+}))
+
 // ExpandNamedFilters expands recursively any named filter found in the Path field.
 func (f Filtering) ExpandNamedFilters(filters map[string]string, expandedFilters []string) (Filtering, error) {
 	actualPaths := []string{}
 
 	for _, path := range f.Path {
-		if filter, ok := filters[path]; ok && !strings.Contains(expandedFilters, path) {
+		if filter, ok := filters[path]; ok && !strutil.Contains(expandedFilters, path) {
 			wrap := errors.Wrapperf("failed to expand named filter `%v`", path)
 
-			var parsedFilter Filtering
-			parser, err := kong.New(&parsedFilter)
-			if err != nil {
-				return f, wrap(err)
-			}
-			args, err := shellquote.Split(filter)
-			if err != nil {
-				return f, wrap(err)
-			}
-			_, err = parser.Parse(args)
+			parsedFilter, err := parseFiltering(filter)
 			if err != nil {
 				return f, wrap(err)
 			}
 
 			// Expand recursively, but prevent infinite loops by registering
 			// the current filter in the list of expanded filters.
-			parsedFilter, err = parsedFilter.ExpandNamedFilters(filters, append(expandedFilters, path))
+			pathExpandedFilters := append(append([]string{}, expandedFilters...), path)
+			parsedFilter, err = parsedFilter.ExpandNamedFilters(filters, pathExpandedFilters)
 			if err != nil {
 				return f, err
 			}
@@ -78,27 +149,89 @@ func (f Filtering) ExpandNamedFilters(filters map[string]string, expandedFilters
 			actualPaths = append(actualPaths, parsedFilter.Path...)
 			f.Exclude = append(f.Exclude, parsedFilter.Exclude...)
 			f.Tag = append(f.Tag, parsedFilter.Tag...)
+			f.Alias = append(f.Alias, parsedFilter.Alias...)
+			f.UntaggedMention = append(f.UntaggedMention, parsedFilter.UntaggedMention...)
 			f.Mention = append(f.Mention, parsedFilter.Mention...)
 			f.MentionedBy = append(f.MentionedBy, parsedFilter.MentionedBy...)
 			f.LinkTo = append(f.LinkTo, parsedFilter.LinkTo...)
+			f.LinkToTitle = append(f.LinkToTitle, parsedFilter.LinkToTitle...)
 			f.NoLinkTo = append(f.NoLinkTo, parsedFilter.NoLinkTo...)
+			f.LinkToAll = append(f.LinkToAll, parsedFilter.LinkToAll...)
 			f.LinkedBy = append(f.LinkedBy, parsedFilter.LinkedBy...)
 			f.NoLinkedBy = append(f.NoLinkedBy, parsedFilter.NoLinkedBy...)
+			f.LinkContext = append(f.LinkContext, parsedFilter.LinkContext...)
+			f.ExternalLinkTo = append(f.ExternalLinkTo, parsedFilter.ExternalLinkTo...)
 			f.Related = append(f.Related, parsedFilter.Related...)
+			f.ExcludeMetadata = append(f.ExcludeMetadata, parsedFilter.ExcludeMetadata...)
+			f.Has = append(f.Has, parsedFilter.Has...)
+			f.Dir = append(f.Dir, parsedFilter.Dir...)
+			f.CreatedYear = append(f.CreatedYear, parsedFilter.CreatedYear...)
+			f.ModifiedYear = append(f.ModifiedYear, parsedFilter.ModifiedYear...)
 			f.Sort = append(f.Sort, parsedFilter.Sort...)
+			for len(f.orExpandedFilters) < len(f.Or) {
+				f.orExpandedFilters = append(f.orExpandedFilters, expandedFilters)
+			}
+			for i := range parsedFilter.Or {
+				if i < len(parsedFilter.orExpandedFilters) {
+					f.orExpandedFilters = append(f.orExpandedFilters, parsedFilter.orExpandedFilters[i])
+				} else {
+					f.orExpandedFilters = append(f.orExpandedFilters, pathExpandedFilters)
+				}
+			}
+			f.Or = append(f.Or, parsedFilter.Or...)
 
 			f.ExactMatch = f.ExactMatch || parsedFilter.ExactMatch
 			f.Interactive = f.Interactive || parsedFilter.Interactive
+			f.MatchRaw = f.MatchRaw || parsedFilter.MatchRaw
+			f.Fuzzy = f.Fuzzy || parsedFilter.Fuzzy
+			f.MatchAny = f.MatchAny || parsedFilter.MatchAny
+			f.MentionAll = f.MentionAll || parsedFilter.MentionAll
+			f.Literal = f.Literal || parsedFilter.Literal
 			f.Orphan = f.Orphan || parsedFilter.Orphan
 			f.Tagless = f.Tagless || parsedFilter.Tagless
+			f.HasFrontmatter = f.HasFrontmatter || parsedFilter.HasFrontmatter
+			f.NoFrontmatter = f.NoFrontmatter || parsedFilter.NoFrontmatter
+			f.UntypedLinks = f.UntypedLinks || parsedFilter.UntypedLinks
+			f.IgnorePathCase = f.IgnorePathCase || parsedFilter.IgnorePathCase
 			f.Recursive = f.Recursive || parsedFilter.Recursive
-
-			if f.Limit == 0 {
-				f.Limit = parsedFilter.Limit
+			f.Undated = f.Undated || parsedFilter.Undated
+			f.AnomalousDates = f.AnomalousDates || parsedFilter.AnomalousDates
+			f.FutureDates = f.FutureDates || parsedFilter.FutureDates
+			f.Invert = f.Invert || parsedFilter.Invert
+			f.Explain = f.Explain || parsedFilter.Explain
+
+			f.Limit = f.Limit.Or(parsedFilter.Limit)
+			if f.MinBacklinks == 0 {
+				f.MinBacklinks = parsedFilter.MinBacklinks
 			}
+			f.MaxBacklinks = f.MaxBacklinks.Or(parsedFilter.MaxBacklinks)
 			if f.MaxDistance == 0 {
 				f.MaxDistance = parsedFilter.MaxDistance
 			}
+			if f.RandomSeed == 0 {
+				f.RandomSeed = parsedFilter.RandomSeed
+			}
+			if f.AfterID == 0 {
+				f.AfterID = parsedFilter.AfterID
+			}
+			if f.WordsTopPercent == 0 {
+				f.WordsTopPercent = parsedFilter.WordsTopPercent
+			}
+			if f.Stale == 0 {
+				f.Stale = parsedFilter.Stale
+			}
+			if f.ActivelyEdited == 0 {
+				f.ActivelyEdited = parsedFilter.ActivelyEdited
+			}
+			if f.OrderFile == "" {
+				f.OrderFile = parsedFilter.OrderFile
+			}
+			if f.PathRegex == "" {
+				f.PathRegex = parsedFilter.PathRegex
+			}
+			if f.BodyRegex == "" {
+				f.BodyRegex = parsedFilter.BodyRegex
+			}
 			if f.Created == "" {
 				f.Created = parsedFilter.Created
 			}
@@ -108,6 +241,9 @@ func (f Filtering) ExpandNamedFilters(filters map[string]string, expandedFilters
 			if f.CreatedAfter == "" {
 				f.CreatedAfter = parsedFilter.CreatedAfter
 			}
+			if f.CreatedBetween == "" {
+				f.CreatedBetween = parsedFilter.CreatedBetween
+			}
 			if f.Modified == "" {
 				f.Modified = parsedFilter.Modified
 			}
@@ -117,8 +253,21 @@ func (f Filtering) ExpandNamedFilters(filters map[string]string, expandedFilters
 			if f.ModifiedAfter == "" {
 				f.ModifiedAfter = parsedFilter.ModifiedAfter
 			}
+			if f.ModifiedBetween == "" {
+				f.ModifiedBetween = parsedFilter.ModifiedBetween
+			}
+			if f.CreatedLast == "" {
+				f.CreatedLast = parsedFilter.CreatedLast
+			}
+			if f.ModifiedLast == "" {
+				f.ModifiedLast = parsedFilter.ModifiedLast
+			}
+			if f.OnThisDay == "" {
+				f.OnThisDay = parsedFilter.OnThisDay
+			}
 
 			f.Match = append(f.Match, parsedFilter.Match...)
+			f.LeadMatch = append(f.LeadMatch, parsedFilter.LeadMatch...)
 			if f.MatchStrategy == "" {
 				f.MatchStrategy = parsedFilter.MatchStrategy
 			}
@@ -132,11 +281,32 @@ func (f Filtering) ExpandNamedFilters(filters map[string]string, expandedFilters
 	return f, nil
 }
 
+// parseFiltering parses a string of filtering flags, e.g. from a named filter.
+func parseFiltering(flags string) (Filtering, error) {
+	var filtering Filtering
+	parser, err := kong.New(&filtering, SortTermMapper, FilterMapper)
+	if err != nil {
+		return filtering, err
+	}
+	args, err := shellquote.Split(flags)
+	if err != nil {
+		return filtering, err
+	}
+	_, err = parser.Parse(args)
+	return filtering, err
+}
+
 // NewNoteFindOpts creates an instance of core.NoteFindOpts from a set of user flags.
 func (f Filtering) NewNoteFindOpts(notebook *core.Notebook) (core.NoteFindOpts, error) {
+	return f.newNoteFindOpts(notebook, []string{})
+}
+
+// newNoteFindOpts creates an instance of core.NoteFindOpts from a set of user
+// flags, without expanding again the given named filters.
+func (f Filtering) newNoteFindOpts(notebook *core.Notebook, expandedFilters []string) (core.NoteFindOpts, error) {
 	opts := core.NoteFindOpts{}
 
-	f, err := f.ExpandNamedFilters(notebook.Config.Filters, []string{})
+	f, err := f.ExpandNamedFilters(notebook.Config.Filters, expandedFilters)
 	if err != nil {
 		return opts, err
 	}
@@ -151,27 +321,85 @@ func (f Filtering) NewNoteFindOpts(notebook *core.Notebook) (core.NoteFindOpts,
 	if err != nil {
 		return opts, err
 	}
+	opts.MatchRaw = f.MatchRaw
+	opts.MatchLiteral = f.Literal
+	opts.MatchFuzzy = f.Fuzzy
+	opts.MatchAny = f.MatchAny
+	opts.LeadMatch = f.LeadMatch
+	opts.IgnoreHrefCase = f.IgnorePathCase
 
 	if paths, ok := relPaths(notebook, f.Path); ok {
 		opts.IncludeHrefs = paths
 	}
 
+	if len(f.Dir) > 0 {
+		if f.IgnorePathCase {
+			return opts, fmt.Errorf("--dir can't be used with --ignore-path-case")
+		}
+		for _, dir := range f.Dir {
+			path, err := notebook.RelPath(dir)
+			if err != nil {
+				return opts, err
+			}
+			opts.Dirs = append(opts.Dirs, path)
+		}
+	}
+
+	if f.PathRegex != "" {
+		if _, err := regexp.Compile(f.PathRegex); err != nil {
+			return opts, errors.Wrapf(err, "%s: invalid --path-regex", f.PathRegex)
+		}
+		opts.PathRegex = f.PathRegex
+	}
+
+	if f.BodyRegex != "" {
+		if _, err := regexp.Compile(f.BodyRegex); err != nil {
+			return opts, errors.Wrapf(err, "%s: invalid --body-regex", f.BodyRegex)
+		}
+		opts.BodyRegex = f.BodyRegex
+	}
+
 	if paths, ok := relPaths(notebook, f.Exclude); ok {
 		opts.ExcludeHrefs = paths
 	}
 
 	if len(f.Tag) > 0 {
 		opts.Tags = f.Tag
+		opts.TagPrefixes = notebook.Config.Format.Markdown.TagPrefixes
+	}
+
+	if len(f.Alias) > 0 {
+		opts.Aliases = f.Alias
+	}
+
+	// --untagged-mentions is a shortcut for --match <term> --tag "NOT <term>".
+	for _, term := range f.UntaggedMention {
+		if opts.MatchStrategy != core.MatchStrategyFts {
+			return opts, fmt.Errorf("--untagged-mentions can only be used with --match-strategy=fts")
+		}
+		tagQuery, err := untaggedMentionTagQuery(term)
+		if err != nil {
+			return opts, err
+		}
+		opts.Match = append(opts.Match, fts5.QuoteQuery(strings.TrimSpace(term)))
+		opts.Tags = append(opts.Tags, tagQuery)
 	}
 
 	if len(f.Mention) > 0 {
 		opts.Mention = f.Mention
+		opts.MentionAll = f.MentionAll
+	} else if f.MentionAll {
+		return opts, fmt.Errorf("--mention-all requires --mention")
 	}
 
 	if len(f.MentionedBy) > 0 {
 		opts.MentionedBy = f.MentionedBy
 	}
 
+	if len(opts.Mention) > 0 || len(opts.MentionedBy) > 0 {
+		opts.AliasKeys = notebook.Config.Format.Markdown.AliasKeys
+	}
+
 	if paths, ok := relPaths(notebook, f.LinkedBy); ok {
 		opts.LinkedBy = &core.LinkFilter{
 			Hrefs:       paths,
@@ -200,15 +428,169 @@ func (f Filtering) NewNoteFindOpts(notebook *core.Notebook) (core.NoteFindOpts,
 		}
 	}
 
+	if len(f.LinkContext) > 0 {
+		if opts.LinkedBy == nil && opts.LinkTo == nil {
+			return opts, errors.New("--link-context requires --link-to, --linked-by, --no-link-to or --no-linked-by")
+		}
+		if opts.LinkedBy != nil {
+			opts.LinkedBy.Contexts = f.LinkContext
+		}
+		if opts.LinkTo != nil {
+			opts.LinkTo.Contexts = f.LinkContext
+		}
+	}
+
+	if paths, ok := relPaths(notebook, f.LinkToAll); ok {
+		opts.LinkToAll = paths
+	}
+
+	if len(f.LinkToTitle) > 0 {
+		opts.LinkToTitles = f.LinkToTitle
+	}
+
+	if len(f.ExternalLinkTo) > 0 {
+		opts.ExternalLinkTo = f.ExternalLinkTo
+	}
+
 	if paths, ok := relPaths(notebook, f.Related); ok {
 		opts.Related = paths
 	}
 
 	opts.Orphan = f.Orphan
+
+	if f.MinBacklinks < 0 {
+		return opts, fmt.Errorf("%d: invalid --min-backlinks, expected a positive count", f.MinBacklinks)
+	}
+	opts.MinBacklinks = f.MinBacklinks
+
+	if f.MaxBacklinks.Unwrap() < 0 {
+		return opts, fmt.Errorf("%d: invalid --max-backlinks, expected a positive count", f.MaxBacklinks.Unwrap())
+	}
+	opts.MaxBacklinks = f.MaxBacklinks
 	opts.Tagless = f.Tagless
 
+	if f.HasFrontmatter && f.NoFrontmatter {
+		return opts, fmt.Errorf("--has-frontmatter and --no-frontmatter can't be used together")
+	} else if f.HasFrontmatter || f.NoFrontmatter {
+		opts.HasFrontmatter = opt.NewBool(f.HasFrontmatter)
+	}
+
+	for _, filter := range f.ExcludeMetadata {
+		key, value, ok := strings.Cut(filter, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return opts, fmt.Errorf("%s: invalid --exclude-metadata, expected KEY=VALUE", filter)
+		}
+		// Metadata keys are indexed in lowercase.
+		opts.ExcludeMetadata = append(opts.ExcludeMetadata, core.MetadataFilter{
+			Key:   strings.ToLower(key),
+			Value: value,
+		})
+	}
+	opts.UntypedLinks = f.UntypedLinks
+
+	for _, str := range f.Has {
+		feature, err := core.MarkdownFeatureFromString(str)
+		if err != nil {
+			return opts, err
+		}
+		opts.Has = append(opts.Has, feature)
+	}
+
+	if f.WordsTopPercent != 0 {
+		if f.WordsTopPercent < 0 || f.WordsTopPercent > 100 {
+			return opts, fmt.Errorf("%d: invalid --words-top-percent, expected a percentage between 1 and 100", f.WordsTopPercent)
+		}
+		opts.WordCountTopPercent = f.WordsTopPercent
+	}
+
+	if f.Stale < 0 {
+		return opts, fmt.Errorf("%d: invalid --stale, expected a positive number of days", f.Stale)
+	}
+	opts.StaleDays = f.Stale
+
+	if f.ActivelyEdited < 0 {
+		return opts, fmt.Errorf("%d: invalid --actively-edited, expected a positive number of days", f.ActivelyEdited)
+	}
+	opts.ActivelyEditedDays = f.ActivelyEdited
+	opts.Undated = f.Undated
+	opts.AnomalousDates = f.AnomalousDates
+
+	if f.CreatedBetween != "" {
+		if f.CreatedBefore != "" || f.CreatedAfter != "" {
+			return opts, fmt.Errorf("--created-between can't be used with --created-before or --created-after")
+		}
+		f.CreatedAfter, f.CreatedBefore, err = splitDateRange(f.CreatedBetween)
+		if err != nil {
+			return opts, err
+		}
+	}
+
+	if f.ModifiedBetween != "" {
+		if f.ModifiedBefore != "" || f.ModifiedAfter != "" {
+			return opts, fmt.Errorf("--modified-between can't be used with --modified-before or --modified-after")
+		}
+		f.ModifiedAfter, f.ModifiedBefore, err = splitDateRange(f.ModifiedBetween)
+		if err != nil {
+			return opts, err
+		}
+	}
+
+	if len(f.CreatedYear) > 0 {
+		if err := checkYears(f.CreatedYear, "--created-year"); err != nil {
+			return opts, err
+		}
+		opts.CreatedYears = f.CreatedYear
+	}
+
+	if len(f.ModifiedYear) > 0 {
+		if err := checkYears(f.ModifiedYear, "--modified-year"); err != nil {
+			return opts, err
+		}
+		opts.ModifiedYears = f.ModifiedYear
+	}
+
+	now := time.Now()
+
+	switch f.OnThisDay {
+	case "":
+	case "created":
+		opts.CreatedOnThisDay = &now
+	case "modified":
+		opts.ModifiedOnThisDay = &now
+	default:
+		return opts, fmt.Errorf("%s: invalid --on-this-day, expected a date field among: created, modified", f.OnThisDay)
+	}
+
+	if f.CreatedLast != "" {
+		if f.Created != "" || f.CreatedAfter != "" {
+			return opts, fmt.Errorf("--created-last can't be used with --created, --created-after or --created-between")
+		}
+		start, err := parseRecency(f.CreatedLast, now)
+		if err != nil {
+			return opts, err
+		}
+		opts.CreatedStart = &start
+	}
+
+	if f.ModifiedLast != "" {
+		if f.Modified != "" || f.ModifiedAfter != "" {
+			return opts, fmt.Errorf("--modified-last can't be used with --modified, --modified-after or --modified-between")
+		}
+		start, err := parseRecency(f.ModifiedLast, now)
+		if err != nil {
+			return opts, err
+		}
+		opts.ModifiedStart = &start
+	}
+
+	direction := dateutil.Past
+	if f.FutureDates {
+		direction = dateutil.Future
+	}
+
 	if f.Created != "" {
-		start, end, err := parseDayRange(f.Created)
+		start, end, err := parseDayRange(f.Created, direction)
 		if err != nil {
 			return opts, err
 		}
@@ -216,14 +598,14 @@ func (f Filtering) NewNoteFindOpts(notebook *core.Notebook) (core.NoteFindOpts,
 		opts.CreatedEnd = &end
 	} else {
 		if f.CreatedBefore != "" {
-			date, err := dateutil.TimeFromNatural(f.CreatedBefore)
+			date, err := dateutil.TimeFromNaturalInDirection(f.CreatedBefore, direction)
 			if err != nil {
 				return opts, err
 			}
 			opts.CreatedEnd = &date
 		}
 		if f.CreatedAfter != "" {
-			date, err := dateutil.TimeFromNatural(f.CreatedAfter)
+			date, err := dateutil.TimeFromNaturalInDirection(f.CreatedAfter, direction)
 			if err != nil {
 				return opts, err
 			}
@@ -232,7 +614,7 @@ func (f Filtering) NewNoteFindOpts(notebook *core.Notebook) (core.NoteFindOpts,
 	}
 
 	if f.Modified != "" {
-		start, end, err := parseDayRange(f.Modified)
+		start, end, err := parseDayRange(f.Modified, direction)
 		if err != nil {
 			return opts, err
 		}
@@ -240,14 +622,14 @@ func (f Filtering) NewNoteFindOpts(notebook *core.Notebook) (core.NoteFindOpts,
 		opts.ModifiedEnd = &end
 	} else {
 		if f.ModifiedBefore != "" {
-			date, err := dateutil.TimeFromNatural(f.ModifiedBefore)
+			date, err := dateutil.TimeFromNaturalInDirection(f.ModifiedBefore, direction)
 			if err != nil {
 				return opts, err
 			}
 			opts.ModifiedEnd = &date
 		}
 		if f.ModifiedAfter != "" {
-			date, err := dateutil.TimeFromNatural(f.ModifiedAfter)
+			date, err := dateutil.TimeFromNaturalInDirection(f.ModifiedAfter, direction)
 			if err != nil {
 				return opts, err
 			}
@@ -255,17 +637,65 @@ func (f Filtering) NewNoteFindOpts(notebook *core.Notebook) (core.NoteFindOpts,
 		}
 	}
 
-	sorters, err := core.NoteSortersFromStrings(f.Sort)
+	sortTerms, err := expandSortPresets(f.Sort, notebook.Config.Sorts)
+	if err != nil {
+		return opts, err
+	}
+	sorters, err := core.NoteSortersFromStrings(sortTerms)
 	if err != nil {
 		return opts, err
 	}
 	opts.Sorters = sorters
+	opts.RandomSeed = f.RandomSeed
+	opts.AfterID = core.NoteID(f.AfterID)
 
-	opts.Limit = f.Limit
+	if f.OrderFile != "" {
+		opts.PinnedPaths, err = readOrderFile(f.OrderFile)
+		if err != nil {
+			return opts, err
+		}
+	}
+
+	opts.Limit = f.Limit.Unwrap()
+
+	for i, flags := range f.Or {
+		alternative, err := parseFiltering(flags)
+		if err != nil {
+			return opts, errors.Wrapf(err, "failed to parse --or filter `%v`", flags)
+		}
+		altExpandedFilters := expandedFilters
+		if i < len(f.orExpandedFilters) {
+			altExpandedFilters = f.orExpandedFilters[i]
+		}
+		altOpts, err := alternative.newNoteFindOpts(notebook, altExpandedFilters)
+		if err != nil {
+			return opts, errors.Wrapf(err, "--or filter `%v`", flags)
+		}
+		opts.Or = append(opts.Or, altOpts)
+	}
+	opts.Invert = f.Invert
+	opts.Explain = f.Explain
 
 	return opts, nil
 }
 
+// untaggedMentionTagQuery returns the tag expression excluding the notes
+// tagged with the given --untagged-mentions term. The term must be a plain
+// tag name, without any operator or glob pattern.
+func untaggedMentionTagQuery(term string) (string, error) {
+	term = strings.TrimSpace(term)
+	valid := term != "" && !strings.ContainsAny(term, "()|*?[") && !strings.HasPrefix(term, "-")
+	for _, word := range strings.Fields(term) {
+		if word == "AND" || word == "OR" || strings.HasPrefix(word, "NOT") {
+			valid = false
+		}
+	}
+	if !valid {
+		return "", fmt.Errorf("%s: invalid --untagged-mentions, expected a tag name", term)
+	}
+	return "NOT " + term, nil
+}
+
 func relPaths(notebook *core.Notebook, paths []string) ([]string, bool) {
 	relPaths := make([]string, 0)
 	for _, p := range paths {
@@ -277,8 +707,57 @@ func relPaths(notebook *core.Notebook, paths []string) ([]string, bool) {
 	return relPaths, len(relPaths) > 0
 }
 
-func parseDayRange(date string) (start time.Time, end time.Time, err error) {
-	day, err := dateutil.TimeFromNatural(date)
+// expandSortPresets replaces the @name sort terms with the comma-separated
+// terms of the matching preset declared in the [sort] config section.
+func expandSortPresets(terms []string, presets map[string]string) ([]string, error) {
+	res := []string{}
+	for _, term := range terms {
+		if !strings.HasPrefix(term, "@") {
+			res = append(res, term)
+			continue
+		}
+
+		preset, ok := presets[term[1:]]
+		if !ok {
+			names := []string{}
+			for name := range presets {
+				names = append(names, "@"+name)
+			}
+			if len(names) == 0 {
+				return nil, fmt.Errorf("%s: unknown sort preset\ndeclare it in the [sort] config section", term)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("%s: unknown sort preset\ntry %s", term, strings.Join(names, ", "))
+		}
+		for _, presetTerm := range strings.Split(preset, ",") {
+			if presetTerm = strings.TrimSpace(presetTerm); presetTerm != "" {
+				res = append(res, presetTerm)
+			}
+		}
+	}
+	return res, nil
+}
+
+// readOrderFile reads the note paths listed in the given file, one per line
+// and relative to the notebook root. Blank lines are ignored.
+func readOrderFile(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s: failed to read --order-file", path)
+	}
+
+	paths := []string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			paths = append(paths, filepath.Clean(line))
+		}
+	}
+	return paths, nil
+}
+
+func parseDayRange(date string, direction dateutil.Direction) (start time.Time, end time.Time, err error) {
+	day, err := dateutil.TimeFromNaturalInDirection(date, direction)
 	if err != nil {
 		return
 	}
@@ -290,6 +769,91 @@ func parseDayRange(date string) (start time.Time, end time.Time, err error) {
 	return start, end, nil
 }
 
+// checkYears returns an error if one of the years given to the flag can't be
+// written with four digits, as they are compared with the stored dates.
+func checkYears(years []int, flag string) error {
+	for _, year := range years {
+		if year < 1 || year > 9999 {
+			return fmt.Errorf("%d: invalid year for %s", year, flag)
+		}
+	}
+	return nil
+}
+
+// splitDateRange splits a range of dates formatted as START..END. Either of
+// the bounds can be omitted for an open-ended range.
+func splitDateRange(dateRange string) (start string, end string, err error) {
+	bounds := strings.SplitN(dateRange, "..", 2)
+	if len(bounds) != 2 {
+		err = fmt.Errorf("%s: invalid date range, expected START..END", dateRange)
+		return
+	}
+
+	start = strings.TrimSpace(bounds[0])
+	end = strings.TrimSpace(bounds[1])
+	if start == "" && end == "" {
+		err = fmt.Errorf("%s: invalid date range, expected START..END", dateRange)
+	}
+	return
+}
+
+var recencyRegex = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*(mo|[dwy])`)
+
+// parseRecency returns the time at the given duration before now, e.g. 7d.
+//
+// The duration is made of one or more amounts suffixed by a unit among d
+// (days), w (weeks), mo (months) and y (years), e.g. 1.5d or 1y6mo. Whole
+// units are subtracted on the calendar of now, so the boundary keeps the same
+// local time of day.
+func parseRecency(duration string, now time.Time) (time.Time, error) {
+	invalid := fmt.Errorf("%s: invalid duration, expected e.g. 7d, 2w, 1mo or 1y", duration)
+
+	str := strings.TrimSpace(duration)
+	matches := recencyRegex.FindAllStringSubmatchIndex(str, -1)
+	if len(matches) == 0 {
+		return now, invalid
+	}
+
+	res := now
+	end := 0
+	for _, match := range matches {
+		if strings.TrimSpace(str[end:match[0]]) != "" {
+			return now, invalid
+		}
+		end = match[1]
+
+		amount, err := strconv.ParseFloat(str[match[2]:match[3]], 64)
+		if err != nil {
+			return now, invalid
+		}
+		whole := int(amount)
+		fraction := amount - float64(whole)
+
+		// Fractions of months and years are approximated in days.
+		var days float64
+		switch str[match[4]:match[5]] {
+		case "d":
+			res = res.AddDate(0, 0, -whole)
+			days = fraction
+		case "w":
+			res = res.AddDate(0, 0, -7*whole)
+			days = 7 * fraction
+		case "mo":
+			res = res.AddDate(0, -whole, 0)
+			days = 30 * fraction
+		case "y":
+			res = res.AddDate(-whole, 0, 0)
+			days = 365 * fraction
+		}
+		res = res.Add(-time.Duration(days * float64(24*time.Hour)))
+	}
+	if strings.TrimSpace(str[end:]) != "" {
+		return now, invalid
+	}
+
+	return res, nil
+}
+
 func startOfDay(t time.Time) time.Time {
 	year, month, day := t.Date()
 	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
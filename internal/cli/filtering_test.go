@@ -1,15 +1,19 @@
 package cli
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/zk-org/zk/internal/util/opt"
 	"github.com/zk-org/zk/internal/util/test/assert"
 )
 
 func TestExpandNamedFiltersNone(t *testing.T) {
 	f := Filtering{
 		Path:           []string{"path1"},
-		Limit:          10,
+		Limit:          opt.NewInt(10),
 		Interactive:    true,
 		Match:          []string{"match query"},
 		Exclude:        []string{"excl-path1", "excl-path2"},
@@ -46,23 +50,35 @@ func TestExpandNamedFiltersNone(t *testing.T) {
 // ExpandNamedFilters: list options are concatenated.
 func TestExpandNamedFiltersJoinLists(t *testing.T) {
 	f := Filtering{
-		Path:        []string{"path1", "f1", "f2"},
-		Exclude:     []string{"excl-path1", "excl-path2"},
-		Tag:         []string{"tag1", "tag2"},
-		Mention:     []string{"mention1", "mention2"},
-		MentionedBy: []string{"note1", "note2"},
-		LinkTo:      []string{"link1", "link2"},
-		NoLinkTo:    []string{"link3", "link4"},
-		LinkedBy:    []string{"linked1", "linked2"},
-		NoLinkedBy:  []string{"linked3", "linked4"},
-		Related:     []string{"related1", "related2"},
-		Sort:        []string{"title", "created"},
+		Path:            []string{"path1", "f1", "f2"},
+		Exclude:         []string{"excl-path1", "excl-path2"},
+		Tag:             []string{"tag1", "tag2"},
+		Alias:           []string{"alias1"},
+		UntaggedMention: []string{"term1"},
+		Mention:         []string{"mention1", "mention2"},
+		MentionedBy:     []string{"note1", "note2"},
+		LinkTo:          []string{"link1", "link2"},
+		NoLinkTo:        []string{"link3", "link4"},
+		LinkToAll:       []string{"all1"},
+		LinkToTitle:     []string{"title1"},
+		LinkedBy:        []string{"linked1", "linked2"},
+		NoLinkedBy:      []string{"linked3", "linked4"},
+		LinkContext:     []string{"context1"},
+		ExternalLinkTo:  []string{"domain1"},
+		Related:         []string{"related1", "related2"},
+		ExcludeMetadata: []string{"draft=true"},
+		Has:             []string{"code"},
+		Dir:             []string{"dir1"},
+		CreatedYear:     []int{2020},
+		ModifiedYear:    []int{2021},
+		Sort:            []string{"title", "created"},
+		Or:              []string{"--tag or1"},
 	}
 
 	res, err := f.ExpandNamedFilters(
 		map[string]string{
-			"f1": "path2 --exclude excl-path3 -x excl-path4 --tag tag3 -t tag4 --mention mention3,mention4 --mentioned-by note3",
-			"f2": "--link-to link5 --no-link-to link6 --linked-by linked5 --no-linked-by linked6 --related related3 --related related4 --sort random-",
+			"f1": "path2 --exclude excl-path3 -x excl-path4 --tag tag3 -t tag4 --alias alias2 --untagged-mentions term2 --mention mention3,mention4 --mentioned-by note3",
+			"f2": "--link-to link5 --no-link-to link6 --link-to-all all2 --link-to-title title2 --linked-by linked5 --no-linked-by linked6 --link-context context2 --external-link-to domain2 --related related3 --related related4 --exclude-metadata status=wip --has table,image --dir dir2 --created-year 2021 --modified-year 2022,2023 --sort random- --or '--tag or2,or3' --or \"--link-to 'a b'\"",
 		},
 		[]string{},
 	)
@@ -71,14 +87,26 @@ func TestExpandNamedFiltersJoinLists(t *testing.T) {
 	assert.Equal(t, res.Path, []string{"path1", "path2"})
 	assert.Equal(t, res.Exclude, []string{"excl-path1", "excl-path2", "excl-path3", "excl-path4"})
 	assert.Equal(t, res.Tag, []string{"tag1", "tag2", "tag3", "tag4"})
+	assert.Equal(t, res.Alias, []string{"alias1", "alias2"})
+	assert.Equal(t, res.UntaggedMention, []string{"term1", "term2"})
 	assert.Equal(t, res.Mention, []string{"mention1", "mention2", "mention3", "mention4"})
 	assert.Equal(t, res.MentionedBy, []string{"note1", "note2", "note3"})
 	assert.Equal(t, res.LinkTo, []string{"link1", "link2", "link5"})
 	assert.Equal(t, res.NoLinkTo, []string{"link3", "link4", "link6"})
+	assert.Equal(t, res.LinkToAll, []string{"all1", "all2"})
+	assert.Equal(t, res.LinkToTitle, []string{"title1", "title2"})
 	assert.Equal(t, res.LinkedBy, []string{"linked1", "linked2", "linked5"})
 	assert.Equal(t, res.NoLinkedBy, []string{"linked3", "linked4", "linked6"})
+	assert.Equal(t, res.LinkContext, []string{"context1", "context2"})
+	assert.Equal(t, res.ExternalLinkTo, []string{"domain1", "domain2"})
 	assert.Equal(t, res.Related, []string{"related1", "related2", "related3", "related4"})
+	assert.Equal(t, res.ExcludeMetadata, []string{"draft=true", "status=wip"})
+	assert.Equal(t, res.Has, []string{"code", "table", "image"})
+	assert.Equal(t, res.Dir, []string{"dir1", "dir2"})
+	assert.Equal(t, res.CreatedYear, []int{2020, 2021})
+	assert.Equal(t, res.ModifiedYear, []int{2021, 2022, 2023})
 	assert.Equal(t, res.Sort, []string{"title", "created", "random-"})
+	assert.Equal(t, res.Or, []string{"--tag or1", "--tag or2,or3", "--link-to 'a b'"})
 }
 
 // ExpandNamedFilters: boolean options are computed with disjunction.
@@ -90,7 +118,7 @@ func TestExpandNamedFiltersJoinBools(t *testing.T) {
 	res, err := f.ExpandNamedFilters(
 		map[string]string{
 			"f1": "--exact-match --interactive --orphan",
-			"f2": "--recursive",
+			"f2": "--recursive --match-raw --future-dates --untyped-links --ignore-path-case --undated --literal --anomalous-dates --fuzzy --invert --explain --match-any --mention-all --has-frontmatter --no-frontmatter",
 		},
 		[]string{},
 	)
@@ -100,6 +128,20 @@ func TestExpandNamedFiltersJoinBools(t *testing.T) {
 	assert.True(t, res.Interactive)
 	assert.True(t, res.Orphan)
 	assert.True(t, res.Recursive)
+	assert.True(t, res.MatchRaw)
+	assert.True(t, res.FutureDates)
+	assert.True(t, res.UntypedLinks)
+	assert.True(t, res.IgnorePathCase)
+	assert.True(t, res.Undated)
+	assert.True(t, res.Literal)
+	assert.True(t, res.AnomalousDates)
+	assert.True(t, res.Fuzzy)
+	assert.True(t, res.Invert)
+	assert.True(t, res.Explain)
+	assert.True(t, res.MatchAny)
+	assert.True(t, res.MentionAll)
+	assert.True(t, res.HasFrontmatter)
+	assert.True(t, res.NoFrontmatter)
 }
 
 // ExpandNamedFilters: non-zero integer and non-empty string options take precedence over named filters.
@@ -107,68 +149,111 @@ func TestExpandNamedFiltersJoinLitterals(t *testing.T) {
 	f1 := Filtering{Path: []string{"f1", "f2"}}
 	res1, err := f1.ExpandNamedFilters(
 		map[string]string{
-			"f1": "--limit 42 --created 'yesterday' --created-before '2 days ago' --created-after '3 days ago'",
-			"f2": "--max-distance 24 --modified 'tomorrow' --modified-before '2 days' --modified-after '3 days'",
+			"f1": "--limit 42 --random-seed 7 --after-id 12 --min-backlinks 2 --max-backlinks 0 --words-top-percent 15 --path-regex '^log/' --body-regex 'TODO' --created 'yesterday' --created-before '2 days ago' --created-after '3 days ago' --created-between '2020..2021' --created-last 30d --on-this-day created",
+			"f2": "--max-distance 24 --stale 3 --actively-edited 30 --order-file order.txt --modified 'tomorrow' --modified-before '2 days' --modified-after '3 days' --modified-between 'last month..' --modified-last 1w",
 		},
 		[]string{},
 	)
 	assert.Nil(t, err)
-	assert.Equal(t, res1.Limit, 42)
+	assert.Equal(t, res1.Limit, opt.NewInt(42))
 	assert.Equal(t, res1.MaxDistance, 24)
+	assert.Equal(t, res1.WordsTopPercent, 15)
+	assert.Equal(t, res1.RandomSeed, int64(7))
+	assert.Equal(t, res1.AfterID, int64(12))
+	assert.Equal(t, res1.MinBacklinks, 2)
+	assert.Equal(t, res1.MaxBacklinks, opt.NewInt(0))
+	assert.Equal(t, res1.Stale, 3)
+	assert.Equal(t, res1.ActivelyEdited, 30)
+	assert.Equal(t, res1.OrderFile, "order.txt")
+	assert.Equal(t, res1.PathRegex, "^log/")
+	assert.Equal(t, res1.BodyRegex, "TODO")
 	assert.Equal(t, res1.Created, "yesterday")
 	assert.Equal(t, res1.CreatedBefore, "2 days ago")
 	assert.Equal(t, res1.CreatedAfter, "3 days ago")
 	assert.Equal(t, res1.Modified, "tomorrow")
 	assert.Equal(t, res1.ModifiedBefore, "2 days")
 	assert.Equal(t, res1.ModifiedAfter, "3 days")
+	assert.Equal(t, res1.CreatedBetween, "2020..2021")
+	assert.Equal(t, res1.ModifiedBetween, "last month..")
+	assert.Equal(t, res1.CreatedLast, "30d")
+	assert.Equal(t, res1.ModifiedLast, "1w")
+	assert.Equal(t, res1.OnThisDay, "created")
 
 	f2 := Filtering{
-		Path:           []string{"f1", "f2"},
-		Limit:          10,
-		MaxDistance:    20,
-		Created:        "last week",
-		CreatedBefore:  "two weeks ago",
-		CreatedAfter:   "three weeks ago",
-		Modified:       "next week",
-		ModifiedBefore: "two weeks",
-		ModifiedAfter:  "three weeks",
+		Path:            []string{"f1", "f2"},
+		Limit:           opt.NewInt(10),
+		MaxDistance:     20,
+		WordsTopPercent: 5,
+		RandomSeed:      3,
+		AfterID:         5,
+		MinBacklinks:    1,
+		MaxBacklinks:    opt.NewInt(5),
+		Stale:           1,
+		ActivelyEdited:  60,
+		OrderFile:       "pinned.txt",
+		Created:         "last week",
+		CreatedBefore:   "two weeks ago",
+		CreatedAfter:    "three weeks ago",
+		Modified:        "next week",
+		ModifiedBefore:  "two weeks",
+		ModifiedAfter:   "three weeks",
+		CreatedBetween:  "2019..2020",
+		ModifiedBetween: "..yesterday",
+		CreatedLast:     "2y",
+		ModifiedLast:    "3mo",
+		OnThisDay:       "modified",
 	}
 	res2, err := f2.ExpandNamedFilters(
 		map[string]string{
-			"f1": "--limit 42 --created 'yesterday' --created-before '2 days ago' --created-after '3 days ago'",
-			"f2": "--max-distance 24 --modified 'tomorrow' --modified-before '2 days' --modified-after '3 days'",
+			"f1": "--limit 42 --random-seed 7 --after-id 12 --min-backlinks 2 --max-backlinks 0 --words-top-percent 15 --created 'yesterday' --created-before '2 days ago' --created-after '3 days ago' --created-last 30d",
+			"f2": "--max-distance 24 --stale 3 --actively-edited 30 --order-file order.txt --modified 'tomorrow' --modified-before '2 days' --modified-after '3 days' --modified-last 1w --on-this-day created",
 		},
 		[]string{},
 	)
 
 	assert.Nil(t, err)
-	assert.Equal(t, res2.Limit, 10)
+	assert.Equal(t, res2.Limit, opt.NewInt(10))
 	assert.Equal(t, res2.MaxDistance, 20)
+	assert.Equal(t, res2.WordsTopPercent, 5)
+	assert.Equal(t, res2.RandomSeed, int64(3))
+	assert.Equal(t, res2.AfterID, int64(5))
+	assert.Equal(t, res2.MinBacklinks, 1)
+	assert.Equal(t, res2.MaxBacklinks, opt.NewInt(5))
+	assert.Equal(t, res2.Stale, 1)
+	assert.Equal(t, res2.ActivelyEdited, 60)
+	assert.Equal(t, res2.OrderFile, "pinned.txt")
 	assert.Equal(t, res2.Created, "last week")
 	assert.Equal(t, res2.CreatedBefore, "two weeks ago")
 	assert.Equal(t, res2.CreatedAfter, "three weeks ago")
 	assert.Equal(t, res2.Modified, "next week")
 	assert.Equal(t, res2.ModifiedBefore, "two weeks")
 	assert.Equal(t, res2.ModifiedAfter, "three weeks")
+	assert.Equal(t, res2.CreatedBetween, "2019..2020")
+	assert.Equal(t, res2.ModifiedBetween, "..yesterday")
+	assert.Equal(t, res2.CreatedLast, "2y")
+	assert.Equal(t, res2.ModifiedLast, "3mo")
+	assert.Equal(t, res2.OnThisDay, "modified")
 }
 
 // ExpandNamedFilters: Match option predicates are cumulated with AND.
 func TestExpandNamedFiltersJoinMatch(t *testing.T) {
 	f := Filtering{
-		Path:  []string{"f1", "f2"},
-		Match: []string{"(chocolate OR caramel)"},
+		Path:      []string{"f1", "f2"},
+		Match:     []string{"(chocolate OR caramel)"},
+		LeadMatch: []string{"fruit"},
 	}
 
 	res, err := f.ExpandNamedFilters(
 		map[string]string{
 			"f1": "--match banana",
-			"f2": "--match apple",
+			"f2": "--match apple --lead-match recipe",
 		},
 		[]string{},
 	)
 
 	assert.Nil(t, err)
 	assert.Equal(t, res.Match, []string{"(chocolate OR caramel)", "banana", "apple"})
+	assert.Equal(t, res.LeadMatch, []string{"fruit", "recipe"})
 }
 
 func TestExpandNamedFiltersExpandsRecursively(t *testing.T) {
@@ -191,6 +276,19 @@ func TestExpandNamedFiltersExpandsRecursively(t *testing.T) {
 	assert.Equal(t, res.Sort, []string{"created"})
 }
 
+// ExpandNamedFilters: sorting terms may start with a `-` order marker.
+func TestExpandNamedFiltersParsesPrefixedSortTerms(t *testing.T) {
+	f := Filtering{Path: []string{"f1"}}
+
+	res, err := f.ExpandNamedFilters(
+		map[string]string{"f1": "--sort -created,title -s -path"},
+		[]string{},
+	)
+
+	assert.Nil(t, err)
+	assert.Equal(t, res.Sort, []string{"-created", "title", "-path"})
+}
+
 func TestExpandNamedFiltersReportsParsingError(t *testing.T) {
 	f := Filtering{Path: []string{"f1"}}
 
@@ -203,3 +301,107 @@ func TestExpandNamedFiltersReportsParsingError(t *testing.T) {
 
 	assert.Err(t, err, "failed to expand named filter `f1`: unknown flag --test")
 }
+
+func TestSplitDateRange(t *testing.T) {
+	test := func(dateRange string, expectedStart string, expectedEnd string) {
+		start, end, err := splitDateRange(dateRange)
+		assert.Nil(t, err)
+		assert.Equal(t, start, expectedStart)
+		assert.Equal(t, end, expectedEnd)
+	}
+
+	test("2020..2021", "2020", "2021")
+	test("last monday .. yesterday", "last monday", "yesterday")
+	test("..2021", "", "2021")
+	test("2020..", "2020", "")
+	test("2020-01-01..2020-02-01T10:00", "2020-01-01", "2020-02-01T10:00")
+
+	testErr := func(dateRange string) {
+		_, _, err := splitDateRange(dateRange)
+		assert.Err(t, err, dateRange+": invalid date range, expected START..END")
+	}
+
+	testErr("2020")
+	testErr("..")
+	testErr("")
+}
+
+func TestParseRecency(t *testing.T) {
+	now := time.Date(2021, time.June, 15, 14, 30, 0, 0, time.Local)
+
+	test := func(duration string, expected time.Time) {
+		actual, err := parseRecency(duration, now)
+		assert.Nil(t, err)
+		assert.Equal(t, actual, expected)
+	}
+
+	test("7d", time.Date(2021, time.June, 8, 14, 30, 0, 0, time.Local))
+	test(" 2w ", time.Date(2021, time.June, 1, 14, 30, 0, 0, time.Local))
+	test("1mo", time.Date(2021, time.May, 15, 14, 30, 0, 0, time.Local))
+	test("1y", time.Date(2020, time.June, 15, 14, 30, 0, 0, time.Local))
+	test("1.5d", time.Date(2021, time.June, 14, 2, 30, 0, 0, time.Local))
+	test("1y2mo3d", time.Date(2020, time.April, 12, 14, 30, 0, 0, time.Local))
+	test("1w 2d", time.Date(2021, time.June, 6, 14, 30, 0, 0, time.Local))
+
+	testErr := func(duration string) {
+		_, err := parseRecency(duration, now)
+		assert.Err(t, err, duration+": invalid duration, expected e.g. 7d, 2w, 1mo or 1y")
+	}
+
+	testErr("")
+	testErr("7")
+	testErr("3m")
+	testErr("d")
+	testErr("7d ago")
+	testErr("last 7d")
+}
+
+func TestReadOrderFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "order.txt")
+	err := os.WriteFile(path, []byte("books/rust.md\n\n  ./inbox/idea.md  \nindex.md"), 0644)
+	assert.Nil(t, err)
+
+	paths, err := readOrderFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, paths, []string{"books/rust.md", "inbox/idea.md", "index.md"})
+
+	_, err = readOrderFile("missing.txt")
+	assert.Err(t, err, "missing.txt: failed to read --order-file")
+}
+
+func TestExpandSortPresets(t *testing.T) {
+	presets := map[string]string{
+		"journal": "created-, title+",
+		"long":    "wc-",
+	}
+
+	terms, err := expandSortPresets([]string{"path", "@journal", "@long"}, presets)
+	assert.Nil(t, err)
+	assert.Equal(t, terms, []string{"path", "created-", "title+", "wc-"})
+
+	_, err = expandSortPresets([]string{"@unknown"}, presets)
+	assert.Err(t, err, "@unknown: unknown sort preset\ntry @journal, @long")
+
+	_, err = expandSortPresets([]string{"@unknown"}, map[string]string{})
+	assert.Err(t, err, "@unknown: unknown sort preset\ndeclare it in the [sort] config section")
+}
+
+func TestUntaggedMentionTagQuery(t *testing.T) {
+	test := func(term string, expected string) {
+		actual, err := untaggedMentionTagQuery(term)
+		assert.Nil(t, err)
+		assert.Equal(t, actual, expected)
+	}
+
+	test("golang", "NOT golang")
+	test(" golang ", "NOT golang")
+	test("well-known", "NOT well-known")
+	test("machine learning", "NOT machine learning")
+	test("project/zk", "NOT project/zk")
+
+	// The operators and glob patterns of the tag expressions are rejected.
+	for _, term := range []string{"", " ", "a|b", "(a)", "go*", "-draft", "NOT draft", "NOTES", "a AND b", "a OR b"} {
+		_, err := untaggedMentionTagQuery(term)
+		assert.Err(t, err, "invalid --untagged-mentions, expected a tag name")
+	}
+}
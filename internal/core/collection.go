@@ -2,8 +2,11 @@ package core
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"unicode/utf8"
+
+	strutil "github.com/zk-org/zk/internal/util/strings"
 )
 
 // Collection represents a collection, such as a tag.
@@ -18,6 +21,39 @@ type Collection struct {
 	NoteCount int `json:"note_count"`
 }
 
+// SimilarCollectionNames returns the names of the given collections which are
+// close to the given name, e.g. to suggest a fix for a typo. The closest names
+// come first.
+//
+// Nothing is returned if the name matches one of the collections, or if it is
+// too short to find meaningful suggestions.
+func SimilarCollectionNames(name string, collections []Collection) []string {
+	name = strings.ToLower(name)
+	// Allows one typo every three characters, up to two.
+	maxDistance := utf8.RuneCountInString(name) / 3
+	if maxDistance > 2 {
+		maxDistance = 2
+	}
+
+	distances := map[string]int{}
+	names := []string{}
+	for _, collection := range collections {
+		distance := strutil.EditDistance(name, strings.ToLower(collection.Name))
+		if distance == 0 {
+			return []string{}
+		}
+		if distance <= maxDistance {
+			distances[collection.Name] = distance
+			names = append(names, collection.Name)
+		}
+	}
+
+	sort.SliceStable(names, func(i, j int) bool {
+		return distances[names[i]] < distances[names[j]]
+	})
+	return names
+}
+
 // CollectionID represents the unique ID of a collection relative to a given
 // NoteIndex implementation.
 type CollectionID int64
@@ -38,7 +74,8 @@ func (id NoteCollectionID) IsValid() bool {
 type CollectionKind string
 
 const (
-	CollectionKindTag CollectionKind = "tag"
+	CollectionKindTag   CollectionKind = "tag"
+	CollectionKindAlias CollectionKind = "alias"
 )
 
 // CollectionRepository persists note collection across sessions.
@@ -0,0 +1,30 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/zk-org/zk/internal/util/test/assert"
+)
+
+func TestSimilarCollectionNames(t *testing.T) {
+	collections := []Collection{}
+	for _, name := range []string{"adventure", "fiction", "history", "Project", "projects", "science-fiction"} {
+		collections = append(collections, Collection{Kind: CollectionKindTag, Name: name})
+	}
+
+	test := func(name string, expected []string) {
+		assert.Equal(t, SimilarCollectionNames(name, collections), expected)
+	}
+
+	test("projct", []string{"Project", "projects"})
+	test("projets", []string{"projects", "Project"})
+	test("fictoin", []string{"fiction"})
+	test("histry", []string{"history"})
+	test("sience-fction", []string{"science-fiction"})
+	// Exact matches, regardless of the case.
+	test("project", []string{})
+	test("fiction", []string{})
+	// Too short or too different.
+	test("fic", []string{})
+	test("cooking", []string{})
+}
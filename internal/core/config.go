@@ -18,8 +18,10 @@ type Config struct {
 	Groups   map[string]GroupConfig
 	Format   FormatConfig
 	Tool     ToolConfig
+	List     ListConfig
 	LSP      LSPConfig
 	Filters  map[string]string
+	Sorts    map[string]string
 	Aliases  map[string]string
 	Extra    map[string]string
 }
@@ -56,6 +58,8 @@ func NewDefaultConfig() Config {
 				LinkFormat:        "markdown",
 				LinkEncodePath:    true,
 				LinkDropExtension: true,
+				AliasKeys:         DefaultAliasKeys,
+				TagPrefixes:       DefaultTagPrefixes,
 			},
 		},
 		LSP: LSPConfig{
@@ -72,6 +76,7 @@ func NewDefaultConfig() Config {
 			},
 		},
 		Filters: map[string]string{},
+		Sorts:   map[string]string{},
 		Aliases: map[string]string{},
 		Extra:   map[string]string{},
 	}
@@ -160,6 +165,13 @@ type MarkdownConfig struct {
 	LinkEncodePath bool
 	// Indicates whether a link's path file extension will be removed.
 	LinkDropExtension bool
+
+	// Frontmatter keys holding alternative names of a note, used to find
+	// mentions of its title.
+	AliasKeys []string
+	// Characters stripped from the start of the tags given to the tag
+	// filters, e.g. the # of a hashtag.
+	TagPrefixes string
 }
 
 // ToolConfig holds the external tooling configuration.
@@ -173,6 +185,13 @@ type ToolConfig struct {
 	FzfBindNew opt.String
 }
 
+// ListConfig holds the configuration of the `list` command.
+type ListConfig struct {
+	// Default number of notes listed when --limit is not given, 0 for no
+	// limit.
+	Limit int
+}
+
 // LSPConfig holds the Language Server Protocol configuration.
 type LSPConfig struct {
 	Completion  LSPCompletionConfig
@@ -389,6 +408,12 @@ func ParseConfig(content []byte, path string, parentConfig Config, isGlobal bool
 	if markdown.LinkDropExtension != nil {
 		config.Format.Markdown.LinkDropExtension = *markdown.LinkDropExtension
 	}
+	if markdown.AliasKeys != nil {
+		config.Format.Markdown.AliasKeys = append([]string{}, *markdown.AliasKeys...)
+	}
+	if markdown.TagPrefixes != nil {
+		config.Format.Markdown.TagPrefixes = *markdown.TagPrefixes
+	}
 
 	// Tool
 	tool := tomlConf.Tool
@@ -414,6 +439,14 @@ func ParseConfig(content []byte, path string, parentConfig Config, isGlobal bool
 		config.Tool.FzfBindNew = opt.NewStringWithPtr(tool.FzfBindNew)
 	}
 
+	// List
+	if tomlConf.List.Limit != nil {
+		if *tomlConf.List.Limit < 0 {
+			return config, wrap(errors.New("list.limit should not be negative"))
+		}
+		config.List.Limit = *tomlConf.List.Limit
+	}
+
 	// LSP completion
 	lspCompl := tomlConf.LSP.Completion
 	if lspCompl.NoteLabel != nil {
@@ -449,6 +482,13 @@ func ParseConfig(content []byte, path string, parentConfig Config, isGlobal bool
 		}
 	}
 
+	// Sort presets
+	if tomlConf.Sorts != nil {
+		for k, v := range tomlConf.Sorts {
+			config.Sorts[k] = v
+		}
+	}
+
 	// Aliases
 	if tomlConf.Aliases != nil {
 		for k, v := range tomlConf.Aliases {
@@ -519,9 +559,11 @@ type tomlConfig struct {
 	Groups   map[string]tomlGroupConfig `toml:"group"`
 	Format   tomlFormatConfig
 	Tool     tomlToolConfig
+	List     tomlListConfig
 	LSP      tomlLSPConfig
 	Extra    map[string]string
 	Filters  map[string]string `toml:"filter"`
+	Sorts    map[string]string `toml:"sort"`
 	Aliases  map[string]string `toml:"alias"`
 }
 
@@ -553,12 +595,14 @@ type tomlFormatConfig struct {
 }
 
 type tomlMarkdownConfig struct {
-	Hashtags          *bool   `toml:"hashtags"`
-	ColonTags         *bool   `toml:"colon-tags"`
-	MultiwordTags     *bool   `toml:"multiword-tags"`
-	LinkFormat        *string `toml:"link-format"`
-	LinkEncodePath    *bool   `toml:"link-encode-path"`
-	LinkDropExtension *bool   `toml:"link-drop-extension"`
+	Hashtags          *bool     `toml:"hashtags"`
+	ColonTags         *bool     `toml:"colon-tags"`
+	MultiwordTags     *bool     `toml:"multiword-tags"`
+	LinkFormat        *string   `toml:"link-format"`
+	LinkEncodePath    *bool     `toml:"link-encode-path"`
+	LinkDropExtension *bool     `toml:"link-drop-extension"`
+	AliasKeys         *[]string `toml:"alias-keys"`
+	TagPrefixes       *string   `toml:"tag-prefixes"`
 }
 
 type tomlToolConfig struct {
@@ -571,6 +615,10 @@ type tomlToolConfig struct {
 	FzfBindNew *string `toml:"fzf-bind-new"`
 }
 
+type tomlListConfig struct {
+	Limit *int
+}
+
 type tomlLSPConfig struct {
 	Completion struct {
 		NoteLabel              *string `toml:"note-label"`
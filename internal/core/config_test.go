@@ -39,6 +39,8 @@ func TestParseDefaultConfig(t *testing.T) {
 				LinkFormat:        "markdown",
 				LinkEncodePath:    true,
 				LinkDropExtension: true,
+				AliasKeys:         []string{"aliases"},
+				TagPrefixes:       "#",
 			},
 		},
 		Tool: ToolConfig{
@@ -55,6 +57,7 @@ func TestParseDefaultConfig(t *testing.T) {
 			},
 		},
 		Filters: make(map[string]string),
+		Sorts:   make(map[string]string),
 		Aliases: make(map[string]string),
 		Extra:   make(map[string]string),
 	})
@@ -90,6 +93,8 @@ func TestParseComplete(t *testing.T) {
 		link-format = "custom"
 		link-encode-path = true
 		link-drop-extension = false
+		alias-keys = ["aliases", "synonyms"]
+		tag-prefixes = "#@"
 
 		[tool]
 		editor = "vim"
@@ -100,6 +105,9 @@ func TestParseComplete(t *testing.T) {
 		fzf-options = "--border --height 40%"
 		fzf-bind-new = "Ctrl-C"
 
+		[list]
+		limit = 100
+
 		[extra]
 		hello = "world"
 		salut = "le monde"
@@ -108,6 +116,9 @@ func TestParseComplete(t *testing.T) {
 		recents = "--created-after '2 weeks ago'"
 		journal = "journal --sort created"
 
+		[sort]
+		journal = "title+, created-"
+
 		[alias]
 		ls = "zk list $@"
 		ed = "zk edit $@"
@@ -235,6 +246,8 @@ func TestParseComplete(t *testing.T) {
 				LinkFormat:        "custom",
 				LinkEncodePath:    true,
 				LinkDropExtension: false,
+				AliasKeys:         []string{"aliases", "synonyms"},
+				TagPrefixes:       "#@",
 			},
 		},
 		Tool: ToolConfig{
@@ -246,6 +259,9 @@ func TestParseComplete(t *testing.T) {
 			FzfOptions: opt.NewString("--border --height 40%"),
 			FzfBindNew: opt.NewString("Ctrl-C"),
 		},
+		List: ListConfig{
+			Limit: 100,
+		},
 		LSP: LSPConfig{
 			Completion: LSPCompletionConfig{
 				Note: LSPCompletionTemplates{
@@ -264,6 +280,9 @@ func TestParseComplete(t *testing.T) {
 			"recents": "--created-after '2 weeks ago'",
 			"journal": "journal --sort created",
 		},
+		Sorts: map[string]string{
+			"journal": "title+, created-",
+		},
 		Aliases: map[string]string{
 			"ls": "zk list $@",
 			"ed": "zk edit $@",
@@ -400,6 +419,8 @@ func TestParseMergesGroupConfig(t *testing.T) {
 				LinkFormat:        "markdown",
 				LinkEncodePath:    true,
 				LinkDropExtension: true,
+				AliasKeys:         []string{"aliases"},
+				TagPrefixes:       "#",
 			},
 		},
 		LSP: LSPConfig{
@@ -416,6 +437,7 @@ func TestParseMergesGroupConfig(t *testing.T) {
 			},
 		},
 		Filters: make(map[string]string),
+		Sorts:   make(map[string]string),
 		Aliases: make(map[string]string),
 		Extra: map[string]string{
 			"hello": "world",
@@ -543,6 +565,19 @@ func TestParseLSPDiagnosticsSeverity(t *testing.T) {
 	assert.Err(t, err, "foobar: unknown LSP diagnostic severity - may be none, hint, info, warning or error")
 }
 
+func TestParseListLimit(t *testing.T) {
+	conf, err := ParseConfig([]byte(""), ".zk/config.toml", NewDefaultConfig(), false)
+	assert.Nil(t, err)
+	assert.Equal(t, conf.List.Limit, 0)
+
+	toml := `
+		[list]
+		limit = -1
+	`
+	_, err = ParseConfig([]byte(toml), ".zk/config.toml", NewDefaultConfig(), false)
+	assert.Err(t, err, "list.limit should not be negative")
+}
+
 func TestGroupConfigExcludeGlobs(t *testing.T) {
 	// empty globs
 	config := GroupConfig{
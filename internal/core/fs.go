@@ -1,5 +1,7 @@
 package core
 
+import "time"
+
 // FileStorage is a port providing read and write access to a file storage.
 type FileStorage interface {
 
@@ -33,4 +35,8 @@ type FileStorage interface {
 	// Write creates or overwrite the content at the given file path, creating
 	// any intermediate directories if needed.
 	Write(path string, content []byte) error
+
+	// SetModified changes the modification time of the file at the given
+	// file path.
+	SetModified(path string, modified time.Time) error
 }
@@ -3,6 +3,7 @@ package core
 import (
 	"os"
 	"path/filepath"
+	"time"
 )
 
 // fileStorageMock implements an in-memory FileStorage for testing purposes.
@@ -79,3 +80,7 @@ func (fs *fileStorageMock) Write(path string, content []byte) error {
 	fs.files[path] = string(content)
 	return nil
 }
+
+func (fs *fileStorageMock) SetModified(path string, modified time.Time) error {
+	panic("not implemented")
+}
@@ -47,6 +47,9 @@ type Note struct {
 	Links []Link
 	// List of tags found in the content.
 	Tags []string
+	// List of aliases declared in the metadata, under the configured alias
+	// keys.
+	Aliases []string
 	// JSON dictionary of raw metadata extracted from the frontmatter.
 	Metadata map[string]interface{}
 	// Date of creation.
@@ -86,4 +89,35 @@ type ContextualNote struct {
 	Note
 	// List of context-sensitive excerpts from the note.
 	Snippets []string
+	// Relevance of the note for the full-text search query, a higher score
+	// being a better match. It is zero when no query was given.
+	Score float64
+	// Character offset of the first match of the query in the raw content of
+	// the note, or -1 if it could not be located. Only computed when
+	// NoteFindOpts.LocateMatch is set.
+	MatchOffset int
+	// Intermediate notes connecting the note to the ones given to
+	// NoteFindOpts.Related, explaining why it was found.
+	RelatedVia []RelatedBridge
+	// Notes linking to the note, ordered by title. Only loaded when
+	// NoteFindOpts.IncludeBacklinks is set.
+	Backlinks []Backlink
+}
+
+// Backlink is a note linking to another one.
+type Backlink struct {
+	ID    NoteID
+	Path  string
+	Title string
+}
+
+// RelatedBridge is a note linking a note found with NoteFindOpts.Related to
+// one of the given notes.
+type RelatedBridge struct {
+	ID   NoteID
+	Path string
+	// Indicates whether the path starts from the given note, which links to
+	// the bridge linking to the found note. Otherwise, the found note links
+	// to the bridge linking to the given note.
+	Outbound bool
 }
@@ -2,9 +2,12 @@ package core
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 	"unicode/utf8"
+
+	"github.com/zk-org/zk/internal/util/opt"
 )
 
 // NoteFindOpts holds a set of filtering options used to find notes.
@@ -13,33 +16,101 @@ type NoteFindOpts struct {
 	Match []string
 	// Text matching strategy used with Match.
 	MatchStrategy MatchStrategy
+	// Indicates whether Match searches the raw content of the notes, including
+	// their Markdown markup and frontmatter. The full-text search index only
+	// covers the path, title and processed body of the notes, so
+	// MatchStrategyFts falls back on MatchStrategyExact in this case.
+	MatchRaw bool
+	// Indicates whether the Match and LeadMatch queries are searched
+	// literally, without interpreting any operator of the match strategy.
+	MatchLiteral bool
+	// Indicates whether the Match queries tolerate typos, using the trigram
+	// index instead of the standard full-text one. Only supported with
+	// MatchStrategyFts.
+	MatchFuzzy bool
+	// Indicates whether the Match and LeadMatch queries find the notes
+	// matching any of their terms, instead of all of them. Only supported
+	// with MatchStrategyFts.
+	MatchAny bool
+	// Filter to select notes whose lead, i.e. first paragraph, matches the
+	// given full-text search queries.
+	LeadMatch []string
 	// Filter by note hrefs.
 	IncludeHrefs []string
 	// Filter excluding notes at the given hrefs.
 	ExcludeHrefs []string
+	// Filter by directories, relative to the notebook root. The notes in
+	// their subdirectories are included.
+	Dirs []string
+	// Filter by a regular expression matched against the note paths.
+	PathRegex string
+	// Filter by a regular expression matched against the note bodies.
+	BodyRegex string
 	// Indicates whether href options can match any portion of a path.
 	// This is used for wiki links.
 	AllowPartialHrefs bool
+	// Indicates whether href options match the note paths regardless of
+	// their case, e.g. for case-insensitive file systems.
+	IgnoreHrefCase bool
 	// Filter including notes with the given IDs.
 	IncludeIDs []NoteID
 	// Filter excluding notes with the given IDs.
 	ExcludeIDs []NoteID
 	// Filter by tags found in the notes.
 	Tags []string
+	// Characters stripped from the start of the tags of the Tags expressions,
+	// which are not part of the indexed tags.
+	TagPrefixes string
+	// Filter the notes declaring any of the given aliases in their metadata.
+	Aliases []string
 	// Filter the notes mentioning the given ones.
 	Mention []string
+	// Require the notes to mention all the Mention notes, instead of any of
+	// them.
+	MentionAll bool
 	// Filter the notes mentioned by the given ones.
 	MentionedBy []string
+	// Metadata keys holding alternative names of the notes, matched with
+	// Mention and MentionedBy in addition to their titles. Defaults to
+	// DefaultAliasKeys when nil.
+	AliasKeys []string
 	// Filter to select notes being linked by another one.
 	LinkedBy *LinkFilter
 	// Filter to select notes linking to another one.
 	LinkTo *LinkFilter
+	// Filter to select notes linking to every one of the given notes hrefs.
+	LinkToAll []string
+	// Filter to select notes linking to any of the notes with the given
+	// titles.
+	LinkToTitles []string
+	// Filter to select notes having an external link whose href contains
+	// one of the given domains.
+	ExternalLinkTo []string
 	// Filter to select notes which could might be related to the given notes hrefs.
 	Related []string
 	// Filter to select notes having no other notes linking to them.
 	Orphan bool
+	// Filter to select notes linked by at least the given number of other
+	// notes.
+	MinBacklinks int
+	// Filter to select notes linked by at most the given number of other
+	// notes.
+	MaxBacklinks opt.Int
 	// Filter to select notes having no tags.
 	Tagless bool
+	// Filter to select notes having a frontmatter when true, or missing one
+	// when false. A frontmatter without any key counts as missing.
+	HasFrontmatter opt.Bool
+	// Filter excluding notes whose metadata match any of the given key/value
+	// pairs. Notes without the key are kept.
+	ExcludeMetadata []MetadataFilter
+	// Filter to select notes having internal links without any relation.
+	UntypedLinks bool
+	// Filter to select notes containing all the given Markdown features.
+	Has []MarkdownFeature
+	// Filter the notes among the given percentage of the longest notes of the
+	// notebook, by word count.
+	WordCountTopPercent int
 	// Filter notes created after the given date.
 	CreatedStart *time.Time
 	// Filter notes created before the given date.
@@ -48,12 +119,88 @@ type NoteFindOpts struct {
 	ModifiedStart *time.Time
 	// Filter notes modified before the given date.
 	ModifiedEnd *time.Time
+	// Filter notes created during any of the given years.
+	CreatedYears []int
+	// Filter notes modified during any of the given years.
+	ModifiedYears []int
+	// Filter notes created on the month and day of the given date, during
+	// an earlier year. The days are compared in the local time zone.
+	CreatedOnThisDay *time.Time
+	// Filter notes modified on the month and day of the given date, during
+	// an earlier year. The days are compared in the local time zone.
+	ModifiedOnThisDay *time.Time
+	// Filter notes modified less than the given number of days after their
+	// creation, e.g. stubs which were never revisited.
+	StaleDays int
+	// Filter notes modified at least the given number of days after their
+	// creation.
+	ActivelyEditedDays int
+	// Filter to select notes without a creation date, i.e. holding the zero
+	// time.
+	Undated bool
+	// Filter to select notes modified before their creation date, which
+	// usually reveals timestamps swapped by an import.
+	AnomalousDates bool
+	// Alternative sets of filters: the notes matching any of them are found
+	// in addition to the notes matching the other filters. Only their
+	// filtering criteria are used, not their sorting or limit.
+	Or []NoteFindOpts
+	// Indicates whether the complement of the filters is found instead,
+	// that is the notes which don't match them.
+	Invert bool
 	// Limits the number of results
 	Limit int
 	// Sorting criteria
 	Sorters []NoteSorter
+	// Paths of the notes listed first, in the given order, before the notes
+	// ordered by Sorters.
+	PinnedPaths []string
+	// Seed used to shuffle the notes in a reproducible order with
+	// NoteSortRandom. The order changes on every run when zero.
+	RandomSeed int64
+	// Finds only the notes sorted after the one with this ID, to fetch the
+	// next page of results without an offset. Sorters must hold a single
+	// NoteSortCreated or NoteSortModified criterion.
+	AfterID NoteID
+	// Leaves the body and raw content of the found notes empty, to reduce
+	// the amount of data loaded when they are not needed.
+	ExcludeBody bool
+	// Computes the offset of the first match in the raw content of the found
+	// notes, e.g. to open a note at the match location. This also works with
+	// ExcludeBody, at the cost of loading the raw content of each note.
+	LocateMatch bool
+	// Loads the notes linking to each found note, with an additional query.
+	IncludeBacklinks bool
+	// Part of the notes from which the snippets are extracted.
+	SnippetSource SnippetSource
+	// Maximum number of characters of the link snippets concatenated for a
+	// single note, e.g. a hub linked by many notes. Unlimited when zero.
+	MaxLinkSnippetsLength int
+	// Marker inserted before the matched terms in the snippets, defaults to
+	// DefaultMatchOpen.
+	MatchOpen opt.String
+	// Marker inserted after the matched terms in the snippets, defaults to
+	// DefaultMatchClose.
+	MatchClose opt.String
+	// Reports the SQL queries executed to find the notes, with their bound
+	// arguments and query plan, to debug unexpected results or slow queries.
+	Explain bool
 }
 
+// Default markers surrounding the matched terms in the note snippets.
+const (
+	DefaultMatchOpen  = "<zk:match>"
+	DefaultMatchClose = "</zk:match>"
+)
+
+// DefaultAliasKeys are the metadata keys holding the aliases of a note, like
+// in Obsidian: https://publish.obsidian.md/help/How+to/Add+aliases+to+note
+var DefaultAliasKeys = []string{"aliases"}
+
+// DefaultTagPrefixes are the characters stripped from the tag filters, as
+// the #hashtags are indexed without their #.
+var DefaultTagPrefixes = "#"
+
 // IncludingIDs creates a new FinderOpts after adding the given IDs to the list
 // of excluded note IDs.
 func (o NoteFindOpts) IncludingIDs(ids []NoteID) NoteFindOpts {
@@ -65,6 +212,25 @@ func (o NoteFindOpts) IncludingIDs(ids []NoteID) NoteFindOpts {
 	return o
 }
 
+// WithoutFilters creates a new FinderOpts keeping only the options
+// controlling the order and presentation of the results.
+func (o NoteFindOpts) WithoutFilters() NoteFindOpts {
+	return NoteFindOpts{
+		Limit:                 o.Limit,
+		Sorters:               o.Sorters,
+		PinnedPaths:           o.PinnedPaths,
+		RandomSeed:            o.RandomSeed,
+		AfterID:               o.AfterID,
+		ExcludeBody:           o.ExcludeBody,
+		LocateMatch:           o.LocateMatch,
+		SnippetSource:         o.SnippetSource,
+		MaxLinkSnippetsLength: o.MaxLinkSnippetsLength,
+		MatchOpen:             o.MatchOpen,
+		MatchClose:            o.MatchClose,
+		Explain:               o.Explain,
+	}
+}
+
 // ExcludingIDs creates a new FinderOpts after adding the given IDs to the list
 // of excluded note IDs.
 func (o NoteFindOpts) ExcludingIDs(ids []NoteID) NoteFindOpts {
@@ -82,12 +248,28 @@ type LinkFilter struct {
 	Negate      bool
 	Recursive   bool
 	MaxDistance int
+	// Terms which must all be found in the snippet surrounding the links,
+	// ignoring the case.
+	Contexts []string
+}
+
+// MetadataFilter is a note filter matching a value of the note metadata.
+//
+// When the metadata value is a list, any of its items can match.
+type MetadataFilter struct {
+	Key   string
+	Value string
 }
 
 // NoteSorter represents an order term used to sort a list of notes.
 type NoteSorter struct {
 	Field     NoteSortField
 	Ascending bool
+	// NullsFirst lists the notes without any value for the field before the
+	// others, instead of after.
+	NullsFirst bool
+	// MetadataKey is the frontmatter key sorted by a NoteSortMetadata sorter.
+	MetadataKey string
 }
 
 // NoteSortField represents a note field used to sort a list of notes.
@@ -106,6 +288,16 @@ const (
 	NoteSortTitle
 	// Sort by the number of words in the note bodies.
 	NoteSortWordCount
+	// Sort by the modification date of the most recent note linking to them.
+	NoteSortLinked
+	// Sort by the number of characters in the note titles.
+	NoteSortTitleLength
+	// Sort by the file names, ignoring their parent directories.
+	NoteSortFilename
+	// Sort by the value of a metadata key.
+	NoteSortMetadata
+	// Sort by the number of occurrences of the --match terms in the notes.
+	NoteSortMentions
 )
 
 // NoteSortersFromStrings returns a list of NoteSorter from their string
@@ -127,14 +319,28 @@ func NoteSortersFromStrings(strs []string) ([]NoteSorter, error) {
 
 // NoteSorterFromString returns a NoteSorter from its string representation.
 //
-// If the input str has for suffix `+`, then the order will be ascending, while
-// descending for `-`. If no suffix is given, then the default order for the
-// sorting field will be used.
+// If the input str has for suffix or prefix `+`, then the order will be
+// ascending, while descending for `-`. If no marker is given, then the default
+// order for the sorting field will be used.
 func NoteSorterFromString(str string) (NoteSorter, error) {
 	orderSymbol, _ := utf8.DecodeLastRuneInString(str)
-	str = strings.TrimRight(str, "+-")
+	if prefix, _ := utf8.DecodeRuneInString(str); prefix == '+' || prefix == '-' {
+		if orderSymbol == '+' || orderSymbol == '-' {
+			return NoteSorter{}, fmt.Errorf("%s: the order can't be given both as a prefix and a suffix", str)
+		}
+		orderSymbol = prefix
+	}
+	str = strings.Trim(str, "+-")
 
 	var sorter NoteSorter
+	if key, ok := strings.CutPrefix(str, "meta:"); ok {
+		if key == "" {
+			return sorter, fmt.Errorf("%s: missing metadata key", str)
+		}
+		str = "meta"
+		sorter.MetadataKey = key
+	}
+
 	switch str {
 	case "created", "c":
 		sorter = NoteSorter{Field: NoteSortCreated, Ascending: false}
@@ -148,8 +354,18 @@ func NoteSorterFromString(str string) (NoteSorter, error) {
 		sorter = NoteSorter{Field: NoteSortRandom, Ascending: true}
 	case "word-count", "wc":
 		sorter = NoteSorter{Field: NoteSortWordCount, Ascending: true}
+	case "linked", "l":
+		sorter = NoteSorter{Field: NoteSortLinked, Ascending: false}
+	case "title-length", "tl":
+		sorter = NoteSorter{Field: NoteSortTitleLength, Ascending: true}
+	case "filename", "f":
+		sorter = NoteSorter{Field: NoteSortFilename, Ascending: true}
+	case "mentions":
+		sorter = NoteSorter{Field: NoteSortMentions, Ascending: false}
+	case "meta":
+		sorter = NoteSorter{Field: NoteSortMetadata, Ascending: true, MetadataKey: sorter.MetadataKey}
 	default:
-		return sorter, fmt.Errorf("%s: unknown sorting term\ntry created, modified, path, title, random or word-count", str)
+		return sorter, fmt.Errorf("%s: unknown sorting term\ntry created, modified, path, filename, title, title-length, random, word-count, linked, mentions or meta:<key>", str)
 	}
 
 	switch orderSymbol {
@@ -187,3 +403,127 @@ func MatchStrategyFromString(str string) (MatchStrategy, error) {
 		return 0, fmt.Errorf("%s: unknown match strategy\ntry fts (full-text search), re (regular expression) or exact", str)
 	}
 }
+
+// MarkdownFeature represents a Markdown construct found in the raw content of
+// a note.
+type MarkdownFeature int
+
+const (
+	// Fenced code block.
+	MarkdownFeatureCode MarkdownFeature = iota + 1
+	// Table, detected by its delimiter row.
+	MarkdownFeatureTable
+	// Inline image.
+	MarkdownFeatureImage
+	// Regular link, wiki link or autolink.
+	MarkdownFeatureLink
+	// Task list item, checked or not, e.g. `- [ ] Buy milk`.
+	MarkdownFeatureTask
+	// Unchecked task list item.
+	MarkdownFeatureOpenTask
+)
+
+// MarkdownFeatureFromString returns a MarkdownFeature from its string
+// representation.
+func MarkdownFeatureFromString(str string) (MarkdownFeature, error) {
+	switch str {
+	case "code":
+		return MarkdownFeatureCode, nil
+	case "table":
+		return MarkdownFeatureTable, nil
+	case "image":
+		return MarkdownFeatureImage, nil
+	case "link":
+		return MarkdownFeatureLink, nil
+	case "task":
+		return MarkdownFeatureTask, nil
+	case "open-task":
+		return MarkdownFeatureOpenTask, nil
+	default:
+		return 0, fmt.Errorf("%s: unknown Markdown feature\ntry code, table, image, link, task or open-task", str)
+	}
+}
+
+// String returns the representation of the feature accepted by
+// MarkdownFeatureFromString, or its number if it is unknown.
+func (f MarkdownFeature) String() string {
+	switch f {
+	case MarkdownFeatureCode:
+		return "code"
+	case MarkdownFeatureTable:
+		return "table"
+	case MarkdownFeatureImage:
+		return "image"
+	case MarkdownFeatureLink:
+		return "link"
+	case MarkdownFeatureTask:
+		return "task"
+	case MarkdownFeatureOpenTask:
+		return "open-task"
+	default:
+		return strconv.Itoa(int(f))
+	}
+}
+
+// SnippetSource represents the part of a note from which its snippets are
+// extracted.
+type SnippetSource int
+
+const (
+	// Excerpts surrounding the matched terms or links, falling back on the
+	// lead of the note. This is the default source.
+	SnippetSourceBody SnippetSource = iota
+	// First paragraph of the note, even when matching terms.
+	SnippetSourceLead
+)
+
+// SnippetSourceFromString returns a SnippetSource from its string
+// representation.
+func SnippetSourceFromString(str string) (SnippetSource, error) {
+	switch str {
+	case "body", "b", "":
+		return SnippetSourceBody, nil
+	case "lead", "l":
+		return SnippetSourceLead, nil
+	default:
+		return 0, fmt.Errorf("%s: unknown snippet source\ntry body or lead", str)
+	}
+}
+
+// DateBucket represents a period of time used to group notes by date.
+type DateBucket int
+
+const (
+	// Group notes by day.
+	DateBucketDay DateBucket = iota + 1
+	// Group notes by week.
+	DateBucketWeek
+	// Group notes by month.
+	DateBucketMonth
+	// Group notes by year.
+	DateBucketYear
+)
+
+// DateBucketFromString returns a DateBucket from its string representation.
+func DateBucketFromString(str string) (DateBucket, error) {
+	switch str {
+	case "day", "d":
+		return DateBucketDay, nil
+	case "week", "w":
+		return DateBucketWeek, nil
+	case "month", "m":
+		return DateBucketMonth, nil
+	case "year", "y":
+		return DateBucketYear, nil
+	default:
+		return 0, fmt.Errorf("%s: unknown date bucket\ntry day, week, month or year", str)
+	}
+}
+
+// DateBucketCount holds the number of notes found in a period of time.
+type DateBucketCount struct {
+	// Label of the period, e.g. 2021-03 for a month.
+	Bucket string
+	// Number of notes found in this period.
+	Count int
+}
@@ -38,9 +38,44 @@ func TestNoteSorterFromString(t *testing.T) {
 	test("wc", NoteSortWordCount, true)
 	test("word-count", NoteSortWordCount, true)
 	test("word-count-", NoteSortWordCount, false)
+	test("l", NoteSortLinked, false)
+	test("linked", NoteSortLinked, false)
+	test("linked+", NoteSortLinked, true)
+	test("tl", NoteSortTitleLength, true)
+	test("title-length", NoteSortTitleLength, true)
+	test("title-length-", NoteSortTitleLength, false)
+	test("f", NoteSortFilename, true)
+	test("filename", NoteSortFilename, true)
+	test("filename-", NoteSortFilename, false)
+	test("mentions", NoteSortMentions, false)
+	test("mentions+", NoteSortMentions, true)
+
+	// The order can also be given as a prefix.
+	test("-path", NoteSortPath, false)
+	test("+created", NoteSortCreated, true)
+	test("-word-count", NoteSortWordCount, false)
+	test("-title-length", NoteSortTitleLength, false)
 
 	_, err := NoteSorterFromString("foobar")
 	assert.Err(t, err, "foobar: unknown sorting term")
+	_, err = NoteSorterFromString("-title+")
+	assert.Err(t, err, "-title+: the order can't be given both as a prefix and a suffix")
+}
+
+func TestNoteSorterFromStringWithMetadata(t *testing.T) {
+	test := func(str string, expectedKey string, expectedAscending bool) {
+		actual, err := NoteSorterFromString(str)
+		assert.Nil(t, err)
+		assert.Equal(t, actual, NoteSorter{Field: NoteSortMetadata, Ascending: expectedAscending, MetadataKey: expectedKey})
+	}
+
+	test("meta:priority", "priority", true)
+	test("meta:priority-", "priority", false)
+	test("-meta:priority", "priority", false)
+	test("meta:due-date+", "due-date", true)
+
+	_, err := NoteSorterFromString("meta:")
+	assert.Err(t, err, "meta:: missing metadata key")
 }
 
 func TestSortersFromStrings(t *testing.T) {
@@ -88,3 +123,61 @@ func TestMatchStrategyFromString(t *testing.T) {
 	_, err := MatchStrategyFromString("foobar")
 	assert.Err(t, err, "foobar: unknown match strategy\ntry fts (full-text search), re (regular expression) or exact")
 }
+
+func TestMarkdownFeatureFromString(t *testing.T) {
+	test := func(str string, expected MarkdownFeature) {
+		actual, err := MarkdownFeatureFromString(str)
+		assert.Nil(t, err)
+		assert.Equal(t, actual, expected)
+		assert.Equal(t, actual.String(), str)
+	}
+
+	test("code", MarkdownFeatureCode)
+	test("table", MarkdownFeatureTable)
+	test("image", MarkdownFeatureImage)
+	test("link", MarkdownFeatureLink)
+	test("task", MarkdownFeatureTask)
+	test("open-task", MarkdownFeatureOpenTask)
+
+	_, err := MarkdownFeatureFromString("foobar")
+	assert.Err(t, err, "foobar: unknown Markdown feature\ntry code, table, image, link, task or open-task")
+
+	assert.Equal(t, MarkdownFeature(42).String(), "42")
+}
+
+func TestSnippetSourceFromString(t *testing.T) {
+	test := func(str string, expected SnippetSource) {
+		actual, err := SnippetSourceFromString(str)
+		assert.Nil(t, err)
+		assert.Equal(t, actual, expected)
+	}
+
+	test("", SnippetSourceBody)
+	test("b", SnippetSourceBody)
+	test("body", SnippetSourceBody)
+	test("l", SnippetSourceLead)
+	test("lead", SnippetSourceLead)
+
+	_, err := SnippetSourceFromString("foobar")
+	assert.Err(t, err, "foobar: unknown snippet source\ntry body or lead")
+}
+
+func TestDateBucketFromString(t *testing.T) {
+	test := func(str string, expected DateBucket) {
+		actual, err := DateBucketFromString(str)
+		assert.Nil(t, err)
+		assert.Equal(t, actual, expected)
+	}
+
+	test("d", DateBucketDay)
+	test("day", DateBucketDay)
+	test("w", DateBucketWeek)
+	test("week", DateBucketWeek)
+	test("m", DateBucketMonth)
+	test("month", DateBucketMonth)
+	test("y", DateBucketYear)
+	test("year", DateBucketYear)
+
+	_, err := DateBucketFromString("foobar")
+	assert.Err(t, err, "foobar: unknown date bucket")
+}
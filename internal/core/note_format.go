@@ -3,6 +3,7 @@ package core
 import (
 	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"regexp"
 	"time"
 )
@@ -32,10 +33,46 @@ func newNoteFormatter(basePath string, template Template, linkFormatter LinkForm
 			snippets = append(snippets, noteTermRegex.ReplaceAllString(snippet, termRepl))
 		}
 
+		var relatedVia []noteFormatBridge
+		for _, bridge := range note.RelatedVia {
+			bridgePath := NotebookPath{
+				Path:       bridge.Path,
+				BasePath:   basePath,
+				WorkingDir: fs.WorkingDir(),
+			}
+			relBridgePath, err := bridgePath.PathRelToWorkingDir()
+			if err != nil {
+				return "", err
+			}
+			relatedVia = append(relatedVia, noteFormatBridge{
+				Path:     relBridgePath,
+				Outbound: bridge.Outbound,
+			})
+		}
+
+		var backlinks []noteFormatBacklink
+		for _, backlink := range note.Backlinks {
+			backlinkPath := NotebookPath{
+				Path:       backlink.Path,
+				BasePath:   basePath,
+				WorkingDir: fs.WorkingDir(),
+			}
+			relBacklinkPath, err := backlinkPath.PathRelToWorkingDir()
+			if err != nil {
+				return "", err
+			}
+			backlinks = append(backlinks, noteFormatBacklink{
+				Path:  relBacklinkPath,
+				Title: backlink.Title,
+			})
+		}
+
 		return template.Render(noteFormatRenderContext{
+			ID:           note.ID,
 			Filename:     note.Filename(),
 			FilenameStem: note.FilenameStem(),
 			Path:         relPath,
+			Dir:          filepath.Dir(relPath),
 			AbsPath:      path.AbsPath(),
 			Title:        note.Title,
 			Link: newLazyStringer(func() string {
@@ -49,6 +86,9 @@ func newNoteFormatter(basePath string, template Template, linkFormatter LinkForm
 			Lead:       note.Lead,
 			Body:       note.Body,
 			Snippets:   snippets,
+			Score:      note.Score,
+			RelatedVia: relatedVia,
+			Backlinks:  backlinks,
 			Tags:       note.Tags,
 			RawContent: note.RawContent,
 			WordCount:  note.WordCount,
@@ -66,15 +106,20 @@ var noteTermRegex = regexp.MustCompile(`<zk:match>(.*?)</zk:match>`)
 // noteFormatRenderContext holds the variables available to the note formatting
 // templates.
 type noteFormatRenderContext struct {
+	ID           NoteID                 `json:"id" handlebars:"id"`
 	Filename     string                 `json:"filename"`
 	FilenameStem string                 `json:"filenameStem" handlebars:"filename-stem"`
 	Path         string                 `json:"path"`
+	Dir          string                 `json:"dir"`
 	AbsPath      string                 `json:"absPath" handlebars:"abs-path"`
 	Title        string                 `json:"title"`
 	Link         fmt.Stringer           `json:"link"`
 	Lead         string                 `json:"lead"`
 	Body         string                 `json:"body"`
 	Snippets     []string               `json:"snippets"`
+	Score        float64                `json:"score,omitempty"`
+	RelatedVia   []noteFormatBridge     `json:"relatedVia,omitempty" handlebars:"related-via"`
+	Backlinks    []noteFormatBacklink   `json:"backlinks,omitempty"`
 	RawContent   string                 `json:"rawContent" handlebars:"raw-content"`
 	WordCount    int                    `json:"wordCount" handlebars:"word-count"`
 	Tags         []string               `json:"tags"`
@@ -85,6 +130,20 @@ type noteFormatRenderContext struct {
 	Env          map[string]string      `json:"-"`
 }
 
+// noteFormatBridge holds the variables of a note connecting a note
+// found with --related to the given note.
+type noteFormatBridge struct {
+	Path     string `json:"path"`
+	Outbound bool   `json:"outbound"`
+}
+
+// noteFormatBacklink holds the variables of a note linking to the formatted
+// note.
+type noteFormatBacklink struct {
+	Path  string `json:"path"`
+	Title string `json:"title"`
+}
+
 func (c noteFormatRenderContext) Equal(other noteFormatRenderContext) bool {
 	json1, err := json.Marshal(c)
 	if err != nil {
@@ -72,9 +72,11 @@ func TestNewNoteFormatter(t *testing.T) {
 	// Check that the template received the proper contexts
 	assert.Equal(t, test.template.Contexts, []interface{}{
 		noteFormatRenderContext{
+			ID:           1,
 			Filename:     "note1.md",
 			FilenameStem: "note1",
 			Path:         "note1.md",
+			Dir:          ".",
 			AbsPath:      "/notebook/note1.md",
 			Title:        "Note 1",
 			Link:         opt.NewString("[Note 1](note1)"),
@@ -93,9 +95,11 @@ func TestNewNoteFormatter(t *testing.T) {
 			Checksum: "checksum1",
 		},
 		noteFormatRenderContext{
+			ID:           2,
 			Filename:     "note2.md",
 			FilenameStem: "note2",
 			Path:         "dir/note2.md",
+			Dir:          "dir",
 			AbsPath:      "/notebook/dir/note2.md",
 			Title:        "Note 2",
 			Link:         opt.NewString("[Note 2](dir/note2)"),
@@ -114,7 +118,7 @@ func TestNewNoteFormatter(t *testing.T) {
 }
 
 func TestNoteFormatterMakesPathRelative(t *testing.T) {
-	test := func(basePath, currentPath, path string, expected, expectedFull string) {
+	test := func(basePath, currentPath, path string, expected, expectedDir, expectedFull string) {
 		test := formatTest{
 			rootDir:    basePath,
 			workingDir: currentPath,
@@ -131,6 +135,7 @@ func TestNoteFormatterMakesPathRelative(t *testing.T) {
 				Filename:     filepath.Base(expected),
 				FilenameStem: paths.FilenameStem(expected),
 				Path:         expected,
+				Dir:          expectedDir,
 				AbsPath:      expectedFull,
 				Link:         opt.NewString("[](" + paths.DropExt(expected) + ")"),
 				Snippets:     []string{},
@@ -139,14 +144,80 @@ func TestNoteFormatterMakesPathRelative(t *testing.T) {
 	}
 
 	// Check that the path is relative to the current directory.
-	test("", "", "note.md", "note.md", "/notebook/note.md")
-	test("", "", "dir/note.md", "dir/note.md", "/notebook/dir/note.md")
-	test("/abs/zk", "/abs/zk", "note.md", "note.md", "/abs/zk/note.md")
-	test("/abs/zk", "/abs/zk", "dir/note.md", "dir/note.md", "/abs/zk/dir/note.md")
-	test("/abs/zk", "/abs/zk/dir", "note.md", "../note.md", "/abs/zk/note.md")
-	test("/abs/zk", "/abs/zk/dir", "dir/note.md", "note.md", "/abs/zk/dir/note.md")
-	test("/abs/zk", "/abs", "note.md", "zk/note.md", "/abs/zk/note.md")
-	test("/abs/zk", "/abs", "dir/note.md", "zk/dir/note.md", "/abs/zk/dir/note.md")
+	test("", "", "note.md", "note.md", ".", "/notebook/note.md")
+	test("", "", "dir/note.md", "dir/note.md", "dir", "/notebook/dir/note.md")
+	test("/abs/zk", "/abs/zk", "note.md", "note.md", ".", "/abs/zk/note.md")
+	test("/abs/zk", "/abs/zk", "dir/note.md", "dir/note.md", "dir", "/abs/zk/dir/note.md")
+	test("/abs/zk", "/abs/zk/dir", "note.md", "../note.md", "..", "/abs/zk/note.md")
+	test("/abs/zk", "/abs/zk/dir", "dir/note.md", "note.md", ".", "/abs/zk/dir/note.md")
+	test("/abs/zk", "/abs", "note.md", "zk/note.md", "zk", "/abs/zk/note.md")
+	test("/abs/zk", "/abs", "dir/note.md", "zk/dir/note.md", "zk/dir", "/abs/zk/dir/note.md")
+}
+
+func TestNoteFormatterMakesRelatedBridgePathsRelative(t *testing.T) {
+	test := formatTest{
+		rootDir:    "/abs/zk",
+		workingDir: "/abs/zk/dir",
+	}
+	test.setup()
+	formatter, err := test.run("format")
+	assert.Nil(t, err)
+	_, err = formatter(ContextualNote{
+		Note: Note{Path: "dir/note.md"},
+		RelatedVia: []RelatedBridge{
+			{ID: 2, Path: "bridge.md", Outbound: true},
+			{ID: 3, Path: "dir/other.md", Outbound: false},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, test.template.Contexts, []interface{}{
+		noteFormatRenderContext{
+			Filename:     "note.md",
+			FilenameStem: "note",
+			Path:         "note.md",
+			Dir:          ".",
+			AbsPath:      "/abs/zk/dir/note.md",
+			Link:         opt.NewString("[](note)"),
+			Snippets:     []string{},
+			RelatedVia: []noteFormatBridge{
+				{Path: "../bridge.md", Outbound: true},
+				{Path: "other.md", Outbound: false},
+			},
+		},
+	})
+}
+
+func TestNoteFormatterMakesBacklinkPathsRelative(t *testing.T) {
+	test := formatTest{
+		rootDir:    "/abs/zk",
+		workingDir: "/abs/zk/dir",
+	}
+	test.setup()
+	formatter, err := test.run("format")
+	assert.Nil(t, err)
+	_, err = formatter(ContextualNote{
+		Note: Note{Path: "dir/note.md"},
+		Backlinks: []Backlink{
+			{ID: 2, Path: "index.md", Title: "Index"},
+			{ID: 3, Path: "dir/other.md", Title: "Other"},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, test.template.Contexts, []interface{}{
+		noteFormatRenderContext{
+			Filename:     "note.md",
+			FilenameStem: "note",
+			Path:         "note.md",
+			Dir:          ".",
+			AbsPath:      "/abs/zk/dir/note.md",
+			Link:         opt.NewString("[](note)"),
+			Snippets:     []string{},
+			Backlinks: []noteFormatBacklink{
+				{Path: "../index.md", Title: "Index"},
+				{Path: "other.md", Title: "Other"},
+			},
+		},
+	})
 }
 
 func TestNoteFormatterStylesSnippetTerm(t *testing.T) {
@@ -164,6 +235,7 @@ func TestNoteFormatterStylesSnippetTerm(t *testing.T) {
 				Filename:     ".",
 				FilenameStem: ".",
 				Path:         ".",
+				Dir:          ".",
 				AbsPath:      "/notebook",
 				Link:         opt.NewString("[]()"),
 				Snippets:     []string{expected},
@@ -19,6 +19,15 @@ type NoteIndex interface {
 	// FindMinimal retrieves lightweight metadata for the notes matching the
 	// given filtering and sorting criteria.
 	FindMinimal(opts NoteFindOpts) ([]MinimalNote, error)
+	// FindPaths retrieves only the paths of the notes matching the given
+	// filtering and sorting criteria.
+	FindPaths(opts NoteFindOpts) ([]string, error)
+	// CountByCreationDate counts the notes matching the given filtering
+	// criteria, grouped by their creation date.
+	CountByCreationDate(opts NoteFindOpts, bucket DateBucket) ([]DateBucketCount, error)
+	// CheckIntegrity returns a description of each inconsistency found in
+	// the index, such as links pointing to missing notes.
+	CheckIntegrity() ([]string, error)
 
 	// Find link match returns the best note match for a given link href,
 	// relative to baseDir.
@@ -27,17 +36,42 @@ type NoteIndex interface {
 	// FindLinksBetweenNotes retrieves the links between the given notes.
 	FindLinksBetweenNotes(ids []NoteID) ([]ResolvedLink, error)
 
+	// FindBacklinks retrieves the links pointing to the given note.
+	FindBacklinks(id NoteID) ([]ResolvedLink, error)
+
+	// FindOutboundLinks retrieves the links found in the given note,
+	// including the broken and external ones.
+	FindOutboundLinks(id NoteID) ([]ResolvedLink, error)
+
+	// CountBacklinks returns the number of distinct notes linking to each
+	// note, indexed by path.
+	CountBacklinks() (map[string]int, error)
+
+	// FindUnresolvedLinks retrieves the internal links which don't point to
+	// any indexed note.
+	FindUnresolvedLinks() ([]ResolvedLink, error)
+
 	// FindCollections retrieves all the collections of the given kind.
 	FindCollections(kind CollectionKind, sorters []CollectionSorter) ([]Collection, error)
 
 	// Indexed returns the list of indexed note file metadata.
 	IndexedPaths() (<-chan paths.Metadata, error)
+	// IndexedPathsSince returns the list of indexed note file metadata
+	// modified since the given date, inclusive.
+	IndexedPathsSince(t time.Time) (<-chan paths.Metadata, error)
 	// Add indexes a new note.
+	// Returns ErrNoteIndexed if a note is already indexed at the same path.
 	Add(note Note) (NoteID, error)
 	// Update resets the metadata of an already indexed note.
 	Update(note Note) error
 	// Remove deletes a note from the index.
 	Remove(path string) error
+	// Touch sets the modification date of an indexed note, without
+	// reindexing its content.
+	Touch(path string, modified time.Time) error
+	// RebuildLinkTargets resolves again the target notes of all the internal
+	// links, without reindexing the notes.
+	RebuildLinkTargets() error
 
 	// Commit performs a set of operations atomically.
 	Commit(transaction func(idx NoteIndex) error) error
@@ -46,6 +80,28 @@ type NoteIndex interface {
 	NeedsReindexing() (bool, error)
 	// SetNeedsReindexing indicates whether all notes should be reindexed.
 	SetNeedsReindexing(needsReindexing bool) error
+
+	// LastListedAt returns when the notes were last listed with
+	// --new-since-last, or the zero time if they never were.
+	LastListedAt() (time.Time, error)
+	// SetLastListedAt saves when the notes were listed with --new-since-last.
+	SetLastListedAt(t time.Time) error
+
+	// LastIndexedAt returns when the notebook was last indexed, or the zero
+	// time if it never was.
+	LastIndexedAt() (time.Time, error)
+	// SetLastIndexedAt saves when the notebook was indexed.
+	SetLastIndexedAt(t time.Time) error
+}
+
+// ErrNoteIndexed is an error returned when adding a note already indexed at
+// the same path, relative to the notebook root.
+type ErrNoteIndexed struct {
+	Path string
+}
+
+func (e ErrNoteIndexed) Error() string {
+	return fmt.Sprintf("%s: note already indexed", e.Path)
 }
 
 // NoteIndexingStats holds statistics about a notebook indexing process.
@@ -200,6 +256,9 @@ func (t *indexTask) execute(callback func(change paths.DiffChange)) (NoteIndexin
 	if needsReindexing {
 		err = t.index.SetNeedsReindexing(false)
 	}
+	if err == nil {
+		err = t.index.SetLastIndexedAt(startTime)
+	}
 
 	print("")
 	return stats, wrap(err)
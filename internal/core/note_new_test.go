@@ -504,19 +504,49 @@ type noteIndexAddMock struct {
 
 func (m *noteIndexAddMock) Find(opts NoteFindOpts) ([]ContextualNote, error)     { return nil, nil }
 func (m *noteIndexAddMock) FindMinimal(opts NoteFindOpts) ([]MinimalNote, error) { return nil, nil }
+func (m *noteIndexAddMock) FindPaths(opts NoteFindOpts) ([]string, error)        { return nil, nil }
+func (m *noteIndexAddMock) CountByCreationDate(opts NoteFindOpts, bucket DateBucket) ([]DateBucketCount, error) {
+	return nil, nil
+}
+func (m *noteIndexAddMock) RebuildLinkTargets() error {
+	return nil
+}
+func (m *noteIndexAddMock) CheckIntegrity() ([]string, error) {
+	return nil, nil
+}
 func (m *noteIndexAddMock) FindLinkMatch(baseDir string, href string, linkType LinkType) (NoteID, error) {
 	return 0, nil
 }
 func (m *noteIndexAddMock) FindLinksBetweenNotes(ids []NoteID) ([]ResolvedLink, error) {
 	return nil, nil
 }
+func (m *noteIndexAddMock) FindBacklinks(id NoteID) ([]ResolvedLink, error) {
+	return nil, nil
+}
+func (m *noteIndexAddMock) FindOutboundLinks(id NoteID) ([]ResolvedLink, error) {
+	return nil, nil
+}
+func (m *noteIndexAddMock) CountBacklinks() (map[string]int, error) {
+	return nil, nil
+}
+func (m *noteIndexAddMock) FindUnresolvedLinks() ([]ResolvedLink, error) {
+	return nil, nil
+}
 func (m *noteIndexAddMock) FindCollections(kind CollectionKind, sorters []CollectionSorter) ([]Collection, error) {
 	return nil, nil
 }
+func (m *noteIndexAddMock) IndexedPathsSince(t time.Time) (<-chan paths.Metadata, error) {
+	return nil, nil
+}
 func (m *noteIndexAddMock) IndexedPaths() (<-chan paths.Metadata, error)       { return nil, nil }
 func (m *noteIndexAddMock) Add(note Note) (NoteID, error)                      { return m.ReturnedID, nil }
 func (m *noteIndexAddMock) Update(note Note) error                             { return nil }
 func (m *noteIndexAddMock) Remove(path string) error                           { return nil }
+func (m *noteIndexAddMock) Touch(path string, modified time.Time) error        { return nil }
 func (m *noteIndexAddMock) Commit(transaction func(idx NoteIndex) error) error { return nil }
 func (m *noteIndexAddMock) NeedsReindexing() (bool, error)                     { return false, nil }
 func (m *noteIndexAddMock) SetNeedsReindexing(needsReindexing bool) error      { return nil }
+func (m *noteIndexAddMock) LastListedAt() (time.Time, error)                   { return time.Time{}, nil }
+func (m *noteIndexAddMock) SetLastListedAt(t time.Time) error                  { return nil }
+func (m *noteIndexAddMock) LastIndexedAt() (time.Time, error)                  { return time.Time{}, nil }
+func (m *noteIndexAddMock) SetLastIndexedAt(t time.Time) error                 { return nil }
@@ -75,6 +75,7 @@ func (n *Notebook) ParseNoteWithContent(absPath string, content []byte) (*Note,
 		WordCount:  len(strings.Fields(contentStr)),
 		Links:      make([]Link, 0),
 		Tags:       contentParts.Tags,
+		Aliases:    aliasesFrom(contentParts.Metadata, n.Config.Format.Markdown.AliasKeys),
 		Metadata:   contentParts.Metadata,
 		Checksum:   fmt.Sprintf("%x", sha256.Sum256(content)),
 	}
@@ -101,6 +102,31 @@ func (n *Notebook) ParseNoteWithContent(absPath string, content []byte) (*Note,
 	return &note, nil
 }
 
+// aliasesFrom returns the aliases declared in the metadata under the given
+// keys, either as a single string or as a list.
+func aliasesFrom(metadata map[string]interface{}, keys []string) []string {
+	aliases := []string{}
+	appendAlias := func(alias string) {
+		alias = strings.TrimSpace(alias)
+		if alias != "" && !strutil.Contains(aliases, alias) {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	for _, key := range keys {
+		switch val := metadata[key].(type) {
+		case []interface{}:
+			for _, alias := range val {
+				appendAlias(fmt.Sprint(alias))
+			}
+		case string:
+			appendAlias(val)
+		}
+	}
+
+	return aliases
+}
+
 func creationDateFrom(metadata map[string]interface{}, times times.Timespec) time.Time {
 	// Read the creation date from the YAML frontmatter `date` key.
 	if dateVal, ok := metadata["date"]; ok {
@@ -1,5 +1,11 @@
 package core
 
+import (
+	"testing"
+
+	"github.com/zk-org/zk/internal/util/test/assert"
+)
+
 type noteContentParserMock struct {
 	results map[string]*NoteContent
 }
@@ -16,3 +22,16 @@ func (p *noteContentParserMock) ParseNoteContent(content string) (*NoteContent,
 	}
 	return &NoteContent{}, nil
 }
+
+func TestAliasesFrom(t *testing.T) {
+	test := func(metadata map[string]interface{}, keys []string, expected []string) {
+		assert.Equal(t, aliasesFrom(metadata, keys), expected)
+	}
+
+	test(map[string]interface{}{}, DefaultAliasKeys, []string{})
+	test(map[string]interface{}{"aliases": "Foo"}, DefaultAliasKeys, []string{"Foo"})
+	test(map[string]interface{}{"aliases": []interface{}{"Foo", " Bar ", "", "Foo", 42}}, DefaultAliasKeys, []string{"Foo", "Bar", "42"})
+	test(map[string]interface{}{"aliases": "Foo", "alias": "Bar"}, []string{"alias", "aliases"}, []string{"Bar", "Foo"})
+	test(map[string]interface{}{"aliases": "Foo"}, []string{}, []string{})
+	test(map[string]interface{}{"aliases": map[string]interface{}{"a": "Foo"}}, DefaultAliasKeys, []string{})
+}
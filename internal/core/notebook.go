@@ -226,6 +226,53 @@ func (n *Notebook) FindMinimalNote(opts NoteFindOpts) (*MinimalNote, error) {
 	}
 }
 
+// FindNotePaths retrieves only the paths of the notes matching the given
+// filtering options, relative to the notebook directory.
+func (n *Notebook) FindNotePaths(opts NoteFindOpts) ([]string, error) {
+	return n.index.FindPaths(opts)
+}
+
+// CountNotesByCreationDate counts the notes matching the given filtering
+// options, grouped by their creation date.
+func (n *Notebook) CountNotesByCreationDate(opts NoteFindOpts, bucket DateBucket) ([]DateBucketCount, error) {
+	return n.index.CountByCreationDate(opts, bucket)
+}
+
+// LastListedAt returns when the notes were last listed with
+// --new-since-last, or the zero time if they never were.
+func (n *Notebook) LastListedAt() (time.Time, error) {
+	return n.index.LastListedAt()
+}
+
+// SetLastListedAt saves when the notes were listed with --new-since-last.
+func (n *Notebook) SetLastListedAt(t time.Time) error {
+	return n.index.SetLastListedAt(t)
+}
+
+// TouchNote sets the modification date of the note at the given path,
+// relative to the notebook directory. Both the file and the index are
+// updated, so the note is not reindexed afterwards.
+func (n *Notebook) TouchNote(path string, modified time.Time) error {
+	modified = modified.UTC()
+	err := n.fs.SetModified(filepath.Join(n.Path, path), modified)
+	if err != nil {
+		return err
+	}
+	return n.index.Touch(path, modified)
+}
+
+// CheckIntegrity returns a description of each inconsistency found in the
+// notebook index.
+func (n *Notebook) CheckIntegrity() ([]string, error) {
+	return n.index.CheckIntegrity()
+}
+
+// RebuildLinkTargets resolves again the target notes of all the indexed links,
+// e.g. after moving notes around, without reindexing their content.
+func (n *Notebook) RebuildLinkTargets() error {
+	return n.index.RebuildLinkTargets()
+}
+
 // FindByHref retrieves the first note matching the given link href.
 // If allowPartialHref is true, the href can match any unique sub portion of a note path.
 func (n *Notebook) FindByHref(href string, allowPartialHref bool) (*MinimalNote, error) {
@@ -240,6 +287,41 @@ func (n *Notebook) FindLinksBetweenNotes(ids []NoteID) ([]ResolvedLink, error) {
 	return n.index.FindLinksBetweenNotes(ids)
 }
 
+// FindBacklinks retrieves the links pointing to the given note.
+func (n *Notebook) FindBacklinks(id NoteID) ([]ResolvedLink, error) {
+	return n.index.FindBacklinks(id)
+}
+
+// FindOutboundLinks retrieves the links found in the given note, including
+// the broken and external ones.
+func (n *Notebook) FindOutboundLinks(id NoteID) ([]ResolvedLink, error) {
+	return n.index.FindOutboundLinks(id)
+}
+
+// CountBacklinks returns the number of distinct notes linking to each note,
+// indexed by their path relative to the notebook directory.
+func (n *Notebook) CountBacklinks() (map[string]int, error) {
+	return n.index.CountBacklinks()
+}
+
+// FindUnresolvedLinks retrieves the internal links which don't point to any
+// indexed note.
+func (n *Notebook) FindUnresolvedLinks() ([]ResolvedLink, error) {
+	return n.index.FindUnresolvedLinks()
+}
+
+// FindSimilarTags retrieves the existing tags whose name is close to the
+// given one, e.g. to suggest a fix for a typo.
+func (n *Notebook) FindSimilarTags(tag string) ([]string, error) {
+	tags, err := n.index.FindCollections(CollectionKindTag, []CollectionSorter{
+		{Field: CollectionSortName, Ascending: true},
+	})
+	if err != nil {
+		return []string{}, err
+	}
+	return SimilarCollectionNames(tag, tags), nil
+}
+
 // FindCollections retrieves all the collections of the given kind.
 func (n *Notebook) FindCollections(kind CollectionKind, sorters []CollectionSorter) ([]Collection, error) {
 	return n.index.FindCollections(kind, sorters)
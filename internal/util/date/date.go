@@ -35,8 +35,26 @@ func (n *Frozen) Date() time.Time {
 	return n.date
 }
 
-// TimeFromNatural parses a human date into a time.Time.
+// Direction is the direction in time in which ambiguous human dates, such as
+// "monday", are resolved.
+type Direction int
+
+const (
+	// Past resolves ambiguous dates to the most recent past occurrence.
+	Past Direction = iota
+	// Future resolves ambiguous dates to the next occurrence.
+	Future
+)
+
+// TimeFromNatural parses a human date into a time.Time, resolving ambiguous
+// dates in the past.
 func TimeFromNatural(date string) (time.Time, error) {
+	return TimeFromNaturalInDirection(date, Past)
+}
+
+// TimeFromNaturalInDirection parses a human date into a time.Time, resolving
+// ambiguous dates in the given direction.
+func TimeFromNaturalInDirection(date string, direction Direction) (time.Time, error) {
 	if date == "" {
 		return time.Now(), nil
 	}
@@ -61,5 +79,10 @@ func TimeFromNatural(date string) (time.Time, error) {
 	if t, err := time.ParseInLocation("15:04", date, time.Local); err == nil {
 		return t, nil
 	}
-	return naturaldate.Parse(date, time.Now(), naturaldate.WithDirection(naturaldate.Past))
+
+	naturalDirection := naturaldate.Past
+	if direction == Future {
+		naturalDirection = naturaldate.Future
+	}
+	return naturaldate.Parse(date, time.Now(), naturaldate.WithDirection(naturalDirection))
 }
@@ -2,8 +2,30 @@ package fts5
 
 import "strings"
 
+// QuoteQuery transforms a query into a single SQLite FTS5 phrase, searched
+// literally without interpreting any operator.
+//
+// The FTS5 tokenizer still ignores punctuation inside the phrase.
+func QuoteQuery(query string) string {
+	return `"` + strings.ReplaceAll(query, `"`, `""`) + `"`
+}
+
 // ConvertQuery transforms a Google-like query into a SQLite FTS5 one.
 func ConvertQuery(query string) string {
+	return convertQuery(query, false)
+}
+
+// ConvertAnyQuery transforms a Google-like query into a SQLite FTS5 one
+// matching any of its terms, instead of all of them. The explicit operators
+// are kept, and the terms joined with OR are grouped before a NOT, e.g.
+// `foo bar -qux` -> `("foo" OR "bar") NOT "qux"`.
+func ConvertAnyQuery(query string) string {
+	return convertQuery(query, true)
+}
+
+// convertQuery transforms a Google-like query into a SQLite FTS5 one, joining
+// the consecutive terms with OR when anyTerm is true.
+func convertQuery(query string, anyTerm bool) string {
 	out := ""
 
 	// List of tokens which won't be automatically quoted in the output query.
@@ -24,8 +46,43 @@ func ConvertQuery(query string) string {
 
 	// Indicates whether the current term was explicitly quoted in the query.
 	inQuote := false
+	// Indicates whether we are reading a set of columns, e.g. {title body}.
+	inColumnSet := false
 	// Current term being read.
 	term := ""
+	// Indicates whether the previous term was not followed by any operator
+	// yet, requiring an OR before the next one with anyTerm.
+	needsOr := false
+	// Groups of terms nested in parentheses, the last one being the current
+	// group. Each records where it starts in the output and whether an OR was
+	// added between its terms with anyTerm.
+	type group struct {
+		start int
+		hasOr bool
+	}
+	groups := []group{{start: 0}}
+
+	// Writes an OR before a term starting in the output, if needed.
+	startTerm := func() {
+		if anyTerm && needsOr {
+			out += "OR "
+			groups[len(groups)-1].hasOr = true
+		}
+		needsOr = false
+	}
+
+	// Wraps the terms of the current group joined with OR in parentheses,
+	// before a NOT. Otherwise FTS5 would only apply the NOT to the last
+	// term, as it has a higher precedence than OR.
+	groupBeforeNot := func() {
+		g := &groups[len(groups)-1]
+		if !g.hasOr {
+			return
+		}
+		terms := strings.TrimRight(out[g.start:], " \t\n")
+		out = out[:g.start] + "(" + terms + ")" + out[g.start+len(terms):]
+		g.hasOr = false
+	}
 
 	// Finishes the current term and write it to the output after quoting it.
 	closeTerm := func() {
@@ -34,8 +91,13 @@ func ConvertQuery(query string) string {
 		}
 
 		if !inQuote && passthroughTokens[term] {
+			if term == "NOT" {
+				groupBeforeNot()
+			}
 			out += term
+			needsOr = false
 		} else {
+			startTerm()
 			// If the term has a wildcard suffix, it is a prefix token. We make
 			// sure that the * is not quoted or it will be ignored by the FTS5
 			// tokenizer.
@@ -47,6 +109,7 @@ func ConvertQuery(query string) string {
 			if isPrefixToken {
 				out += "*"
 			}
+			needsOr = true
 		}
 
 		term = ""
@@ -54,6 +117,19 @@ func ConvertQuery(query string) string {
 
 	for _, c := range query {
 		switch {
+		// Passthrough for the content of FTS5's column sets, e.g.
+		//   {title body}:foo -> {title body}:"foo"
+		case inColumnSet:
+			out += string(c)
+			if c == '}' {
+				inColumnSet = false
+			}
+
+		case !inQuote && c == '{' && term == "":
+			startTerm()
+			out += string(c)
+			inColumnSet = true
+
 		// Explicit quotes.
 		case c == '"':
 			if inQuote { // We are already in a quoted term? Then it's a closing quote.
@@ -65,23 +141,30 @@ func ConvertQuery(query string) string {
 		//   ^foo -> ^"foo"
 		//   "foo"* -> "foo"*
 		case term == "" && (c == '^' || c == '*'):
+			if c == '^' {
+				startTerm()
+			}
 			out += string(c)
 
 		// Passthrough for FTS5's column filters, e.g.
 		//   col:foo -> col:"foo"
 		case !inQuote && c == ':':
+			startTerm()
 			out += term + string(c)
 			term = ""
 
 		// - is an alias to NOT, but only at the start of a term, to allow
 		// compound words such as "well-known"
 		case c == '-' && term == "":
+			groupBeforeNot()
 			out += " NOT "
+			needsOr = false
 
 		// | is an alias to OR.
 		case !inQuote && c == '|':
 			closeTerm()
 			out += " OR "
+			needsOr = false
 
 		// FTS5's + is ignored because it doesn't bring much to the syntax,
 		// compared to explicit quotes.
@@ -91,7 +174,19 @@ func ConvertQuery(query string) string {
 		// Term separators outside explicit quotes terminates the current term.
 		case !inQuote && termSeparators[c]:
 			closeTerm()
+			switch c {
+			case '(':
+				startTerm()
+			case ')':
+				needsOr = true
+				if len(groups) > 1 {
+					groups = groups[:len(groups)-1]
+				}
+			}
 			out += string(c)
+			if c == '(' {
+				groups = append(groups, group{start: len(out)})
+			}
 
 		default:
 			term += string(c)
@@ -65,6 +65,13 @@ func TestConvertQuery(t *testing.T) {
 	test(`":foo"`, `":foo"`)
 	test(`-col:foo bar`, ` NOT col:"foo" "bar"`)
 	test(`col:(foo bar)`, `col:("foo" "bar")`)
+	test(`col: foo bar`, `col: "foo" "bar"`)
+	test(`title:foo body:bar qux`, `title:"foo" body:"bar" "qux"`)
+	test(`{title body}:foo bar`, `{title body}:"foo" "bar"`)
+	test(`{title}: foo`, `{title}: "foo"`)
+	test(`-{path title}:foo`, ` NOT {path title}:"foo"`)
+	test(`"{foo bar}"`, `"{foo bar}"`)
+	test(`foo{bar`, `"foo{bar"`)
 
 	// First token
 	test(`^foo`, `^"foo"`)
@@ -82,3 +89,45 @@ func TestConvertQuery(t *testing.T) {
 	// NEAR is not supported
 	test(`NEAR(foo, bar, 4)`, `"NEAR"("foo," "bar," "4")`)
 }
+
+func TestConvertAnyQuery(t *testing.T) {
+	test := func(query, expected string) {
+		assert.Equal(t, ConvertAnyQuery(query), expected)
+	}
+
+	test(``, ``)
+	test(`foo`, `"foo"`)
+	test(`foo bar`, `"foo" OR "bar"`)
+	test(`foo   bar qux`, `"foo"   OR "bar" OR "qux"`)
+	test(`"foo bar" qux`, `"foo bar" OR "qux"`)
+	test(`foo* ^bar`, `"foo"* OR ^"bar"`)
+	test(`title:foo body:bar`, `title:"foo" OR body:"bar"`)
+	test(`{title body}:foo bar`, `{title body}:"foo" OR "bar"`)
+	test(`foo (bar qux)`, `"foo" OR ("bar" OR "qux")`)
+	test(`(foo bar) qux`, `("foo" OR "bar") OR "qux"`)
+	// The explicit operators are kept.
+	test(`foo AND bar qux`, `"foo" AND "bar" OR "qux"`)
+	test(`foo OR bar`, `"foo" OR "bar"`)
+	test(`foo | bar`, `"foo"  OR  "bar"`)
+	test(`foo -bar`, `"foo"  NOT "bar"`)
+	test(`foo NOT bar`, `"foo" NOT "bar"`)
+	// The terms joined with OR are grouped before a NOT.
+	test(`foo bar -qux`, `("foo" OR "bar")  NOT "qux"`)
+	test(`foo bar NOT qux`, `("foo" OR "bar") NOT "qux"`)
+	test(`foo bar -qux baz -quux`, `(("foo" OR "bar")  NOT "qux" OR "baz")  NOT "quux"`)
+	test(`foo (bar qux -quux)`, `"foo" OR (("bar" OR "qux")  NOT "quux")`)
+	test(`foo | bar -qux`, `"foo"  OR  "bar"  NOT "qux"`)
+}
+
+func TestQuoteQuery(t *testing.T) {
+	test := func(query, expected string) {
+		assert.Equal(t, QuoteQuery(query), expected)
+	}
+
+	test(``, `""`)
+	test(`foo`, `"foo"`)
+	test(`foo AND bar`, `"foo AND bar"`)
+	test(`-foo | (bar*)`, `"-foo | (bar*)"`)
+	test(`col:foo`, `"col:foo"`)
+	test(`say "hello"`, `"say ""hello"""`)
+}
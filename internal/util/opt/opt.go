@@ -1,6 +1,9 @@
 package opt
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+)
 
 // String holds an optional string value.
 type String struct {
@@ -164,3 +167,70 @@ func (s Bool) MarshalJSON() ([]byte, error) {
 		return []byte("false"), nil
 	}
 }
+
+// Int holds an optional integer value.
+type Int struct {
+	Value *int
+}
+
+// NullInt represents an empty optional Int.
+var NullInt = Int{nil}
+
+// NewInt creates a new optional Int with the given value.
+func NewInt(value int) Int {
+	return Int{&value}
+}
+
+// IsNull returns whether the optional Int has no value.
+func (i Int) IsNull() bool {
+	return i.Value == nil
+}
+
+// Or returns the receiver if it is not null, otherwise the given optional
+// Int.
+func (i Int) Or(other Int) Int {
+	if i.IsNull() {
+		return other
+	} else {
+		return i
+	}
+}
+
+// Unwrap returns the optional Int value or 0 if none is set.
+func (i Int) Unwrap() int {
+	if i.IsNull() {
+		return 0
+	} else {
+		return *i.Value
+	}
+}
+
+func (i Int) Equal(other Int) bool {
+	return i.Value == other.Value ||
+		(i.Value != nil && other.Value != nil && *i.Value == *other.Value)
+}
+
+func (i Int) MarshalJSON() ([]byte, error) {
+	if i.IsNull() {
+		return []byte("null"), nil
+	} else {
+		return []byte(strconv.Itoa(*i.Value)), nil
+	}
+}
+
+// UnmarshalJSON parses an integer, or null for an empty optional Int.
+//
+// This is also used by kong to parse the command-line flags of this type.
+func (i *Int) UnmarshalJSON(data []byte) error {
+	str := string(data)
+	if str == "null" {
+		*i = NullInt
+		return nil
+	}
+	value, err := strconv.Atoi(str)
+	if err != nil {
+		return fmt.Errorf("%s: expected an integer", str)
+	}
+	*i = NewInt(value)
+	return nil
+}
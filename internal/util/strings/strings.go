@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 // Prepend prefixes each lines of a string with the given prefix.
@@ -166,3 +167,151 @@ func ByteIndexToRuneIndex(s string, i int) int {
 	}
 	return res
 }
+
+// EditDistance returns the Levenshtein distance between two strings, that is
+// the minimum number of single character insertions, deletions or
+// substitutions required to change one into the other.
+func EditDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	// Only the previous row of the distance matrix is needed.
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+
+	return prev[len(rb)]
+}
+
+// TruncateLines shortens the lines of text longer than width characters,
+// ending them with an ellipsis.
+//
+// The spans highlighted between the open and close markers are preserved, the
+// markers not taking any room.
+func TruncateLines(text string, width int, open, close string) string {
+	if width <= 0 {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = truncateLine(line, width, open, close)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func truncateLine(line string, width int, open, close string) string {
+	if visibleLength(line, open, close) <= width {
+		return line
+	}
+
+	var res strings.Builder
+	highlighted := false
+	count := 0
+	for i := 0; i < len(line) && count < width-1; {
+		switch {
+		case open != "" && strings.HasPrefix(line[i:], open):
+			res.WriteString(open)
+			highlighted = true
+			i += len(open)
+		case close != "" && strings.HasPrefix(line[i:], close):
+			res.WriteString(close)
+			highlighted = false
+			i += len(close)
+		default:
+			r, size := utf8.DecodeRuneInString(line[i:])
+			res.WriteRune(r)
+			count++
+			i += size
+		}
+	}
+	if highlighted {
+		res.WriteString(close)
+	}
+	res.WriteString("…")
+	return res.String()
+}
+
+// WrapLines breaks the lines of text longer than width characters between
+// their words. A word longer than width is kept whole on its own line.
+//
+// The spans highlighted between the open and close markers are closed at the
+// end of a wrapped line and reopened on the next one, the markers not taking
+// any room.
+func WrapLines(text string, width int, open, close string) string {
+	if width <= 0 {
+		return text
+	}
+	res := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		res = append(res, wrapLine(line, width, open, close)...)
+	}
+	return strings.Join(res, "\n")
+}
+
+func wrapLine(line string, width int, open, close string) []string {
+	if visibleLength(line, open, close) <= width {
+		return []string{line}
+	}
+
+	lines := []string{}
+	current := ""
+	length := 0
+	highlighted := false
+	for _, match := range spacedWordRegex.FindAllStringSubmatch(line, -1) {
+		space, word := match[1], match[2]
+		wordLength := visibleLength(word, open, close)
+		if length > 0 && length+utf8.RuneCountInString(space)+wordLength > width {
+			if highlighted {
+				current += close
+			}
+			lines = append(lines, current)
+			current = ""
+			length = 0
+			if highlighted {
+				current = open
+			}
+		}
+		// The spaces at which a line is wrapped are dropped, but not the
+		// indentation of the first one.
+		if length > 0 || len(lines) == 0 {
+			current += space
+			length += utf8.RuneCountInString(space)
+		}
+		current += word
+		length += wordLength
+
+		if open != "" && close != "" {
+			if o, c := strings.LastIndex(word, open), strings.LastIndex(word, close); o != c {
+				highlighted = o > c
+			}
+		}
+	}
+	return append(lines, current)
+}
+
+var spacedWordRegex = regexp.MustCompile(`([ \t]*)([^ \t]+)`)
+
+// visibleLength returns the number of characters of s, without the open and
+// close markers.
+func visibleLength(s string, open, close string) int {
+	if open != "" {
+		s = strings.ReplaceAll(s, open, "")
+	}
+	if close != "" {
+		s = strings.ReplaceAll(s, close, "")
+	}
+	return utf8.RuneCountInString(s)
+}
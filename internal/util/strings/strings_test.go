@@ -176,3 +176,57 @@ func TestByteIndexToRuneIndex(t *testing.T) {
 	test(source, 21, 19)
 	test(source, 22, 19)
 }
+
+func TestEditDistance(t *testing.T) {
+	test := func(a, b string, expected int) {
+		assert.Equal(t, EditDistance(a, b), expected)
+		assert.Equal(t, EditDistance(b, a), expected)
+	}
+
+	test("", "", 0)
+	test("", "tag", 3)
+	test("project", "project", 0)
+	test("projct", "project", 1)
+	test("porject", "project", 2)
+	test("kitten", "sitting", 3)
+	test("étoile", "etoile", 1)
+}
+
+func TestTruncateLines(t *testing.T) {
+	test := func(text string, width int, expected string) {
+		assert.Equal(t, TruncateLines(text, width, "<m>", "</m>"), expected)
+	}
+
+	test("", 5, "")
+	test("A short line", 0, "A short line")
+	test("A short line", 12, "A short line")
+	test("A short line", 11, "A short li…")
+	test("A short line", 1, "…")
+	test("Café crème", 6, "Café …")
+	test("A long line\nshort\nanother long one", 8, "A long …\nshort\nanother…")
+	// The markers don't count in the width.
+	test("A <m>short</m> line", 12, "A <m>short</m> line")
+	test("A <m>short</m> line", 8, "A <m>short</m>…")
+	// A highlighted span cut short is closed.
+	test("A <m>short</m> line", 5, "A <m>sh</m>…")
+}
+
+func TestWrapLines(t *testing.T) {
+	test := func(text string, width int, expected string) {
+		assert.Equal(t, WrapLines(text, width, "<m>", "</m>"), expected)
+	}
+
+	test("", 5, "")
+	test("A short line", 0, "A short line")
+	test("A short line", 12, "A short line")
+	test("A short line", 7, "A short\nline")
+	test("A short line", 3, "A\nshort\nline")
+	test("  Indented line to wrap", 10, "  Indented\nline to\nwrap")
+	test("One line\nanother line", 8, "One line\nanother\nline")
+	test("Spaced   out  words", 12, "Spaced   out\nwords")
+	test("Spaced   out  words", 8, "Spaced\nout\nwords")
+	// The markers don't count in the width.
+	test("A <m>short</m> line", 12, "A <m>short</m> line")
+	// A highlighted span is reopened on the next line.
+	test("<m>A short</m> line", 3, "<m>A</m>\n<m>short</m>\nline")
+}
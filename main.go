@@ -22,14 +22,18 @@ var Version = "dev"
 var Build = "dev"
 
 var root struct {
-	Init  cmd.Init  `cmd group:"zk" help:"Create a new notebook in the given directory."`
-	Index cmd.Index `cmd group:"zk" help:"Index the notes to be searchable."`
-
-	New   cmd.New   `cmd group:"notes" help:"Create a new note in the given notebook directory."`
-	List  cmd.List  `cmd group:"notes" help:"List notes matching the given criteria."`
-	Graph cmd.Graph `cmd group:"notes" help:"Produce a graph of the notes matching the given criteria."`
-	Edit  cmd.Edit  `cmd group:"notes" help:"Edit notes matching the given criteria."`
-	Tag   cmd.Tag   `cmd group:"notes" help:"Manage the note tags."`
+	Init   cmd.Init   `cmd group:"zk" help:"Create a new notebook in the given directory."`
+	Index  cmd.Index  `cmd group:"zk" help:"Index the notes to be searchable."`
+	Doctor cmd.Doctor `cmd group:"zk" help:"Check the notebook index for inconsistencies."`
+
+	New       cmd.New       `cmd group:"notes" help:"Create a new note in the given notebook directory."`
+	List      cmd.List      `cmd group:"notes" help:"List notes matching the given criteria."`
+	Graph     cmd.Graph     `cmd group:"notes" help:"Produce a graph of the notes matching the given criteria."`
+	Backlinks cmd.Backlinks `cmd group:"notes" help:"List the notes linking to the given note."`
+	Recent    cmd.Recent    `cmd group:"notes" help:"List the latest notes of the notebook."`
+	Edit      cmd.Edit      `cmd group:"notes" help:"Edit notes matching the given criteria."`
+	Touch     cmd.Touch     `cmd group:"notes" help:"Update the modification date of notes."`
+	Tag       cmd.Tag       `cmd group:"notes" help:"Manage the note tags."`
 
 	NotebookDir string  `type:path placeholder:PATH help:"Turn off notebook auto-discovery and set manually the notebook where commands are run."`
 	WorkingDir  string  `short:W type:path placeholder:PATH help:"Run as if zk was started in <PATH> instead of the current working directory."`
@@ -121,6 +125,8 @@ func options(container *cli.Container) []kong.Option {
 		kong.Bind(container),
 		kong.Name("zk"),
 		kong.UsageOnError(),
+		cli.SortTermMapper,
+		cli.FilterMapper,
 		kong.HelpOptions{
 			Compact:             true,
 			FlagsLast:           true,